@@ -2,43 +2,130 @@ package ap
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-pay/crypto/xpem"
+	"github.com/go-pay/crypto/xrsa"
+	"github.com/go-pay/gopay"
 	"github.com/go-pay/gopay/alipay"
+	"github.com/leapig/tpp/util"
 )
 
 type App interface {
-	SystemOauthToken(code string) map[string]interface{}
+	Key() string
+	SystemOauthToken(code string) (map[string]interface{}, error)
+	SystemOauthTokenContext(ctx context.Context, code string) (map[string]interface{}, error)
 }
 
 type Config struct {
-	AppId      string `json:"appid"`
-	AesKey     string `json:"aesKey"`
-	PublicKey  string `json:"publicKey"`
-	PrivateKey string `json:"privateKey"`
+	Key        string `json:"key" yaml:"key"`
+	AppId      string `json:"appid" yaml:"appid"`
+	AesKey     string `json:"aesKey" yaml:"aesKey"`
+	PublicKey  string `json:"publicKey" yaml:"publicKey"`
+	PrivateKey string `json:"privateKey" yaml:"privateKey"`
+	// BaseURL 服务地址，为空时使用平台默认地址
+	BaseURL string `json:"base_url" yaml:"base_url"`
+	// Proxy 出口代理地址
+	Proxy string `json:"proxy" yaml:"proxy"`
+	// HTTPClient 自定义请求客户端，优先于 Transport 与 Proxy
+	HTTPClient *http.Client `json:"-" yaml:"-"`
+	// Transport 自定义 Transport
+	Transport http.RoundTripper `json:"-" yaml:"-"`
 }
 
 type app struct {
 	config Config
 	server string
+	client *util.Client
 }
 
+// NewApp 支付宝实例不缓存令牌，无需 Cache 与 Locker
 func NewApp(config Config) App {
 	return &app{
 		config: config,
+		server: util.BaseURL(config.BaseURL, "https://openapi.alipay.com"),
+		client: &util.Client{Platform: util.PlatformAP, HttpClient: util.NewHTTPClient(config.HTTPClient, config.Transport, config.Proxy)},
 	}
 }
 
+// Key 获取当前实例Key
+func (a *app) Key() string {
+	return a.config.Key
+}
+
 // SystemOauthToken 获取用户登录信息
-func (a *app) SystemOauthToken(code string) (res map[string]interface{}) {
-	if resp, err := alipay.SystemOauthToken(
-		context.Background(),
-		a.config.AppId,
-		a.config.PrivateKey,
-		"authorization_code",
-		code, "RSA2"); err == nil && resp.ErrorResponse == nil {
-		res = map[string]interface{}{
-			"openid":  resp.Response.UserId,
-			"unionid": resp.Response.UnionId,
-		}
-	}
-	return
+func (a *app) SystemOauthToken(code string) (map[string]interface{}, error) {
+	return a.SystemOauthTokenContext(context.Background(), code)
+}
+
+// SystemOauthTokenContext 同 SystemOauthToken，支持 context.Context
+func (a *app) SystemOauthTokenContext(ctx context.Context, code string) (map[string]interface{}, error) {
+	bm := make(gopay.BodyMap)
+	bm.Set("grant_type", "authorization_code").Set("code", code)
+	body, err := a.doHttp(ctx, "alipay.system.oauth.token", bm)
+	if err != nil {
+		return nil, err
+	}
+	resp := new(alipay.SystemOauthTokenResponse)
+	if err = json.Unmarshal(body, resp); err != nil {
+		return nil, util.Errorf(util.PlatformAP, "failed to parse JSON response: %w", err)
+	}
+	if resp.ErrorResponse != nil {
+		return nil, newError(resp.ErrorResponse)
+	}
+	if resp.Response == nil || resp.Response.AccessToken == "" {
+		return nil, util.Errorf(util.PlatformAP, "response is nil or access_token is empty")
+	}
+	return map[string]interface{}{
+		"openid":  resp.Response.UserId,
+		"unionid": resp.Response.UnionId,
+	}, nil
+}
+
+// doHttp 签名后以表单提交网关请求，返回原始响应体
+// doc https://opendocs.alipay.com/common/02kf5q
+func (a *app) doHttp(ctx context.Context, method string, bm gopay.BodyMap) ([]byte, error) {
+	privateKey, err := xpem.DecodePrivateKey([]byte(xrsa.FormatAlipayPrivateKey(a.config.PrivateKey)))
+	if err != nil {
+		return nil, util.NewError(util.PlatformAP, err)
+	}
+	bm.Set("app_id", a.config.AppId).
+		Set("method", method).
+		Set("format", "JSON").
+		Set("charset", "utf-8").
+		Set("sign_type", alipay.RSA2).
+		Set("timestamp", time.Now().Format(time.DateTime)).
+		Set("version", "1.0")
+	sign, err := alipay.GetRsaSign(bm, alipay.RSA2, privateKey)
+	if err != nil {
+		return nil, util.NewError(util.PlatformAP, err)
+	}
+	bm.Set("sign", sign)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.server+"/gateway.do?charset=utf-8", strings.NewReader(bm.EncodeURLParams()))
+	if err != nil {
+		return nil, util.NewError(util.PlatformAP, fmt.Errorf("failed to create request: %w", err))
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded;charset=utf-8")
+	return a.client.Fetch(req)
+}
+
+// newError 转换支付宝错误响应
+func newError(resp *alipay.ErrorResponse) error {
+	code, _ := strconv.Atoi(resp.Code)
+	msg := resp.SubMsg
+	if msg == "" {
+		msg = resp.Msg
+	}
+	return &util.Error{
+		Platform: util.PlatformAP,
+		ErrCode:  code,
+		ErrMsg:   msg,
+		SubCode:  resp.SubCode,
+		Kind:     errorKinds[resp.SubCode],
+	}
 }
@@ -0,0 +1,71 @@
+package ap
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-pay/gopay"
+	"github.com/leapig/tpp/util"
+)
+
+func TestSystemOauthToken(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		resp string
+		want string
+		err  error
+	}{
+		{"success", `{"alipay_system_oauth_token_response":{"user_id":"2088","access_token":"AT"},"sign":"s"}`, "2088", nil},
+		{"invalid code", `{"error_response":{"code":"40002","msg":"Invalid Arguments","sub_code":"isv.code-invalid","sub_msg":"授权码code无效"},"sign":"s"}`, "", util.ErrInvalidParam},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if err := r.ParseForm(); err != nil {
+					t.Error(err)
+				}
+				bm := make(gopay.BodyMap)
+				for k := range r.PostForm {
+					if k != "sign" {
+						bm.Set(k, r.PostForm.Get(k))
+					}
+				}
+				if bm.GetString("method") != "alipay.system.oauth.token" || bm.GetString("code") != "c1" {
+					t.Errorf("form = %v", r.PostForm)
+				}
+				sign, _ := base64.StdEncoding.DecodeString(r.PostForm.Get("sign"))
+				sum := sha256.Sum256([]byte(bm.EncodeAliPaySignParams()))
+				if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, sum[:], sign); err != nil {
+					t.Errorf("invalid sign: %v", err)
+				}
+				w.Header().Set("Content-Type", "text/html;charset=utf-8")
+				_, _ = w.Write([]byte(tt.resp))
+			}))
+			defer srv.Close()
+			a := NewApp(Config{
+				AppId:      "2021",
+				PrivateKey: base64.StdEncoding.EncodeToString(x509.MarshalPKCS1PrivateKey(key)),
+				BaseURL:    srv.URL,
+			})
+			got, err := a.SystemOauthToken("c1")
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("SystemOauthToken() error = %v, want %v", err, tt.err)
+			}
+			if err == nil && got["openid"] != tt.want {
+				t.Errorf("openid = %v, want %s", got["openid"], tt.want)
+			}
+		})
+	}
+}
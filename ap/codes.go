@@ -0,0 +1,15 @@
+package ap
+
+import "github.com/leapig/tpp/util"
+
+// errorKinds 支付宝子错误码分类
+// doc https://opendocs.alipay.com/common/02km9f
+var errorKinds = map[string]error{
+	"isv.code-invalid":                  util.ErrInvalidParam,
+	"isv.invalid-app-id":                util.ErrInvalidParam,
+	"isv.insufficient-isv-permissions":  util.ErrPermissionDenied,
+	"isv.insufficient-user-permissions": util.ErrPermissionDenied,
+	"aop.invalid-auth-token":            util.ErrInvalidToken,
+	"aop.auth-token-time-out":           util.ErrInvalidToken,
+	"isp.flow-control":                  util.ErrRateLimited,
+}
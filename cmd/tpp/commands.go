@@ -0,0 +1,327 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+
+	"github.com/leapig/tpp/dt"
+	"github.com/leapig/tpp/fs"
+	"github.com/leapig/tpp/oa"
+	"github.com/leapig/tpp/util"
+	"github.com/leapig/tpp/wo"
+	"github.com/leapig/tpp/ww"
+)
+
+// runToken 获取平台令牌，wo 指定 -authorizer 时获取授权方令牌
+func runToken(ctx context.Context, c *cli, args []string) (interface{}, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("%w: platform is required", errUsage)
+	}
+	platform := args[0]
+	f := flags("token")
+	key := f.String("key", "", "实例 Key")
+	authorizer := f.String("authorizer", "", "wo 授权方 appid")
+	if err := parse(f, args[1:]); err != nil {
+		return nil, err
+	}
+	var (
+		token string
+		err   error
+	)
+	switch platform {
+	case util.PlatformMP:
+		app, e := lookup(c, platform, *key, c.registry.MP)
+		if e != nil {
+			return nil, e
+		}
+		token, err = app.TokenContext(ctx)
+	case util.PlatformOA:
+		app, e := lookup(c, platform, *key, c.registry.OA)
+		if e != nil {
+			return nil, e
+		}
+		token, err = app.TokenContext(ctx)
+	case util.PlatformWO:
+		app, e := lookup(c, platform, *key, c.registry.WO)
+		if e != nil {
+			return nil, e
+		}
+		if *authorizer != "" {
+			token, err = app.AuthorizerTokenContext(ctx, *authorizer)
+		} else {
+			token, err = app.TokenContext(ctx)
+		}
+	case util.PlatformWW:
+		app, e := lookup(c, platform, *key, c.registry.WW)
+		if e != nil {
+			return nil, e
+		}
+		token, err = app.TestContext(ctx)
+	case util.PlatformWK:
+		app, e := lookup(c, platform, *key, c.registry.WK)
+		if e != nil {
+			return nil, e
+		}
+		token, err = app.TestContext(ctx)
+	case util.PlatformDT:
+		app, e := lookup(c, platform, *key, c.registry.DT)
+		if e != nil {
+			return nil, e
+		}
+		token, err = app.TestContext(ctx)
+	case util.PlatformFS:
+		app, e := lookup(c, platform, *key, c.registry.FS)
+		if e != nil {
+			return nil, e
+		}
+		token, err = app.TestContext(ctx)
+	default:
+		return nil, fmt.Errorf("%w: unsupported platform %q", errUsage, platform)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{"access_token": token}, nil
+}
+
+// runOAMenuGet 查询公众号当前自定义菜单
+func runOAMenuGet(ctx context.Context, c *cli, args []string) (interface{}, error) {
+	f := flags("oa menu get")
+	key := f.String("key", "", "实例 Key")
+	if err := parse(f, args); err != nil {
+		return nil, err
+	}
+	app, err := lookup(c, util.PlatformOA, *key, c.registry.OA)
+	if err != nil {
+		return nil, err
+	}
+	return app.GetCurrentSelfMenuInfoContext(ctx)
+}
+
+// runOAMenuSet 创建公众号自定义菜单，文件内容为 {"button":[...]} 或按钮数组
+func runOAMenuSet(ctx context.Context, c *cli, args []string) (interface{}, error) {
+	f := flags("oa menu set")
+	key := f.String("key", "", "实例 Key")
+	file := f.String("file", "", "菜单 JSON 文件，- 为标准输入")
+	if err := parse(f, args); err != nil {
+		return nil, err
+	}
+	var raw json.RawMessage
+	if err := readJSON(*file, &raw); err != nil {
+		return nil, err
+	}
+	var menu struct {
+		Button []oa.Button `json:"button"`
+	}
+	target := interface{}(&menu)
+	if bytes.HasPrefix(bytes.TrimSpace(raw), []byte("[")) {
+		target = &menu.Button
+	}
+	if err := json.Unmarshal(raw, target); err != nil {
+		return nil, err
+	}
+	app, err := lookup(c, util.PlatformOA, *key, c.registry.OA)
+	if err != nil {
+		return nil, err
+	}
+	if err = app.MenuCreateContext(ctx, menu.Button); err != nil {
+		return nil, err
+	}
+	return map[string]bool{"ok": true}, nil
+}
+
+// runOATemplateList 查询公众号模板列表
+func runOATemplateList(ctx context.Context, c *cli, args []string) (interface{}, error) {
+	f := flags("oa template list")
+	key := f.String("key", "", "实例 Key")
+	if err := parse(f, args); err != nil {
+		return nil, err
+	}
+	app, err := lookup(c, util.PlatformOA, *key, c.registry.OA)
+	if err != nil {
+		return nil, err
+	}
+	return app.TemplateGetAllPrivateTemplateContext(ctx)
+}
+
+// runWOAuthorizersList 拉取已授权的账号列表
+func runWOAuthorizersList(ctx context.Context, c *cli, args []string) (interface{}, error) {
+	f := flags("wo authorizers list")
+	key := f.String("key", "", "实例 Key")
+	if err := parse(f, args); err != nil {
+		return nil, err
+	}
+	app, err := lookup(c, util.PlatformWO, *key, c.registry.WO)
+	if err != nil {
+		return nil, err
+	}
+	return app.GetAuthorizerListContext(ctx)
+}
+
+// authorizer 解析 wo 代码管理命令的公共参数并获取授权方令牌，指定 -refresh-token 时先保存
+type authorizer struct {
+	key          *string
+	appid        *string
+	refreshToken *string
+}
+
+func authorizerFlags(name string) (*authorizer, *flag.FlagSet) {
+	f := flags(name)
+	return &authorizer{
+		key:          f.String("key", "", "实例 Key"),
+		appid:        f.String("appid", "", "授权方 appid"),
+		refreshToken: f.String("refresh-token", "", "授权方 refresh_token，未保存过时指定"),
+	}, f
+}
+
+func (a *authorizer) token(ctx context.Context, c *cli) (wo.App, string, error) {
+	if *a.appid == "" {
+		return nil, "", fmt.Errorf("%w: -appid is required", errUsage)
+	}
+	app, err := lookup(c, util.PlatformWO, *a.key, c.registry.WO)
+	if err != nil {
+		return nil, "", err
+	}
+	if *a.refreshToken != "" {
+		if err = app.SetAuthorizerRefreshTokenContext(ctx, *a.appid, *a.refreshToken); err != nil {
+			return nil, "", err
+		}
+	}
+	token, err := app.AuthorizerTokenContext(ctx, *a.appid)
+	return app, token, err
+}
+
+// runWOCodeCommit 上传小程序代码并生成体验版
+func runWOCodeCommit(ctx context.Context, c *cli, args []string) (interface{}, error) {
+	a, f := authorizerFlags("wo code commit")
+	templateId := f.String("template", "", "代码模板ID")
+	ext := f.String("ext", "", "ext.json 文件")
+	version := f.String("version", "", "代码版本号")
+	desc := f.String("desc", "", "代码描述")
+	if err := parse(f, args); err != nil {
+		return nil, err
+	}
+	if *templateId == "" || *version == "" {
+		return nil, fmt.Errorf("%w: -template and -version are required", errUsage)
+	}
+	extJson := "{}"
+	if *ext != "" {
+		var v interface{}
+		if err := readJSON(*ext, &v); err != nil {
+			return nil, err
+		}
+		b, _ := json.Marshal(v)
+		extJson = string(b)
+	}
+	app, token, err := a.token(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	return app.CommitContext(ctx, token, *templateId, extJson, *version, *desc)
+}
+
+// runWOCodeAudit 提交代码审核
+func runWOCodeAudit(ctx context.Context, c *cli, args []string) (interface{}, error) {
+	a, f := authorizerFlags("wo code audit")
+	items := f.String("items", "", "审核项 item_list JSON 文件")
+	desc := f.String("desc", "", "版本说明 version_desc")
+	if err := parse(f, args); err != nil {
+		return nil, err
+	}
+	var itemList interface{}
+	if *items != "" {
+		if err := readJSON(*items, &itemList); err != nil {
+			return nil, err
+		}
+	}
+	app, token, err := a.token(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	return app.SubmitAuditContext(ctx, token, itemList, "", "", *desc, nil, nil, false, "")
+}
+
+// runWOCodeRelease 发布已通过审核的版本
+func runWOCodeRelease(ctx context.Context, c *cli, args []string) (interface{}, error) {
+	a, f := authorizerFlags("wo code release")
+	if err := parse(f, args); err != nil {
+		return nil, err
+	}
+	app, token, err := a.token(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	return app.ReleaseContext(ctx, token)
+}
+
+// messageFlags 解析消息发送命令的公共参数
+func messageFlags(name string, args []string) (key string, file string, err error) {
+	f := flags(name)
+	k := f.String("key", "", "实例 Key")
+	p := f.String("file", "", "消息 JSON 文件，- 为标准输入")
+	err = parse(f, args)
+	return *k, *p, err
+}
+
+// runWWMessageSend 发送企业微信应用消息，文件内容同 ww.Message
+func runWWMessageSend(ctx context.Context, c *cli, args []string) (interface{}, error) {
+	key, file, err := messageFlags("ww message send", args)
+	if err != nil {
+		return nil, err
+	}
+	var msg ww.Message
+	if err = readJSON(file, &msg); err != nil {
+		return nil, err
+	}
+	app, err := lookup(c, util.PlatformWW, key, c.registry.WW)
+	if err != nil {
+		return nil, err
+	}
+	if err = app.MessageSendContext(ctx, msg); err != nil {
+		return nil, err
+	}
+	return map[string]bool{"ok": true}, nil
+}
+
+// runDTMessageSend 发送钉钉工作通知，文件内容同 dt.Message
+func runDTMessageSend(ctx context.Context, c *cli, args []string) (interface{}, error) {
+	key, file, err := messageFlags("dt message send", args)
+	if err != nil {
+		return nil, err
+	}
+	var msg dt.Message
+	if err = readJSON(file, &msg); err != nil {
+		return nil, err
+	}
+	app, err := lookup(c, util.PlatformDT, key, c.registry.DT)
+	if err != nil {
+		return nil, err
+	}
+	if err = app.MessageSendContext(ctx, msg); err != nil {
+		return nil, err
+	}
+	return map[string]bool{"ok": true}, nil
+}
+
+// runFSMessageSend 发送飞书消息，文件内容同 fs.Message
+func runFSMessageSend(ctx context.Context, c *cli, args []string) (interface{}, error) {
+	key, file, err := messageFlags("fs message send", args)
+	if err != nil {
+		return nil, err
+	}
+	var msg fs.Message
+	if err = readJSON(file, &msg); err != nil {
+		return nil, err
+	}
+	app, err := lookup(c, util.PlatformFS, key, c.registry.FS)
+	if err != nil {
+		return nil, err
+	}
+	if err = app.MessageSendContext(ctx, msg); err != nil {
+		return nil, err
+	}
+	return map[string]bool{"ok": true}, nil
+}
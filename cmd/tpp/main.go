@@ -0,0 +1,205 @@
+// Command tpp 使用配置文件中的平台实例调用常用接口，结果以 JSON 输出到标准输出
+//
+//	tpp [-config tpp.yaml] [-cache dir] <command> [flags]
+//
+// 实例由 -key 指定，对应平台只配置了一个实例时可省略
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/faabiosr/cachego/file"
+	"github.com/leapig/tpp"
+	"github.com/leapig/tpp/logger"
+	"go.uber.org/zap"
+)
+
+// errUsage 命令或参数错误，退出码为 2
+var errUsage = errors.New("usage")
+
+// command 子命令，name 为空格分隔的命令路径
+type command struct {
+	name  string
+	usage string
+	run   func(ctx context.Context, c *cli, args []string) (interface{}, error)
+}
+
+var commands = []command{
+	{"token", "token <mp|oa|wo|ww|wk|dt|fs> [-key K] [-authorizer APPID]", runToken},
+	{"oa menu get", "oa menu get [-key K]", runOAMenuGet},
+	{"oa menu set", "oa menu set [-key K] -file menu.json", runOAMenuSet},
+	{"oa template list", "oa template list [-key K]", runOATemplateList},
+	{"wo authorizers list", "wo authorizers list [-key K]", runWOAuthorizersList},
+	{"wo code commit", "wo code commit [-key K] -appid APPID -template ID [-ext ext.json] -version V [-desc D] [-refresh-token T]", runWOCodeCommit},
+	{"wo code audit", "wo code audit [-key K] -appid APPID [-items items.json] [-desc D] [-refresh-token T]", runWOCodeAudit},
+	{"wo code release", "wo code release [-key K] -appid APPID [-refresh-token T]", runWOCodeRelease},
+	{"ww message send", "ww message send [-key K] -file message.json", runWWMessageSend},
+	{"dt message send", "dt message send [-key K] -file message.json", runDTMessageSend},
+	{"fs message send", "fs message send [-key K] -file message.json", runFSMessageSend},
+	{"org export", "org export <ww|dt|fs> [-key K] [-root ID]", runOrgExport},
+}
+
+func usage() {
+	out := flag.CommandLine.Output()
+	_, _ = fmt.Fprintln(out, "Usage: tpp [-config tpp.yaml] [-cache dir] <command> [flags]")
+	_, _ = fmt.Fprintln(out, "\nCommands:")
+	for _, cmd := range commands {
+		_, _ = fmt.Fprintln(out, "  "+cmd.usage)
+	}
+	_, _ = fmt.Fprintln(out, "\nFlags:")
+	flag.PrintDefaults()
+}
+
+func main() {
+	configPath := flag.String("config", "tpp.yaml", "配置文件，YAML 或 JSON")
+	cacheDir := flag.String("cache", "", "令牌缓存目录，与服务共用时可复用令牌与授权方 refresh_token，默认为系统临时目录")
+	flag.Usage = usage
+	flag.Parse()
+
+	cmd, args := match(flag.Args())
+	if cmd == nil {
+		usage()
+		os.Exit(2)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	c, err := newCli(*configPath, *cacheDir)
+	if err == nil {
+		var res interface{}
+		if res, err = cmd.run(ctx, c, args); err == nil {
+			err = write(os.Stdout, res)
+		}
+	}
+	if err != nil {
+		_ = write(os.Stderr, map[string]string{"error": err.Error()})
+		if errors.Is(err, errUsage) {
+			_, _ = fmt.Fprintln(os.Stderr, "usage: tpp "+cmd.usage)
+			os.Exit(2)
+		}
+		os.Exit(1)
+	}
+}
+
+// match 按最长命令路径匹配子命令，返回剩余参数
+func match(args []string) (*command, []string) {
+	var found *command
+	n := 0
+	for i := range commands {
+		path := strings.Fields(commands[i].name)
+		if len(path) > len(args) || len(path) <= n {
+			continue
+		}
+		if strings.Join(args[:len(path)], " ") == commands[i].name {
+			found, n = &commands[i], len(path)
+		}
+	}
+	if found == nil {
+		return nil, nil
+	}
+	return found, args[n:]
+}
+
+// write 以 JSON 格式输出
+func write(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(v)
+}
+
+// cli 子命令共用的注册表
+type cli struct {
+	registry *tpp.Registry
+}
+
+func newCli(configPath, cacheDir string) (*cli, error) {
+	config, err := tpp.LoadConfigFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+	if config.Logger != nil {
+		logger.InitLogger(config.Logger)
+	} else {
+		// 默认日志输出到标准输出，改为标准错误以免混入 JSON 结果
+		zapConfig := zap.NewDevelopmentConfig()
+		zapConfig.Level = zap.NewAtomicLevelAt(zap.WarnLevel)
+		zapConfig.OutputPaths = []string{"stderr"}
+		logger.InitLogger(&logger.Config{ZapConfig: &zapConfig})
+	}
+	shared := tpp.Shared{}
+	if cacheDir != "" {
+		shared.Cache = file.New(cacheDir)
+	}
+	registry := tpp.NewRegistry(shared)
+	if err = registry.Load(config); err != nil {
+		return nil, err
+	}
+	return &cli{registry: registry}, nil
+}
+
+// key 解析实例 Key，为空且平台只配置了一个实例时使用该实例
+func (c *cli) key(platform, key string) (string, error) {
+	if key != "" {
+		return key, nil
+	}
+	keys := c.registry.Keys(platform)
+	if len(keys) != 1 {
+		return "", fmt.Errorf("%w: -key is required, %s instances: %v", errUsage, platform, keys)
+	}
+	return keys[0], nil
+}
+
+// lookup 按 -key 获取实例
+func lookup[T any](c *cli, platform, key string, get func(key string) (T, error)) (T, error) {
+	k, err := c.key(platform, key)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return get(k)
+}
+
+// flags 创建子命令参数，解析失败时返回 errUsage
+func flags(name string) *flag.FlagSet {
+	f := flag.NewFlagSet(name, flag.ContinueOnError)
+	f.SetOutput(io.Discard)
+	return f
+}
+
+func parse(f *flag.FlagSet, args []string) error {
+	if err := f.Parse(args); err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	if f.NArg() > 0 {
+		return fmt.Errorf("%w: unexpected arguments %v", errUsage, f.Args())
+	}
+	return nil
+}
+
+// readJSON 读取 JSON 文件，path 为 - 时读取标准输入
+func readJSON(path string, v interface{}) error {
+	if path == "" {
+		return fmt.Errorf("%w: -file is required", errUsage)
+	}
+	var (
+		data []byte
+		err  error
+	)
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/leapig/tpp/util"
+)
+
+// org 通讯录导出结果，同一成员属于多个部门时只保留一条
+type org struct {
+	Departments []map[string]interface{} `json:"departments"`
+	Users       []interface{}            `json:"users"`
+	seen        map[string]bool
+}
+
+// addUsers 按 idField 去重后追加成员
+func (o *org) addUsers(users []interface{}, idField string) {
+	for _, user := range users {
+		if m, ok := user.(map[string]interface{}); ok {
+			id := fmt.Sprint(m[idField])
+			if o.seen[id] {
+				continue
+			}
+			o.seen[id] = true
+		}
+		o.Users = append(o.Users, user)
+	}
+}
+
+// runOrgExport 导出 -root 及其全部子部门的部门详情与成员
+func runOrgExport(ctx context.Context, c *cli, args []string) (interface{}, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("%w: platform is required", errUsage)
+	}
+	platform := args[0]
+	f := flags("org export")
+	key := f.String("key", "", "实例 Key")
+	root := f.String("root", "", "根部门ID，默认 ww、dt 为 1，fs 为 0")
+	if err := parse(f, args[1:]); err != nil {
+		return nil, err
+	}
+	o := &org{Departments: []map[string]interface{}{}, Users: []interface{}{}, seen: map[string]bool{}}
+	switch platform {
+	case util.PlatformWW:
+		app, err := lookup(c, platform, *key, c.registry.WW)
+		if err != nil {
+			return nil, err
+		}
+		if *root == "" {
+			*root = "1"
+		}
+		// simplelist 返回根部门及其全部子部门
+		ids, err := app.DepartmentSimpleListContext(ctx, *root)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range ids {
+			if m, ok := id.(map[string]interface{}); ok {
+				id = m["id"]
+			}
+			department, err := app.DepartmentGetContext(ctx, fmt.Sprint(id))
+			if err != nil {
+				return nil, err
+			}
+			users, err := app.UserListContext(ctx, fmt.Sprint(id))
+			if err != nil {
+				return nil, err
+			}
+			o.Departments = append(o.Departments, department)
+			o.addUsers(users, "userid")
+		}
+	case util.PlatformDT:
+		app, err := lookup(c, platform, *key, c.registry.DT)
+		if err != nil {
+			return nil, err
+		}
+		if *root == "" {
+			*root = "1"
+		}
+		ids, err := app.DepartmentListSubIdContext(ctx, []interface{}{*root})
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range ids {
+			department, err := app.DepartmentGetContext(ctx, id)
+			if err != nil {
+				return nil, err
+			}
+			users, err := app.UserListContext(ctx, id, 0)
+			if err != nil {
+				return nil, err
+			}
+			o.Departments = append(o.Departments, department)
+			o.addUsers(users, "userid")
+		}
+	case util.PlatformFS:
+		app, err := lookup(c, platform, *key, c.registry.FS)
+		if err != nil {
+			return nil, err
+		}
+		if *root == "" {
+			*root = "0"
+		}
+		// children 已递归获取全部子部门
+		children, err := app.DepartmentsChildrenContext(ctx, *root, "")
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range append([]interface{}{*root}, children...) {
+			department, err := app.DepartmentGetContext(ctx, fmt.Sprint(id))
+			if err != nil {
+				return nil, err
+			}
+			users, err := app.UsersFindByDepartmentContext(ctx, fmt.Sprint(id), "")
+			if err != nil {
+				return nil, err
+			}
+			o.Departments = append(o.Departments, department)
+			o.addUsers(users, "open_id")
+		}
+	default:
+		return nil, fmt.Errorf("%w: unsupported platform %q", errUsage, platform)
+	}
+	return o, nil
+}
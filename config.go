@@ -0,0 +1,206 @@
+package tpp
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/leapig/tpp/ap"
+	"github.com/leapig/tpp/dt"
+	"github.com/leapig/tpp/fs"
+	"github.com/leapig/tpp/logger"
+	"github.com/leapig/tpp/mp"
+	"github.com/leapig/tpp/oa"
+	"github.com/leapig/tpp/util"
+	"github.com/leapig/tpp/wk"
+	"github.com/leapig/tpp/wo"
+	"github.com/leapig/tpp/ww"
+	"gopkg.in/yaml.v3"
+)
+
+// FileConfig 配置文件，各平台为实例配置列表，字段名同各 Config 的 yaml 标签
+// Logger 为 logger 节点，字段名同 logger.Config 的 yaml 标签，需自行调用 logger.InitLogger
+type FileConfig struct {
+	Logger *logger.Config `yaml:"logger"`
+	WW     []ww.Config    `yaml:"ww"`
+	MP     []mp.Config    `yaml:"mp"`
+	OA     []oa.Config    `yaml:"oa"`
+	WK     []wk.Config    `yaml:"wk"`
+	WO     []wo.Config    `yaml:"wo"`
+	AP     []ap.Config    `yaml:"ap"`
+	DT     []dt.Config    `yaml:"dt"`
+	FS     []fs.Config    `yaml:"fs"`
+}
+
+var (
+	// envPattern ${NAME} 或 ${NAME:-default}
+	envPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(?::-([^}]*))?\}`)
+	// intPattern 整数，加引号或来自环境变量时也可用于整数字段
+	intPattern = regexp.MustCompile(`^[-+]?[0-9]+$`)
+)
+
+// LoadConfigFile 读取 YAML 或 JSON 配置文件，见 ParseConfig
+func LoadConfigFile(path string) (*FileConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseConfig(data)
+}
+
+// ParseConfig 解析 YAML 或 JSON 配置，字符串中的 ${NAME} 替换为环境变量，未设置时使用 ${NAME:-default} 中的默认值
+// 整数值可加引号或来自环境变量，字符串字段也可填写数字，解析后校验各平台必填字段
+func ParseConfig(data []byte) (*FileConfig, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	expand(&doc)
+	config := &FileConfig{}
+	if err := doc.Decode(config); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// expand 替换标量中的环境变量，整数标量按无引号整数解析，以便填入整数字段
+// 字符串字段解码时使用标量原文，不受影响
+func expand(node *yaml.Node) {
+	for _, child := range node.Content {
+		expand(child)
+	}
+	if node.Kind != yaml.ScalarNode {
+		return
+	}
+	expanded := envPattern.MatchString(node.Value)
+	if expanded {
+		node.Value = envPattern.ReplaceAllStringFunc(node.Value, func(ref string) string {
+			match := envPattern.FindStringSubmatch(ref)
+			if value, ok := os.LookupEnv(match[1]); ok {
+				return value
+			}
+			return match[2]
+		})
+	}
+	switch {
+	case intPattern.MatchString(node.Value):
+		node.Tag, node.Style = "", 0
+	case expanded:
+		// 替换后的值始终为字符串，避免 true、null 等被重新解析
+		node.Tag, node.Style = "!!str", 0
+	}
+}
+
+// Validate 校验各平台实例的 key 与必填凭证，Key 在同一平台内不可重复
+func (c *FileConfig) Validate() error {
+	seen := map[string]bool{}
+	for i, config := range c.WW {
+		if err := required(seen, util.PlatformWW, i, config.Key, "corpid", config.CorpId, "corpsecret", config.CorpSecret); err != nil {
+			return err
+		}
+	}
+	for i, config := range c.MP {
+		if err := required(seen, util.PlatformMP, i, config.Key, "appid", config.AppId, "secret", config.Secret); err != nil {
+			return err
+		}
+	}
+	for i, config := range c.OA {
+		if err := required(seen, util.PlatformOA, i, config.Key, "appid", config.AppId, "secret", config.Secret); err != nil {
+			return err
+		}
+	}
+	for i, config := range c.WK {
+		if err := required(seen, util.PlatformWK, i, config.Key, "appId", config.AppID, "appSecret", config.AppSecret); err != nil {
+			return err
+		}
+	}
+	for i, config := range c.WO {
+		if err := required(seen, util.PlatformWO, i, config.Key, "appid", config.AppId, "secret", config.Secret); err != nil {
+			return err
+		}
+	}
+	for i, config := range c.AP {
+		if err := required(seen, util.PlatformAP, i, config.Key, "appid", config.AppId, "privateKey", config.PrivateKey); err != nil {
+			return err
+		}
+	}
+	for i, config := range c.DT {
+		if err := required(seen, util.PlatformDT, i, config.Key, "appKey", config.AppKey, "appSecret", config.AppSecret); err != nil {
+			return err
+		}
+	}
+	for i, config := range c.FS {
+		if err := required(seen, util.PlatformFS, i, config.Key, "appId", config.AppID, "appSecret", config.AppSecret); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// required 校验 key 未重复且与成对传入的字段名、字段值均不为空
+func required(seen map[string]bool, platform string, i int, key string, fields ...string) error {
+	if key == "" {
+		return &util.Error{Platform: platform, ErrMsg: fmt.Sprintf("config[%d]: missing key", i), Kind: util.ErrInvalidParam}
+	}
+	if seen[platform+":"+key] {
+		return &util.Error{Platform: platform, ErrMsg: "duplicate config key: " + key, Kind: util.ErrInvalidParam}
+	}
+	seen[platform+":"+key] = true
+	for j := 0; j+1 < len(fields); j += 2 {
+		if fields[j+1] == "" {
+			return &util.Error{Platform: platform, ErrMsg: fmt.Sprintf("config %s: missing %s", key, fields[j]), Kind: util.ErrInvalidParam}
+		}
+	}
+	return nil
+}
+
+// Load 校验并注册配置文件中的全部实例，已存在相同 Key 的实例时替换
+func (r *Registry) Load(config *FileConfig) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	for _, c := range config.WW {
+		if _, err := r.RegisterWW(c); err != nil {
+			return err
+		}
+	}
+	for _, c := range config.MP {
+		if _, err := r.RegisterMP(c); err != nil {
+			return err
+		}
+	}
+	for _, c := range config.OA {
+		if _, err := r.RegisterOA(c); err != nil {
+			return err
+		}
+	}
+	for _, c := range config.WK {
+		if _, err := r.RegisterWK(c); err != nil {
+			return err
+		}
+	}
+	for _, c := range config.WO {
+		if _, err := r.RegisterWO(c); err != nil {
+			return err
+		}
+	}
+	for _, c := range config.AP {
+		if _, err := r.RegisterAP(c); err != nil {
+			return err
+		}
+	}
+	for _, c := range config.DT {
+		if _, err := r.RegisterDT(c); err != nil {
+			return err
+		}
+	}
+	for _, c := range config.FS {
+		if _, err := r.RegisterFS(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
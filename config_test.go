@@ -0,0 +1,131 @@
+package tpp
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseConfig(t *testing.T) {
+	t.Setenv("TPP_TEST_SECRET", "s3cr$t#1")
+	t.Setenv("TPP_TEST_AGENT_ID", "12")
+	t.Setenv("TPP_TEST_BOOL", "true")
+	tests := []struct {
+		name  string
+		data  string
+		check func(t *testing.T, c *FileConfig)
+	}{
+		{
+			name: "yaml",
+			data: "oa:\n  - key: a\n    appid: wx1\n    secret: s\n    base_url: http://127.0.0.1\n",
+			check: func(t *testing.T, c *FileConfig) {
+				if len(c.OA) != 1 || c.OA[0].AppId != "wx1" || c.OA[0].BaseURL != "http://127.0.0.1" {
+					t.Errorf("oa = %+v", c.OA)
+				}
+			},
+		},
+		{
+			name: "json",
+			data: `{"fs":[{"key":"f","appId":"cli_1","appSecret":"s","encryptKey":"k"}]}`,
+			check: func(t *testing.T, c *FileConfig) {
+				if len(c.FS) != 1 || c.FS[0].AppID != "cli_1" || c.FS[0].EncryptKey != "k" {
+					t.Errorf("fs = %+v", c.FS)
+				}
+			},
+		},
+		{
+			name: "env",
+			data: "oa:\n  - key: a\n    appid: wx1\n    secret: ${TPP_TEST_SECRET}\n    token: \"${TPP_TEST_MISSING:-fallback}\"\n    aes_key: ${TPP_TEST_BOOL}\n",
+			check: func(t *testing.T, c *FileConfig) {
+				if c.OA[0].Secret != "s3cr$t#1" || c.OA[0].Token != "fallback" || c.OA[0].AesKey != "true" {
+					t.Errorf("oa = %+v", c.OA[0])
+				}
+			},
+		},
+		{
+			name: "number into string field",
+			data: "ww:\n  - key: w\n    corpid: c\n    corpsecret: s\n    agentid: 1000002\n",
+			check: func(t *testing.T, c *FileConfig) {
+				if c.WW[0].AgentId != "1000002" {
+					t.Errorf("agentid = %q", c.WW[0].AgentId)
+				}
+			},
+		},
+		{
+			name: "quoted number into string field keeps text",
+			data: "mp:\n  - key: m\n    appid: wx1\n    secret: \"00123\"\n",
+			check: func(t *testing.T, c *FileConfig) {
+				if c.MP[0].Secret != "00123" {
+					t.Errorf("secret = %q", c.MP[0].Secret)
+				}
+			},
+		},
+		{
+			name: "env into int field",
+			data: "dt:\n  - key: d\n    appKey: k\n    appSecret: s\n    agentId: ${TPP_TEST_AGENT_ID}\n",
+			check: func(t *testing.T, c *FileConfig) {
+				if c.DT[0].AgentId != 12 {
+					t.Errorf("agentId = %d", c.DT[0].AgentId)
+				}
+			},
+		},
+		{
+			name: "numeric string into int field",
+			data: `{"dt":[{"key":"d","appKey":"k","appSecret":"s","agentId":"34"}]}`,
+			check: func(t *testing.T, c *FileConfig) {
+				if c.DT[0].AgentId != 34 {
+					t.Errorf("agentId = %d", c.DT[0].AgentId)
+				}
+			},
+		},
+		{
+			name: "logger",
+			data: "logger:\n  log-body: true\n  redact-fields: [secret]\n",
+			check: func(t *testing.T, c *FileConfig) {
+				if c.Logger == nil || !c.Logger.LogBody || len(c.Logger.RedactFields) != 1 {
+					t.Errorf("logger = %+v", c.Logger)
+				}
+			},
+		},
+		{
+			name:  "empty",
+			data:  "",
+			check: func(t *testing.T, c *FileConfig) {},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := ParseConfig([]byte(tt.data))
+			if err != nil {
+				t.Fatalf("ParseConfig() error = %v", err)
+			}
+			tt.check(t, c)
+		})
+	}
+}
+
+func TestParseConfigError(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+		invalid bool
+	}{
+		{"missing key", "oa:\n  - appid: wx1\n    secret: s\n", true, true},
+		{"missing secret", "oa:\n  - key: a\n    appid: wx1\n    secret: ${TPP_TEST_UNSET}\n", true, true},
+		{"duplicate key", "mp:\n  - {key: m, appid: a, secret: s}\n  - {key: m, appid: b, secret: s}\n", true, true},
+		{"same key on different platforms", "mp:\n  - {key: m, appid: a, secret: s}\noa:\n  - {key: m, appid: b, secret: s}\n", false, false},
+		{"not a number", "dt:\n  - {key: d, appKey: k, appSecret: s, agentId: abc}\n", true, false},
+		{"malformed", "oa: [", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseConfig([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := errors.Is(err, ErrInvalidParam); got != tt.invalid {
+				t.Errorf("errors.Is(%v, ErrInvalidParam) = %v, want %v", err, got, tt.invalid)
+			}
+		})
+	}
+}
@@ -2,72 +2,191 @@ package dt
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
-	"fmt"
 	json2 "github.com/bitly/go-simplejson"
+	"github.com/faabiosr/cachego"
 	"github.com/faabiosr/cachego/file"
 	"github.com/leapig/tpp/util"
-	"io"
 	"net/http"
 	"net/url"
 	"os"
 	"strconv"
-	"strings"
 	"time"
 )
 
 type App interface {
+	Key() string
 	Id() string
-	Test() string
-	MicroAppAllApps() (res map[string]interface{})
-	MicroAppAppsScopes() (res map[string]interface{})
-	AuthScopes() (res map[string]interface{})
-	DepartmentListSubId(deptIdList []interface{}) (res []interface{})
-	DepartmentGet(id interface{}) (res map[string]interface{})
-	UserList(id interface{}, cursor interface{}) (res []interface{})
-	UserGet(id interface{}) (res map[string]interface{})
-	JsApiTickets() (ticket string)
-	GetUserInfo(code string) (res map[string]interface{})
-	MessageSend(msg Message) (err error)
+	Close()
+	Test() (string, error)
+	TestContext(ctx context.Context) (string, error)
+	MicroAppAllApps() (map[string]interface{}, error)
+	MicroAppAllAppsContext(ctx context.Context) (map[string]interface{}, error)
+	MicroAppAppsScopes() (map[string]interface{}, error)
+	MicroAppAppsScopesContext(ctx context.Context) (map[string]interface{}, error)
+	AuthScopes() (map[string]interface{}, error)
+	AuthScopesContext(ctx context.Context) (map[string]interface{}, error)
+	DepartmentListSubId(deptIdList []interface{}) ([]interface{}, error)
+	DepartmentListSubIdContext(ctx context.Context, deptIdList []interface{}) ([]interface{}, error)
+	DepartmentGet(id interface{}) (map[string]interface{}, error)
+	DepartmentGetContext(ctx context.Context, id interface{}) (map[string]interface{}, error)
+	UserList(id interface{}, cursor interface{}) ([]interface{}, error)
+	UserListContext(ctx context.Context, id interface{}, cursor interface{}) ([]interface{}, error)
+	UserGet(id interface{}) (map[string]interface{}, error)
+	UserGetContext(ctx context.Context, id interface{}) (map[string]interface{}, error)
+	JsApiTickets() (string, error)
+	JsApiTicketsContext(ctx context.Context) (string, error)
+	JsConfig(url string) (*JsConfig, error)
+	JsConfigContext(ctx context.Context, url string) (*JsConfig, error)
+	GetUserInfo(code string) (map[string]interface{}, error)
+	GetUserInfoContext(ctx context.Context, code string) (map[string]interface{}, error)
+	MessageSend(msg Message) error
+	MessageSendContext(ctx context.Context, msg Message) error
+	Server() *Server
 }
 
 type Config struct {
-	CorpId    string `json:"corpId"`
-	AppKey    string `json:"appKey"`
-	AppSecret string `json:"appSecret"`
-	AgentId   int    `json:"agentId"`
+	Key       string `json:"key" yaml:"key"`
+	CorpId    string `json:"corpId" yaml:"corpId"`
+	AppKey    string `json:"appKey" yaml:"appKey"`
+	AppSecret string `json:"appSecret" yaml:"appSecret"`
+	AgentId   int    `json:"agentId" yaml:"agentId"`
+	// Token 事件订阅签名 Token
+	Token string `json:"token" yaml:"token"`
+	// AesKey 事件订阅加密 aes_key
+	AesKey string `json:"aesKey" yaml:"aesKey"`
+	// Cache 令牌缓存，为空时使用系统临时目录下的文件缓存
+	Cache cachego.Cache `json:"cache" yaml:"-"`
+	// BaseURL 服务地址，为空时使用平台默认地址
+	BaseURL string `json:"baseURL" yaml:"baseURL"`
+	// ApiBaseURL 新版接口服务地址，为空时使用 https://api.dingtalk.com
+	ApiBaseURL string `json:"apiBaseURL" yaml:"apiBaseURL"`
+	// Proxy 出口代理地址
+	Proxy string `json:"proxy" yaml:"proxy"`
+	// HTTPClient 自定义请求客户端，优先于 Transport 与 Proxy
+	HTTPClient *http.Client `json:"-" yaml:"-"`
+	// Transport 自定义 Transport
+	Transport http.RoundTripper `json:"-" yaml:"-"`
+	// Locker 多实例部署时的令牌刷新锁
+	Locker util.Locker `json:"-" yaml:"-"`
+	// Refresher 后台预刷新，为空时仅在令牌过期后刷新
+	Refresher *util.Refresher `json:"-" yaml:"-"`
 }
 
+// maxRateLimitRetry 限流重试次数
+const maxRateLimitRetry = 3
+
 type app struct {
 	config Config
 	token  util.AccessToken
+	// ticket jsapiTicket
+	ticket util.AccessToken
+	client *util.Client
 	server string
+	api    string
 }
 
 func NewApp(config Config) App {
-	server := "https://oapi.dingtalk.com"
+	if config.Cache == nil {
+		config.Cache = file.New(os.TempDir())
+	}
+	server := util.BaseURL(config.BaseURL, "https://oapi.dingtalk.com")
+	api := util.BaseURL(config.ApiBaseURL, "https://api.dingtalk.com")
+	client := &util.Client{Platform: util.PlatformDT, Kinds: errorKinds, HttpClient: util.NewHTTPClient(config.HTTPClient, config.Transport, config.Proxy)}
 	// 管理token
-	return &app{
+	a := &app{
 		server: server,
+		api:    api,
 		config: config,
+		client: client,
 		token: util.AccessToken{
-			Id:    config.AppKey + config.AppSecret,
-			Cache: file.New(os.TempDir()),
-			GetRefreshRequestFunc: func() []byte {
+			Id:     util.CacheKey(util.PlatformDT, config.AppKey, config.AppSecret),
+			Cache:  config.Cache,
+			Locker: config.Locker,
+			GetRefreshRequestFunc: func(ctx context.Context) ([]byte, error) {
 				payload, _ := json.Marshal(map[string]string{
 					"appKey":    config.AppKey,
 					"appSecret": config.AppSecret,
 				})
-				req, _ := http.NewRequest(http.MethodPost, "https://api.dingtalk.com/v1.0/oauth2/accessToken", bytes.NewReader(payload))
+				req, err := http.NewRequestWithContext(ctx, http.MethodPost, api+"/v1.0/oauth2/accessToken", bytes.NewReader(payload))
+				if err != nil {
+					return nil, util.NewError(util.PlatformDT, err)
+				}
 				req.Header.Set("Content-Type", "application/json")
-				response, _ := http.DefaultClient.Do(req)
-				resp, _ := io.ReadAll(response.Body)
-				fmt.Printf("\n\n%s\n\n", string(resp))
-				return resp
+				return client.Fetch(req)
 			},
 		},
 	}
+	a.ticket = util.AccessToken{
+		Id:     "ticket:" + a.token.Id,
+		Cache:  a.token.Cache,
+		Locker: config.Locker,
+		GetRefreshRequestFunc: func(ctx context.Context) ([]byte, error) {
+			js, err := a.doApiHttp(ctx, http.MethodPost, "/v1.0/oauth2/jsapiTickets")
+			if err != nil {
+				return nil, err
+			}
+			return js.Encode()
+		},
+		Parse: util.ParseJSON("jsapiTicket", "expireIn"),
+	}
+	if config.Refresher != nil {
+		config.Refresher.Add(a.token)
+		config.Refresher.Add(a.ticket)
+	}
+	return a
+}
+
+// doHttp 附加 access_token 后请求旧版接口（oapi.dingtalk.com），触发限流（subcode=90018）时等待1秒后重试，等待期间 ctx 取消时返回 ctx.Err()，令牌失效时刷新后重试一次
+func (a *app) doHttp(ctx context.Context, method string, path string, data interface{}) (js *json2.Json, err error) {
+	var payload []byte
+	if data != nil {
+		payload, _ = json.Marshal(data)
+	}
+	for attempt := 0; ; attempt++ {
+		err = a.token.WithAccessToken(ctx, func(token string) error {
+			params := url.Values{}
+			params.Add("access_token", token)
+			req, err := http.NewRequestWithContext(ctx, method, a.server+path+"?"+params.Encode(), util.BodyReader(payload))
+			if err != nil {
+				return util.NewError(util.PlatformDT, err)
+			}
+			req.Header.Set("Content-Type", util.ContentType)
+			js, err = a.client.Do(req)
+			return classify(err)
+		})
+		if errors.Is(err, util.ErrRateLimited) && attempt < maxRateLimitRetry {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+		return js, err
+	}
+}
+
+// doApiHttp 附加 x-acs-dingtalk-access-token 后请求新版接口（api.dingtalk.com），令牌失效时刷新后重试一次
+func (a *app) doApiHttp(ctx context.Context, method string, path string) (js *json2.Json, err error) {
+	err = a.token.WithAccessToken(ctx, func(token string) error {
+		req, err := http.NewRequestWithContext(ctx, method, a.api+path, nil)
+		if err != nil {
+			return util.NewError(util.PlatformDT, err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("x-acs-dingtalk-access-token", token)
+		js, err = a.client.Do(req)
+		return err
+	})
+	return
+}
+
+// Key 获取当前实例Key
+func (a *app) Key() string {
+	return a.config.Key
 }
 
 // Id 获取当前实例ID
@@ -75,24 +194,42 @@ func (a *app) Id() string {
 	return a.config.AppKey
 }
 
+// Close 停止实例令牌的后台预刷新，实例不再使用时调用
+func (a *app) Close() {
+	if a.config.Refresher != nil {
+		a.config.Refresher.Remove(a.token, a.ticket)
+	}
+}
+
 // Test 校验是否配置是否正常（返回access_token）
-func (a *app) Test() string {
-	return a.token.GetAccessToken()
+func (a *app) Test() (string, error) {
+	return a.TestContext(context.Background())
+}
+
+// TestContext 同 Test，支持 context.Context
+func (a *app) TestContext(ctx context.Context) (string, error) {
+	return a.token.GetAccessTokenContext(ctx)
 }
 
 // MicroAppAllApps GET /v1.0/microApp/allApps
-func (a *app) MicroAppAllApps() (res map[string]interface{}) {
-	params := url.Values{}
-	params.Add("x-acs-dingtalk-access-token", a.token.GetAccessToken())
-	if response, err := http.Get("https://api.dingtalk.com/v1.0/microApp/allApps?" + params.Encode()); err == nil {
-		if resp, err := io.ReadAll(response.Body); err == nil {
-			js, _ := json2.NewJson(resp)
-			appList := js.Get("appList").MustArray()
-			for _, app := range appList {
-				agentId, _ := strconv.Atoi(string(app.(map[string]interface{})["agentId"].(json.Number)))
-				if agentId == a.config.AgentId {
-					res = app.(map[string]interface{})
-				}
+func (a *app) MicroAppAllApps() (res map[string]interface{}, err error) {
+	return a.MicroAppAllAppsContext(context.Background())
+}
+
+// MicroAppAllAppsContext 同 MicroAppAllApps，支持 context.Context
+func (a *app) MicroAppAllAppsContext(ctx context.Context) (res map[string]interface{}, err error) {
+	js, err := a.doApiHttp(ctx, http.MethodGet, "/v1.0/microApp/allApps")
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range js.Get("appList").MustArray() {
+		if app, ok := item.(map[string]interface{}); ok {
+			n, ok := app["agentId"].(json.Number)
+			if !ok {
+				continue
+			}
+			if agentId, err := n.Int64(); err == nil && int(agentId) == a.config.AgentId {
+				res = app
 			}
 		}
 	}
@@ -100,171 +237,145 @@ func (a *app) MicroAppAllApps() (res map[string]interface{}) {
 }
 
 // MicroAppAppsScopes GET /v1.0/microApp/apps/{agentId}/scopes
-func (a *app) MicroAppAppsScopes() (res map[string]interface{}) {
-	params := url.Values{}
-	params.Add("x-acs-dingtalk-access-token", a.token.GetAccessToken())
-	if response, err := http.Get("https://api.dingtalk.com/v1.0/microApp/apps/" + strconv.Itoa(a.config.AgentId) + "/scopes?" + params.Encode()); err == nil {
-		if resp, err := io.ReadAll(response.Body); err == nil {
-			js, _ := json2.NewJson(resp)
-			res = js.Get("result").MustMap()
-		}
+func (a *app) MicroAppAppsScopes() (map[string]interface{}, error) {
+	return a.MicroAppAppsScopesContext(context.Background())
+}
+
+// MicroAppAppsScopesContext 同 MicroAppAppsScopes，支持 context.Context
+func (a *app) MicroAppAppsScopesContext(ctx context.Context) (map[string]interface{}, error) {
+	js, err := a.doApiHttp(ctx, http.MethodGet, "/v1.0/microApp/apps/"+strconv.Itoa(a.config.AgentId)+"/scopes")
+	if err != nil {
+		return nil, err
 	}
-	return
+	return js.Get("result").MustMap(), nil
 }
 
 // AuthScopes GET https://oapi.dingtalk.com/auth/scopes
-func (a *app) AuthScopes() (res map[string]interface{}) {
-	params := url.Values{}
-	params.Add("access_token", a.token.GetAccessToken())
-	if response, err := http.Get(a.server + "/auth/scopes?" + params.Encode()); err == nil {
-		if resp, err := io.ReadAll(response.Body); err == nil {
-			js, _ := json2.NewJson(resp)
-			if js.Get("errcode").MustInt() == 0 {
-				res = js.Get("auth_org_scopes").MustMap()
-			} else if js.Get("errcode").MustInt() == 88 && strings.Index(js.Get("errmsg").MustString(), "subcode=90018") > -1 {
-				time.Sleep(time.Second)
-				res = a.AuthScopes()
-			}
-		}
+func (a *app) AuthScopes() (map[string]interface{}, error) {
+	return a.AuthScopesContext(context.Background())
+}
+
+// AuthScopesContext 同 AuthScopes，支持 context.Context
+func (a *app) AuthScopesContext(ctx context.Context) (map[string]interface{}, error) {
+	js, err := a.doHttp(ctx, http.MethodGet, "/auth/scopes", nil)
+	if err != nil {
+		return nil, err
 	}
-	return
+	return js.Get("auth_org_scopes").MustMap(), nil
 }
 
 // DepartmentGet POST https://oapi.dingtalk.com/topapi/v2/department/get?access_token=ACCESS_TOKEN
-func (a *app) DepartmentGet(deptId interface{}) (res map[string]interface{}) {
-	params := url.Values{}
-	params = a.token.ApplyAccessToken(params)
-	payload, _ := json.Marshal(map[string]interface{}{
+func (a *app) DepartmentGet(deptId interface{}) (map[string]interface{}, error) {
+	return a.DepartmentGetContext(context.Background(), deptId)
+}
+
+// DepartmentGetContext 同 DepartmentGet，支持 context.Context
+func (a *app) DepartmentGetContext(ctx context.Context, deptId interface{}) (map[string]interface{}, error) {
+	js, err := a.doHttp(ctx, http.MethodPost, "/topapi/v2/department/get", map[string]interface{}{
 		"dept_id": deptId,
 	})
-	if response, err := http.Post(a.server+"/topapi/v2/department/get?"+params.Encode(), "application/json;charset=utf-8", bytes.NewReader(payload)); err == nil {
-		if resp, err := io.ReadAll(response.Body); err == nil {
-			js, _ := json2.NewJson(resp)
-			if js.Get("errcode").MustInt() == 0 {
-				res = js.Get("result").MustMap()
-			} else if js.Get("errcode").MustInt() == 88 && strings.Index(js.Get("errmsg").MustString(), "subcode=90018") > -1 {
-				time.Sleep(time.Second)
-				res = a.DepartmentGet(deptId)
-			}
-		}
+	if err != nil {
+		return nil, err
 	}
-	return
+	return js.Get("result").MustMap(), nil
 }
 
 // DepartmentListSubId POST https://oapi.dingtalk.com/topapi/v2/department/listsubid?access_token=ACCESS_TOKEN
-func (a *app) DepartmentListSubId(deptIdList []interface{}) (res []interface{}) {
+func (a *app) DepartmentListSubId(deptIdList []interface{}) ([]interface{}, error) {
+	return a.DepartmentListSubIdContext(context.Background(), deptIdList)
+}
+
+// DepartmentListSubIdContext 同 DepartmentListSubId，支持 context.Context
+func (a *app) DepartmentListSubIdContext(ctx context.Context, deptIdList []interface{}) ([]interface{}, error) {
 	for _, deptId := range deptIdList {
-		params := url.Values{}
-		params = a.token.ApplyAccessToken(params)
-		payload, _ := json.Marshal(map[string]interface{}{"dept_id": deptId})
-		if response, err := http.Post(a.server+"/topapi/v2/department/listsubid?"+params.Encode(), "application/json;charset=utf-8", bytes.NewReader(payload)); err == nil {
-			if resp, err := io.ReadAll(response.Body); err == nil {
-				js, _ := json2.NewJson(resp)
-				if js.Get("errcode").MustInt() == 0 {
-					ids := js.Get("result").Get("dept_id_list").MustArray()
-					if len(ids) > 0 {
-						ids = a.DepartmentListSubId(ids)
-						deptIdList = append(deptIdList, ids...)
-					}
-				} else if js.Get("errcode").MustInt() == 88 && strings.Index(js.Get("errmsg").MustString(), "subcode=90018") > -1 {
-					time.Sleep(time.Second)
-					ids := a.DepartmentListSubId([]interface{}{deptId})
-					deptIdList = append(deptIdList, ids...)
-				}
+		js, err := a.doHttp(ctx, http.MethodPost, "/topapi/v2/department/listsubid", map[string]interface{}{"dept_id": deptId})
+		if err != nil {
+			return deptIdList, err
+		}
+		ids := js.Get("result").Get("dept_id_list").MustArray()
+		if len(ids) > 0 {
+			ids, err = a.DepartmentListSubIdContext(ctx, ids)
+			deptIdList = append(deptIdList, ids...)
+			if err != nil {
+				return deptIdList, err
 			}
 		}
 	}
-	res = deptIdList
-	return
+	return deptIdList, nil
 }
 
 // UserList POST https://oapi.dingtalk.com/topapi/v2/user/list?access_token=ACCESS_TOKEN
-func (a *app) UserList(id interface{}, cursor interface{}) (res []interface{}) {
-	params := url.Values{}
-	params = a.token.ApplyAccessToken(params)
-	payload, _ := json.Marshal(map[string]interface{}{
+func (a *app) UserList(id interface{}, cursor interface{}) ([]interface{}, error) {
+	return a.UserListContext(context.Background(), id, cursor)
+}
+
+// UserListContext 同 UserList，支持 context.Context
+func (a *app) UserListContext(ctx context.Context, id interface{}, cursor interface{}) ([]interface{}, error) {
+	js, err := a.doHttp(ctx, http.MethodPost, "/topapi/v2/user/list", map[string]interface{}{
 		"dept_id": id,
 		"cursor":  cursor,
 		"size":    100,
 	})
-	if response, err := http.Post(a.server+"/topapi/v2/user/list?"+params.Encode(), "application/json;charset=utf-8", bytes.NewReader(payload)); err == nil {
-		if resp, err := io.ReadAll(response.Body); err == nil {
-			js, _ := json2.NewJson(resp)
-			if js.Get("errcode").MustInt() == 0 {
-				res = js.Get("result").Get("list").MustArray()
-				if js.Get("result").Get("has_more").MustBool() == true {
-					time.Sleep(time.Second * 50)
-					cursor = js.Get("result").Get("next_cursor").MustInt64()
-					res = append(res, a.UserList(id, cursor)...)
-				}
-			} else if js.Get("errcode").MustInt() == 88 && strings.Index(js.Get("errmsg").MustString(), "subcode=90018") > -1 {
-				time.Sleep(time.Second)
-				res = append(res, a.UserList(id, cursor)...)
-			}
+	if err != nil {
+		return nil, err
+	}
+	res := js.Get("result").Get("list").MustArray()
+	if js.Get("result").Get("has_more").MustBool() == true {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(time.Second * 50):
+		}
+		cursor = js.Get("result").Get("next_cursor").MustInt64()
+		more, err := a.UserListContext(ctx, id, cursor)
+		res = append(res, more...)
+		if err != nil {
+			return res, err
 		}
 	}
-	return
+	return res, nil
 }
 
 // UserGet POST https://oapi.dingtalk.com/topapi/v2/user/get?access_token=ACCESS_TOKEN
-func (a *app) UserGet(id interface{}) (res map[string]interface{}) {
-	params := url.Values{}
-	params = a.token.ApplyAccessToken(params)
-	payload, _ := json.Marshal(map[string]interface{}{
+func (a *app) UserGet(id interface{}) (map[string]interface{}, error) {
+	return a.UserGetContext(context.Background(), id)
+}
+
+// UserGetContext 同 UserGet，支持 context.Context
+func (a *app) UserGetContext(ctx context.Context, id interface{}) (map[string]interface{}, error) {
+	js, err := a.doHttp(ctx, http.MethodPost, "/topapi/v2/user/get", map[string]interface{}{
 		"userid":   id,
 		"language": "zh_CN"})
-	if response, err := http.Post(a.server+"/topapi/v2/user/get?"+params.Encode(), "application/json;charset=utf-8", bytes.NewReader(payload)); err == nil {
-		if resp, err := io.ReadAll(response.Body); err == nil {
-			js, _ := json2.NewJson(resp)
-			if js.Get("errcode").MustInt() == 0 {
-				res = js.Get("result").MustMap()
-			} else if js.Get("errcode").MustInt() == 88 && strings.Index(js.Get("errmsg").MustString(), "subcode=90018") > -1 {
-				time.Sleep(time.Second)
-				res = a.UserGet(id)
-			}
-		}
+	if err != nil {
+		return nil, err
 	}
-	return
+	return js.Get("result").MustMap(), nil
 }
 
 // JsApiTickets POST https://api.dingtalk.com/v1.0/oauth2/jsapiTickets
-func (a *app) JsApiTickets() (ticket string) {
-	ticket, _ = a.token.Cache.Fetch("ticket" + a.token.Id)
-	if ticket == "" {
-		req, _ := http.NewRequest(http.MethodPost, "https://api.dingtalk.com/v1.0/oauth2/jsapiTickets", nil)
-		req.Header.Set("Content-Type", "application/json")
-		req.Header.Set("x-acs-dingtalk-access-token", a.token.GetAccessToken())
-		if response, err := http.DefaultClient.Do(req); err == nil {
-			if resp, err := io.ReadAll(response.Body); err == nil {
-				js, _ := json2.NewJson(resp)
-				if js.Get("jsapiTicket") != nil {
-					ticket = js.Get("jsapiTicket").MustString()
-					d := time.Duration(js.Get("expireIn").MustInt()) * time.Second
-					_ = a.token.Cache.Save("ticket:"+a.token.Id, ticket, d)
-				}
-			}
-		}
-	}
-	return
+func (a *app) JsApiTickets() (ticket string, err error) {
+	return a.JsApiTicketsContext(context.Background())
+}
+
+// JsApiTicketsContext 同 JsApiTickets，支持 context.Context
+func (a *app) JsApiTicketsContext(ctx context.Context) (ticket string, err error) {
+	return a.ticket.GetAccessTokenContext(ctx)
 }
 
 // GetUserInfo POST https://oapi.dingtalk.com/topapi/v2/user/getuserinfo
-func (a *app) GetUserInfo(code string) (res map[string]interface{}) {
-	params := url.Values{}
-	params = a.token.ApplyAccessToken(params)
-	payload, _ := json.Marshal(map[string]interface{}{
+func (a *app) GetUserInfo(code string) (map[string]interface{}, error) {
+	return a.GetUserInfoContext(context.Background(), code)
+}
+
+// GetUserInfoContext 同 GetUserInfo，支持 context.Context
+func (a *app) GetUserInfoContext(ctx context.Context, code string) (map[string]interface{}, error) {
+	js, err := a.doHttp(ctx, http.MethodPost, "/topapi/v2/user/getuserinfo", map[string]interface{}{
 		"code": code,
 	})
-	req, _ := http.NewRequest(http.MethodPost, a.server+"/topapi/v2/user/getuserinfo?"+params.Encode(), bytes.NewReader(payload))
-	if response, err := http.DefaultClient.Do(req); err == nil {
-		if resp, err := io.ReadAll(response.Body); err == nil {
-			js, _ := json2.NewJson(resp)
-			if js.Get("errcode").MustInt() == 0 {
-				res = js.Get("result").MustMap()
-			}
-		}
+	if err != nil {
+		return nil, err
 	}
-	return
+	return js.Get("result").MustMap(), nil
 }
 
 type Message struct {
@@ -286,9 +397,12 @@ type MessageMsgCard struct {
 }
 
 // MessageSend POST https://oapi.dingtalk.com/topapi/message/corpconversation/asyncsend_v2?access_token=ACCESS_TOKEN
-func (a *app) MessageSend(msg Message) (err error) {
-	params := url.Values{}
-	params = a.token.ApplyAccessToken(params)
+func (a *app) MessageSend(msg Message) error {
+	return a.MessageSendContext(context.Background(), msg)
+}
+
+// MessageSendContext 同 MessageSend，支持 context.Context
+func (a *app) MessageSendContext(ctx context.Context, msg Message) error {
 	if msg.AgentId == "" {
 		msg.AgentId = strconv.Itoa(a.config.AgentId)
 	}
@@ -298,15 +412,6 @@ func (a *app) MessageSend(msg Message) (err error) {
 	if msg.Msg.Card.Button == "" {
 		msg.Msg.Card.Button = "详情"
 	}
-	payload, _ := json.Marshal(msg)
-	req, _ := http.NewRequest(http.MethodPost, a.server+"/topapi/message/corpconversation/asyncsend_v2?"+params.Encode(), bytes.NewReader(payload))
-	if response, err := http.DefaultClient.Do(req); err == nil {
-		if resp, err := io.ReadAll(response.Body); err == nil {
-			js, _ := json2.NewJson(resp)
-			if js.Get("errcode").MustInt() != 0 {
-				err = errors.New(js.Get("errmsg").MustString())
-			}
-		}
-	}
-	return
+	_, err := a.doHttp(ctx, http.MethodPost, "/topapi/message/corpconversation/asyncsend_v2", msg)
+	return err
 }
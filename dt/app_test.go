@@ -0,0 +1,60 @@
+package dt
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/faabiosr/cachego/sync"
+)
+
+func TestRateLimitRetryCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/v1.0/oauth2/accessToken" {
+			_, _ = w.Write([]byte(`{"accessToken":"AT","expireIn":7200}`))
+			return
+		}
+		// 限流后取消，重试等待应立即返回
+		cancel()
+		_, _ = w.Write([]byte(`{"errcode":90018,"errmsg":"limited"}`))
+	}))
+	defer srv.Close()
+	a := NewApp(Config{AppKey: "k", AppSecret: "s", BaseURL: srv.URL, ApiBaseURL: srv.URL, Cache: sync.New()})
+
+	start := time.Now()
+	_, err := a.UserListContext(ctx, 1, 0)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("UserListContext() error = %v, want context.Canceled", err)
+	}
+	if d := time.Since(start); d > 500*time.Millisecond {
+		t.Errorf("UserListContext() returned after %v", d)
+	}
+}
+
+func TestMicroAppAllApps(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/v1.0/oauth2/accessToken" {
+			_, _ = w.Write([]byte(`{"accessToken":"AT","expireIn":7200}`))
+			return
+		}
+		// 缺少或类型错误的 agentId 应被跳过
+		_, _ = w.Write([]byte(`{"appList":[{"name":"none"},{"name":"string","agentId":"7"},{"name":"want","agentId":7}]}`))
+	}))
+	defer srv.Close()
+	a := NewApp(Config{AppKey: "k", AppSecret: "s", AgentId: 7, BaseURL: srv.URL, ApiBaseURL: srv.URL, Cache: sync.New()})
+
+	res, err := a.MicroAppAllApps()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res["name"] != "want" {
+		t.Errorf("MicroAppAllApps() = %v, want app named want", res)
+	}
+}
@@ -0,0 +1,45 @@
+package dt
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/leapig/tpp/util"
+)
+
+// errorKinds 钉钉错误码分类
+// doc https://open.dingtalk.com/document/orgapp/server-api-error-codes-1
+var errorKinds = map[int]error{
+	40014: util.ErrInvalidToken,
+	42001: util.ErrInvalidToken,
+	40035: util.ErrInvalidParam,
+	41001: util.ErrInvalidParam,
+	60011: util.ErrPermissionDenied,
+	60020: util.ErrPermissionDenied,
+	60003: util.ErrNotFound,
+	60121: util.ErrNotFound,
+	90018: util.ErrRateLimited,
+}
+
+// subCodeKinds 钉钉 errcode=88 时的子错误码分类
+var subCodeKinds = map[string]error{
+	"40014": util.ErrInvalidToken,
+	"42001": util.ErrInvalidToken,
+	"90018": util.ErrRateLimited,
+	"60011": util.ErrPermissionDenied,
+}
+
+// classify 补充 errcode=88 时按子错误码分类
+func classify(err error) error {
+	var e *util.Error
+	if !errors.As(err, &e) || e.ErrCode != 88 {
+		return err
+	}
+	for subCode, kind := range subCodeKinds {
+		if e.SubCode == subCode || strings.Contains(e.ErrMsg, "subcode="+subCode) {
+			e.Kind = kind
+			break
+		}
+	}
+	return err
+}
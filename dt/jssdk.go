@@ -0,0 +1,47 @@
+package dt
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/leapig/tpp/util"
+)
+
+// JsConfig dd.config 签名参数，可直接序列化后传给前端
+// doc https://open.dingtalk.com/document/orgapp/jsapi-authentication
+type JsConfig struct {
+	AgentId   string `json:"agentId"`
+	CorpId    string `json:"corpId"`
+	TimeStamp int64  `json:"timeStamp"`
+	NonceStr  string `json:"nonceStr"`
+	Signature string `json:"signature"`
+	// Type 0 表示企业内部应用或第三方企业应用
+	Type int `json:"type"`
+}
+
+// JsConfig 使用缓存的 jsapi_ticket 生成 pageURL 页面的 dd.config 签名参数
+func (a *app) JsConfig(pageURL string) (*JsConfig, error) {
+	return a.JsConfigContext(context.Background(), pageURL)
+}
+
+// JsConfigContext 同 JsConfig，支持 context.Context
+func (a *app) JsConfigContext(ctx context.Context, pageURL string) (*JsConfig, error) {
+	ticket, err := a.ticket.GetAccessTokenContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// 钉钉使用解码后的 url 计算签名
+	if decoded, err := url.QueryUnescape(pageURL); err == nil {
+		pageURL = decoded
+	}
+	config := &JsConfig{
+		AgentId:   strconv.Itoa(a.config.AgentId),
+		CorpId:    a.config.CorpId,
+		TimeStamp: time.Now().UnixMilli(),
+		NonceStr:  util.GetRandString(16),
+	}
+	config.Signature = util.JsSdkSignature(ticket, config.NonceStr, strconv.FormatInt(config.TimeStamp, 10), pageURL)
+	return config, nil
+}
@@ -0,0 +1,113 @@
+package dt
+
+import "encoding/json"
+
+// 事件类型
+// doc https://open.dingtalk.com/document/orgapp/event-list
+const (
+	EventCheckURL                 = "check_url"
+	EventUserAddOrg               = "user_add_org"
+	EventUserModifyOrg            = "user_modify_org"
+	EventUserLeaveOrg             = "user_leave_org"
+	EventUserActiveOrg            = "user_active_org"
+	EventOrgDeptCreate            = "org_dept_create"
+	EventOrgDeptModify            = "org_dept_modify"
+	EventOrgDeptRemove            = "org_dept_remove"
+	EventBpmsTaskChange           = "bpms_task_change"
+	EventBpmsInstanceChange       = "bpms_instance_change"
+	EventAttendanceCheckRecord    = "attendance_check_record"
+	EventAttendanceScheduleChange = "attendance_schedule_change"
+)
+
+// Event 未注册类型处理函数的事件，Raw 为解密后的明文JSON
+// EventId 为事件ID，未包含时以推送内容去重
+type Event struct {
+	EventType string          `json:"EventType"`
+	EventId   string          `json:"eventId"`
+	Raw       json.RawMessage `json:"-"`
+}
+
+// UserChangeEvent 通讯录用户增加、变更、离职、激活事件
+// doc https://open.dingtalk.com/document/orgapp/address-book-events
+type UserChangeEvent struct {
+	EventType string   `json:"EventType"`
+	CorpId    string   `json:"CorpId"`
+	TimeStamp string   `json:"TimeStamp"`
+	UserId    []string `json:"UserId"`
+}
+
+// DeptChangeEvent 通讯录部门创建、修改、删除事件
+type DeptChangeEvent struct {
+	EventType string  `json:"EventType"`
+	CorpId    string  `json:"CorpId"`
+	TimeStamp string  `json:"TimeStamp"`
+	DeptId    []int64 `json:"DeptId"`
+}
+
+// BpmsTaskChangeEvent 审批任务开始、结束、转交事件
+// Type 为 start、finish、cancel，Result 为 agree、refuse、redirect
+// doc https://open.dingtalk.com/document/orgapp/approval-events
+type BpmsTaskChangeEvent struct {
+	EventType         string `json:"EventType"`
+	ProcessInstanceId string `json:"processInstanceId"`
+	CorpId            string `json:"corpId"`
+	CreateTime        int64  `json:"createTime"`
+	FinishTime        int64  `json:"finishTime"`
+	Title             string `json:"title"`
+	Type              string `json:"type"`
+	StaffId           string `json:"staffId"`
+	Url               string `json:"url"`
+	ProcessCode       string `json:"processCode"`
+	Result            string `json:"result"`
+	Remark            string `json:"remark"`
+}
+
+// BpmsInstanceChangeEvent 审批实例开始、结束、终止事件
+// Type 为 start、finish、terminate，Result 为 agree、refuse
+type BpmsInstanceChangeEvent struct {
+	EventType         string `json:"EventType"`
+	ProcessInstanceId string `json:"processInstanceId"`
+	CorpId            string `json:"corpId"`
+	CreateTime        int64  `json:"createTime"`
+	FinishTime        int64  `json:"finishTime"`
+	Title             string `json:"title"`
+	Type              string `json:"type"`
+	StaffId           string `json:"staffId"`
+	Url               string `json:"url"`
+	ProcessCode       string `json:"processCode"`
+	Result            string `json:"result"`
+}
+
+// AttendanceCheckRecordEvent 员工打卡事件
+// doc https://open.dingtalk.com/document/orgapp/attendance-events
+type AttendanceCheckRecordEvent struct {
+	EventType string `json:"EventType"`
+	CorpId    string `json:"CorpId"`
+	TimeStamp string `json:"TimeStamp"`
+	DataList  []struct {
+		UserId         string  `json:"userId"`
+		CorpId         string  `json:"corpId"`
+		CheckTime      int64   `json:"checkTime"`
+		Address        string  `json:"address"`
+		LocationMethod string  `json:"locationMethod"`
+		DeviceName     string  `json:"deviceName"`
+		DeviceId       string  `json:"deviceId"`
+		Latitude       float64 `json:"latitude"`
+		Longitude      float64 `json:"longitude"`
+		BizId          string  `json:"bizId"`
+	} `json:"DataList"`
+}
+
+// AttendanceScheduleChangeEvent 员工排班变更事件
+type AttendanceScheduleChangeEvent struct {
+	EventType string `json:"EventType"`
+	CorpId    string `json:"CorpId"`
+	TimeStamp string `json:"TimeStamp"`
+	DataList  []struct {
+		UserId    string `json:"userId"`
+		PlanId    int64  `json:"planId"`
+		ClassId   int64  `json:"classId"`
+		CheckType string `json:"checkType"`
+		PlanTime  int64  `json:"planTime"`
+	} `json:"DataList"`
+}
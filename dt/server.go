@@ -0,0 +1,229 @@
+package dt
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/faabiosr/cachego"
+	"github.com/leapig/tpp/logger"
+	"github.com/leapig/tpp/util"
+)
+
+// maxPushSize 推送消息体上限
+const maxPushSize = 1 << 20
+
+// handler 解析明文JSON并调用处理函数
+type handler func(ctx context.Context, raw []byte) error
+
+// Server 钉钉 HTTP 事件订阅回调服务，check_url 与业务事件均回复加密的 success
+// doc https://open.dingtalk.com/document/orgapp/configure-event-subcription
+type Server struct {
+	crypt    *util.DingTalkCrypto
+	handlers map[string]handler
+	fallback func(ctx context.Context, event *Event) error
+	cache    cachego.Cache
+	locker   util.Locker
+	guard    *util.CallbackGuard
+}
+
+// Server 创建回调服务，使用 Config.Token 与 Config.AesKey 校验、解密事件
+// 企业内部应用以 AppKey 加密，未配置 AppKey 时使用 CorpId
+func (a *app) Server() *Server {
+	s := &Server{handlers: map[string]handler{}, cache: a.config.Cache, locker: a.config.Locker}
+	ownerKey := a.config.AppKey
+	if ownerKey == "" {
+		ownerKey = a.config.CorpId
+	}
+	// NewDingTalkCrypto 在密钥不合法时 panic，此处预先校验
+	if _, err := base64.StdEncoding.DecodeString(a.config.AesKey + "="); err == nil && len(a.config.AesKey) == 43 {
+		s.crypt = util.NewDingTalkCrypto(a.config.Token, a.config.AesKey, ownerKey)
+	}
+	return s
+}
+
+func on[T any](s *Server, eventType string, h func(ctx context.Context, event *T) error) *Server {
+	s.handlers[eventType] = func(ctx context.Context, raw []byte) error {
+		event := new(T)
+		if err := json.Unmarshal(raw, event); err != nil {
+			return err
+		}
+		return h(ctx, event)
+	}
+	return s
+}
+
+// OnUserAddOrg 通讯录用户增加事件
+func (s *Server) OnUserAddOrg(h func(ctx context.Context, event *UserChangeEvent) error) *Server {
+	return on(s, EventUserAddOrg, h)
+}
+
+// OnUserModifyOrg 通讯录用户更改事件
+func (s *Server) OnUserModifyOrg(h func(ctx context.Context, event *UserChangeEvent) error) *Server {
+	return on(s, EventUserModifyOrg, h)
+}
+
+// OnUserLeaveOrg 通讯录用户离职事件
+func (s *Server) OnUserLeaveOrg(h func(ctx context.Context, event *UserChangeEvent) error) *Server {
+	return on(s, EventUserLeaveOrg, h)
+}
+
+// OnUserActiveOrg 用户加入企业后的激活事件
+func (s *Server) OnUserActiveOrg(h func(ctx context.Context, event *UserChangeEvent) error) *Server {
+	return on(s, EventUserActiveOrg, h)
+}
+
+// OnOrgDeptCreate 通讯录企业部门创建事件
+func (s *Server) OnOrgDeptCreate(h func(ctx context.Context, event *DeptChangeEvent) error) *Server {
+	return on(s, EventOrgDeptCreate, h)
+}
+
+// OnOrgDeptModify 通讯录企业部门更改事件
+func (s *Server) OnOrgDeptModify(h func(ctx context.Context, event *DeptChangeEvent) error) *Server {
+	return on(s, EventOrgDeptModify, h)
+}
+
+// OnOrgDeptRemove 通讯录企业部门删除事件
+func (s *Server) OnOrgDeptRemove(h func(ctx context.Context, event *DeptChangeEvent) error) *Server {
+	return on(s, EventOrgDeptRemove, h)
+}
+
+// OnBpmsTaskChange 审批任务开始、结束、转交事件
+func (s *Server) OnBpmsTaskChange(h func(ctx context.Context, event *BpmsTaskChangeEvent) error) *Server {
+	return on(s, EventBpmsTaskChange, h)
+}
+
+// OnBpmsInstanceChange 审批实例开始、结束事件
+func (s *Server) OnBpmsInstanceChange(h func(ctx context.Context, event *BpmsInstanceChangeEvent) error) *Server {
+	return on(s, EventBpmsInstanceChange, h)
+}
+
+// OnAttendanceCheckRecord 员工打卡事件
+func (s *Server) OnAttendanceCheckRecord(h func(ctx context.Context, event *AttendanceCheckRecordEvent) error) *Server {
+	return on(s, EventAttendanceCheckRecord, h)
+}
+
+// OnAttendanceScheduleChange 员工排班变更事件
+func (s *Server) OnAttendanceScheduleChange(h func(ctx context.Context, event *AttendanceScheduleChangeEvent) error) *Server {
+	return on(s, EventAttendanceScheduleChange, h)
+}
+
+// Guard 启用重放保护与去重，拒绝时间戳超出 window 的事件，window 内重复的事件直接回复 success
+// 使用 Config.Cache 记录已处理的事件，配置 Config.Locker 时跨实例加锁去重，window 为 0 时使用 util.DefaultCallbackWindow
+func (s *Server) Guard(window time.Duration) *Server {
+	s.guard = util.NewCallbackGuard(s.cache, s.locker, window)
+	return s
+}
+
+// OnDefault 未注册类型的事件
+func (s *Server) OnDefault(h func(ctx context.Context, event *Event) error) *Server {
+	s.fallback = h
+	return s
+}
+
+// ServeHTTP 解密事件并分发，处理成功后回复加密的 success
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.crypt == nil {
+		http.Error(w, "aes key not configured", http.StatusInternalServerError)
+		return
+	}
+	query := r.URL.Query()
+	if s.guard != nil {
+		if err := s.guard.Fresh(query.Get("timestamp")); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+	}
+	var body struct {
+		Encrypt string `json:"encrypt"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxPushSize)).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	signature := query.Get("msg_signature")
+	if signature == "" {
+		signature = query.Get("signature")
+	}
+	plaintext, err := s.crypt.GetDecryptMsg(signature, query.Get("timestamp"), query.Get("nonce"), body.Encrypt)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if err = s.dispatch(r.Context(), []byte(plaintext)); err != nil {
+		logger.Errorf("dt event: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	out, err := s.success()
+	if err != nil {
+		logger.Errorf("dt reply: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", util.ContentType)
+	_, _ = w.Write(out)
+}
+
+// success 加密的 success 回复
+func (s *Server) success() ([]byte, error) {
+	timestamp := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	nonce := util.GetRandString(8)
+	encrypt, signature, err := s.crypt.GetEncryptMsg("success", timestamp, nonce)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(map[string]string{
+		"msg_signature": signature,
+		"timeStamp":     timestamp,
+		"nonce":         nonce,
+		"encrypt":       encrypt,
+	})
+}
+
+// dispatch 解析事件类型并调用处理函数，check_url 与重复事件无需处理
+func (s *Server) dispatch(ctx context.Context, plaintext []byte) error {
+	event := &Event{Raw: plaintext}
+	if err := json.Unmarshal(plaintext, event); err != nil {
+		return fmt.Errorf("failed to parse event: %w", err)
+	}
+	if event.EventType == EventCheckURL {
+		return nil
+	}
+	if s.guard == nil {
+		return s.handle(ctx, event)
+	}
+	id := util.CallbackId(plaintext, event.EventId)
+	if err := s.guard.Accept(ctx, util.PlatformDT, id); err != nil {
+		if errors.Is(err, util.ErrDuplicateCallback) {
+			return nil
+		}
+		return err
+	}
+	err := s.handle(ctx, event)
+	if err != nil {
+		s.guard.Release(util.PlatformDT, id)
+	}
+	return err
+}
+
+// handle 调用事件类型对应的处理函数
+func (s *Server) handle(ctx context.Context, event *Event) error {
+	if h, ok := s.handlers[event.EventType]; ok {
+		return h(ctx, event.Raw)
+	}
+	if s.fallback != nil {
+		return s.fallback(ctx, event)
+	}
+	return nil
+}
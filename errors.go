@@ -0,0 +1,15 @@
+package tpp
+
+import "github.com/leapig/tpp/util"
+
+// Error 平台接口调用错误，包含平台、HTTP状态码、平台错误码/错误信息、请求ID及原始响应体
+type Error = util.Error
+
+// 常见错误分类，可通过 errors.Is(err, tpp.ErrInvalidToken) 判断
+var (
+	ErrInvalidToken     = util.ErrInvalidToken
+	ErrRateLimited      = util.ErrRateLimited
+	ErrPermissionDenied = util.ErrPermissionDenied
+	ErrNotFound         = util.ErrNotFound
+	ErrInvalidParam     = util.ErrInvalidParam
+)
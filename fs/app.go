@@ -2,72 +2,195 @@ package fs
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
-	"errors"
-	"fmt"
 	json2 "github.com/bitly/go-simplejson"
+	"github.com/faabiosr/cachego"
 	"github.com/faabiosr/cachego/file"
 	"github.com/leapig/tpp/util"
 	"io"
 	"net/http"
 	"net/url"
 	"os"
-	"time"
 )
 
 type App interface {
+	Key() string
 	Id() string
-	Test() string
-	TenantQuery() (res map[string]interface{})
-	Applications() (res map[string]interface{})
-	AppVisibility() map[string]interface{}
-	AppContactsRangeConfiguration() map[string]interface{}
-	DepartmentListSubId(deptIdList []interface{}) []interface{}
-	DepartmentsChildren(departmentId string, pageToken string) []interface{}
-	DepartmentGet(id string) (res map[string]interface{})
-	UsersFindByDepartment(id string, pageToken string) []interface{}
-	UserGet(id string) map[string]interface{}
-	UserIdGet(id string) map[string]interface{}
-	AppAccessTokenInternal() string
-	TicketGet() (ticket string)
-	AuthorizationCode(code string) map[string]interface{}
+	Close()
+	Test() (string, error)
+	TestContext(ctx context.Context) (string, error)
+	TenantQuery() (map[string]interface{}, error)
+	TenantQueryContext(ctx context.Context) (map[string]interface{}, error)
+	Applications() (map[string]interface{}, error)
+	ApplicationsContext(ctx context.Context) (map[string]interface{}, error)
+	AppVisibility() (map[string]interface{}, error)
+	AppVisibilityContext(ctx context.Context) (map[string]interface{}, error)
+	AppContactsRangeConfiguration() (map[string]interface{}, error)
+	AppContactsRangeConfigurationContext(ctx context.Context) (map[string]interface{}, error)
+	DepartmentListSubId(deptIdList []interface{}) ([]interface{}, error)
+	DepartmentListSubIdContext(ctx context.Context, deptIdList []interface{}) ([]interface{}, error)
+	DepartmentsChildren(departmentId string, pageToken string) ([]interface{}, error)
+	DepartmentsChildrenContext(ctx context.Context, departmentId string, pageToken string) ([]interface{}, error)
+	DepartmentGet(id string) (map[string]interface{}, error)
+	DepartmentGetContext(ctx context.Context, id string) (map[string]interface{}, error)
+	UsersFindByDepartment(id string, pageToken string) ([]interface{}, error)
+	UsersFindByDepartmentContext(ctx context.Context, id string, pageToken string) ([]interface{}, error)
+	UserGet(id string) (map[string]interface{}, error)
+	UserGetContext(ctx context.Context, id string) (map[string]interface{}, error)
+	UserIdGet(id string) (map[string]interface{}, error)
+	UserIdGetContext(ctx context.Context, id string) (map[string]interface{}, error)
+	AppAccessTokenInternal() (string, error)
+	AppAccessTokenInternalContext(ctx context.Context) (string, error)
+	TicketGet() (string, error)
+	TicketGetContext(ctx context.Context) (string, error)
+	JsConfig(url string) (*JsConfig, error)
+	JsConfigContext(ctx context.Context, url string) (*JsConfig, error)
+	AuthorizationCode(code string) (map[string]interface{}, error)
+	AuthorizationCodeContext(ctx context.Context, code string) (map[string]interface{}, error)
 	MessageSend(msg Message) error
+	MessageSendContext(ctx context.Context, msg Message) error
+	Server() *Server
 }
 
 type Config struct {
-	AppID     string `json:"appId"`
-	AppSecret string `json:"appSecret"`
+	Key       string `json:"key" yaml:"key"`
+	AppID     string `json:"appId" yaml:"appId"`
+	AppSecret string `json:"appSecret" yaml:"appSecret"`
+	// VerificationToken 事件订阅 Verification Token
+	VerificationToken string `json:"verificationToken" yaml:"verificationToken"`
+	// EncryptKey 事件订阅 Encrypt Key，为空时事件不加密
+	EncryptKey string `json:"encryptKey" yaml:"encryptKey"`
+	// Cache 令牌缓存，为空时使用系统临时目录下的文件缓存
+	Cache cachego.Cache `json:"cache" yaml:"-"`
+	// BaseURL 服务地址，为空时使用平台默认地址
+	BaseURL string `json:"baseURL" yaml:"baseURL"`
+	// Proxy 出口代理地址
+	Proxy string `json:"proxy" yaml:"proxy"`
+	// HTTPClient 自定义请求客户端，优先于 Transport 与 Proxy
+	HTTPClient *http.Client `json:"-" yaml:"-"`
+	// Transport 自定义 Transport
+	Transport http.RoundTripper `json:"-" yaml:"-"`
+	// Locker 多实例部署时的令牌刷新锁
+	Locker util.Locker `json:"-" yaml:"-"`
+	// Refresher 后台预刷新，为空时仅在令牌过期后刷新
+	Refresher *util.Refresher `json:"-" yaml:"-"`
 }
 
 type app struct {
 	config Config
 	token  util.AccessToken
+	// appToken app_access_token
+	appToken util.AccessToken
+	// ticket jsapi_ticket
+	ticket util.AccessToken
+	client *util.Client
 	server string
 }
 
 func NewApp(config Config) App {
-	server := "https://open.feishu.cn"
+	if config.Cache == nil {
+		config.Cache = file.New(os.TempDir())
+	}
+	server := util.BaseURL(config.BaseURL, "https://open.feishu.cn")
+	client := &util.Client{Platform: util.PlatformFS, Kinds: errorKinds, HttpClient: util.NewHTTPClient(config.HTTPClient, config.Transport, config.Proxy)}
+	refresh := func(path string) func(ctx context.Context) ([]byte, error) {
+		return func(ctx context.Context) ([]byte, error) {
+			payload, _ := json.Marshal(map[string]string{
+				"app_id":     config.AppID,
+				"app_secret": config.AppSecret,
+			})
+			req, err := http.NewRequestWithContext(ctx, http.MethodPost, server+path, bytes.NewReader(payload))
+			if err != nil {
+				return nil, util.NewError(util.PlatformFS, err)
+			}
+			return client.Fetch(req)
+		}
+	}
 	// 管理token
-	return &app{
+	a := &app{
 		server: server,
 		config: config,
+		client: client,
 		token: util.AccessToken{
-			Id:    config.AppID + config.AppSecret,
-			Cache: file.New(os.TempDir()),
-			GetRefreshRequestFunc: func() []byte {
-				payload, _ := json.Marshal(map[string]string{
-					"app_id":     config.AppID,
-					"app_secret": config.AppSecret,
-				})
-				req, _ := http.NewRequest(http.MethodPost,
-					server+"/open-apis/auth/v3/tenant_access_token/internal", bytes.NewReader(payload))
-				response, _ := http.DefaultClient.Do(req)
-				resp, _ := io.ReadAll(response.Body)
-				fmt.Printf("\n\n%s\n\n", string(resp))
-				return resp
-			},
+			Id:                    util.CacheKey(util.PlatformFS, config.AppID, config.AppSecret),
+			Cache:                 config.Cache,
+			Locker:                config.Locker,
+			GetRefreshRequestFunc: refresh("/open-apis/auth/v3/tenant_access_token/internal"),
+		},
+		appToken: util.AccessToken{
+			Id:                    "app:" + util.CacheKey(util.PlatformFS, config.AppID, config.AppSecret),
+			Cache:                 config.Cache,
+			Locker:                config.Locker,
+			GetRefreshRequestFunc: refresh("/open-apis/auth/v3/app_access_token/internal"),
+			Parse:                 util.ParseJSON("app_access_token", "expire"),
 		},
 	}
+	a.ticket = util.AccessToken{
+		Id:     "ticket:" + a.token.Id,
+		Cache:  config.Cache,
+		Locker: config.Locker,
+		GetRefreshRequestFunc: func(ctx context.Context) ([]byte, error) {
+			js, err := a.doHttpWithAppToken(ctx, http.MethodPost, "/open-apis/jssdk/ticket/get", nil, nil)
+			if err != nil {
+				return nil, err
+			}
+			return js.Encode()
+		},
+		Parse: util.ParseJSON("data.ticket", "data.expire_in"),
+	}
+	if config.Refresher != nil {
+		config.Refresher.Add(a.token)
+		config.Refresher.Add(a.appToken)
+		config.Refresher.Add(a.ticket)
+	}
+	return a
+}
+
+// doHttp 附加 tenant_access_token 后执行请求并解析JSON响应
+func (a *app) doHttp(ctx context.Context, method string, path string, params url.Values, body io.Reader) (*json2.Json, error) {
+	return a.doHttpWith(ctx, a.token, method, path, params, body)
+}
+
+// doHttpWithAppToken 附加 app_access_token 后执行请求并解析JSON响应
+func (a *app) doHttpWithAppToken(ctx context.Context, method string, path string, params url.Values, body io.Reader) (*json2.Json, error) {
+	return a.doHttpWith(ctx, a.appToken, method, path, params, body)
+}
+
+// doHttpWith 以 Authorization: Bearer 附加令牌后执行请求，令牌失效时刷新后重试一次
+func (a *app) doHttpWith(ctx context.Context, token util.AccessToken, method string, path string, params url.Values, body io.Reader) (js *json2.Json, err error) {
+	payload, err := util.ReadBody(body)
+	if err != nil {
+		return nil, util.NewError(util.PlatformFS, err)
+	}
+	err = token.WithAccessToken(ctx, func(token string) error {
+		req, err := a.newRequest(ctx, method, path, params, util.BodyReader(payload))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json; charset=utf-8")
+		js, err = a.client.Do(req)
+		return err
+	})
+	return
+}
+
+func (a *app) newRequest(ctx context.Context, method string, path string, params url.Values, body io.Reader) (*http.Request, error) {
+	uri := a.server + path
+	if len(params) > 0 {
+		uri += "?" + params.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, uri, body)
+	if err != nil {
+		return nil, util.NewError(util.PlatformFS, err)
+	}
+	return req, nil
+}
+
+// Key 获取当前实例Key
+func (a *app) Key() string {
+	return a.config.Key
 }
 
 // Id 获取当前实例ID
@@ -75,89 +198,110 @@ func (a *app) Id() string {
 	return a.config.AppID
 }
 
+// Close 停止实例令牌的后台预刷新，实例不再使用时调用
+func (a *app) Close() {
+	if a.config.Refresher != nil {
+		a.config.Refresher.Remove(a.token, a.appToken, a.ticket)
+	}
+}
+
 // Test 校验是否配置是否正常（返回access_token）
-func (a *app) Test() string {
-	return a.token.GetAccessToken()
+func (a *app) Test() (string, error) {
+	return a.TestContext(context.Background())
+}
+
+// TestContext 同 Test，支持 context.Context
+func (a *app) TestContext(ctx context.Context) (string, error) {
+	return a.token.GetAccessTokenContext(ctx)
 }
 
 // TenantQuery GET https://open.feishu.cn/open-apis/tenant/v2/tenant/query
-func (a *app) TenantQuery() (res map[string]interface{}) {
-	req, _ := http.NewRequest(http.MethodGet, a.server+"/open-apis/tenant/v2/tenant/query", nil)
-	a.token.SetLarkAccessToken(req.Header)
-	if response, err := http.DefaultClient.Do(req); err == nil {
-		if resp, err := io.ReadAll(response.Body); err == nil {
-			js, _ := json2.NewJson(resp)
-			if js.Get("code").MustInt() == 0 {
-				res = js.Get("data").Get("tenant").MustMap()
-			}
-		}
+func (a *app) TenantQuery() (map[string]interface{}, error) {
+	return a.TenantQueryContext(context.Background())
+}
+
+// TenantQueryContext 同 TenantQuery，支持 context.Context
+func (a *app) TenantQueryContext(ctx context.Context) (map[string]interface{}, error) {
+	js, err := a.doHttp(ctx, http.MethodGet, "/open-apis/tenant/v2/tenant/query", nil, nil)
+	if err != nil {
+		return nil, err
 	}
-	return
+	return js.Get("data").Get("tenant").MustMap(), nil
 }
 
 // Applications GET /open-apis/application/v6/applications/:app_id
-func (a *app) Applications() (res map[string]interface{}) {
+func (a *app) Applications() (map[string]interface{}, error) {
+	return a.ApplicationsContext(context.Background())
+}
+
+// ApplicationsContext 同 Applications，支持 context.Context
+func (a *app) ApplicationsContext(ctx context.Context) (map[string]interface{}, error) {
 	params := url.Values{}
 	params.Add("lang", "zh_cn")
-	req, _ := http.NewRequest(http.MethodGet, a.server+"/open-apis/application/v6/applications/"+a.config.AppID+"?"+params.Encode(), nil)
-	a.token.SetLarkAccessToken(req.Header)
-	if response, err := http.DefaultClient.Do(req); err == nil {
-		if resp, err := io.ReadAll(response.Body); err == nil {
-			js, _ := json2.NewJson(resp)
-			if js.Get("code").MustInt() == 0 {
-				res = js.Get("data").Get("app").MustMap()
-			}
-		}
+	js, err := a.doHttp(ctx, http.MethodGet, "/open-apis/application/v6/applications/"+a.config.AppID, params, nil)
+	if err != nil {
+		return nil, err
 	}
-	return
+	return js.Get("data").Get("app").MustMap(), nil
 }
 
 // AppVisibility https://open.feishu.cn/open-apis/application/v2/app/visibility
-func (a *app) AppVisibility() (res map[string]interface{}) {
+func (a *app) AppVisibility() (map[string]interface{}, error) {
+	return a.AppVisibilityContext(context.Background())
+}
+
+// AppVisibilityContext 同 AppVisibility，支持 context.Context
+func (a *app) AppVisibilityContext(ctx context.Context) (map[string]interface{}, error) {
 	params := url.Values{}
 	params.Add("app_id", a.config.AppID)
-	req, _ := http.NewRequest(http.MethodGet, a.server+"/open-apis/application/v2/app/visibility?"+params.Encode(), nil)
-	a.token.SetLarkAccessToken(req.Header)
-	if response, err := http.DefaultClient.Do(req); err == nil {
-		if resp, err := io.ReadAll(response.Body); err == nil {
-			js, _ := json2.NewJson(resp)
-			if js.Get("code").MustInt() == 0 {
-				res = js.Get("data").MustMap()
-			}
-		}
+	js, err := a.doHttp(ctx, http.MethodGet, "/open-apis/application/v2/app/visibility", params, nil)
+	if err != nil {
+		return nil, err
 	}
-	return res
+	return js.Get("data").MustMap(), nil
 }
 
 // AppContactsRangeConfiguration GET https://open.feishu.cn/open-apis/application/v6/applications/:app_id/contacts_range_configuration
-func (a app) AppContactsRangeConfiguration() (res map[string]interface{}) {
+func (a *app) AppContactsRangeConfiguration() (map[string]interface{}, error) {
+	return a.AppContactsRangeConfigurationContext(context.Background())
+}
+
+// AppContactsRangeConfigurationContext 同 AppContactsRangeConfiguration，支持 context.Context
+func (a *app) AppContactsRangeConfigurationContext(ctx context.Context) (map[string]interface{}, error) {
 	params := url.Values{}
 	params.Add("page_size", "100")
 	params.Add("department_id_type", "department_id")
 	params.Add("user_id_type", "user_id")
-	req, _ := http.NewRequest(http.MethodGet, a.server+"/open-apis/application/v6/applications/"+a.config.AppID+"/contacts_range_configuration", nil)
-	a.token.SetLarkAccessToken(req.Header)
-	if response, err := http.DefaultClient.Do(req); err == nil {
-		if resp, err := io.ReadAll(response.Body); err == nil {
-			js, _ := json2.NewJson(resp)
-			if js.Get("code").MustInt() == 0 {
-				res = js.Get("data").Get("contacts_range").MustMap()
-			}
-		}
+	js, err := a.doHttp(ctx, http.MethodGet, "/open-apis/application/v6/applications/"+a.config.AppID+"/contacts_range_configuration", params, nil)
+	if err != nil {
+		return nil, err
 	}
-	return
+	return js.Get("data").Get("contacts_range").MustMap(), nil
 }
 
-func (a *app) DepartmentListSubId(deptIdList []interface{}) (res []interface{}) {
+func (a *app) DepartmentListSubId(deptIdList []interface{}) ([]interface{}, error) {
+	return a.DepartmentListSubIdContext(context.Background(), deptIdList)
+}
+
+// DepartmentListSubIdContext 同 DepartmentListSubId，支持 context.Context
+func (a *app) DepartmentListSubIdContext(ctx context.Context, deptIdList []interface{}) ([]interface{}, error) {
 	for _, deptId := range deptIdList {
-		deptIdList = append(deptIdList, a.DepartmentsChildren(deptId.(string), "")...)
+		children, err := a.DepartmentsChildrenContext(ctx, deptId.(string), "")
+		deptIdList = append(deptIdList, children...)
+		if err != nil {
+			return deptIdList, err
+		}
 	}
-	res = deptIdList
-	return
+	return deptIdList, nil
 }
 
 // DepartmentsChildren GET https://open.feishu.cn/open-apis/contact/v3/departments/:department_id/children
-func (a *app) DepartmentsChildren(departmentId string, pageToken string) (res []interface{}) {
+func (a *app) DepartmentsChildren(departmentId string, pageToken string) (res []interface{}, err error) {
+	return a.DepartmentsChildrenContext(context.Background(), departmentId, pageToken)
+}
+
+// DepartmentsChildrenContext 同 DepartmentsChildren，支持 context.Context
+func (a *app) DepartmentsChildrenContext(ctx context.Context, departmentId string, pageToken string) (res []interface{}, err error) {
 	params := url.Values{}
 	if pageToken != "" {
 		params.Add("pageToken", pageToken)
@@ -165,44 +309,47 @@ func (a *app) DepartmentsChildren(departmentId string, pageToken string) (res []
 	params.Add("department_id_type", "department_id")
 	params.Add("page_size", "50")
 	params.Add("fetch_child", "true")
-	req, _ := http.NewRequest(http.MethodGet, a.server+"/open-apis/contact/v3/departments/"+departmentId+"/children?"+params.Encode(), nil)
-	a.token.SetLarkAccessToken(req.Header)
-	if response, err := http.DefaultClient.Do(req); err == nil {
-		if resp, err := io.ReadAll(response.Body); err == nil {
-			js, _ := json2.NewJson(resp)
-			if js.Get("code").MustInt() == 0 {
-				for _, item := range js.Get("data").Get("items").MustArray() {
-					res = append(res, item.(map[string]interface{})["department_id"])
-				}
-				if js.Get("data").Get("has_more").MustBool() == true {
-					pageToken = js.Get("data").Get("page_token").MustString()
-					res = append(res, a.DepartmentsChildren(departmentId, pageToken)...)
-				}
-			}
+	js, err := a.doHttp(ctx, http.MethodGet, "/open-apis/contact/v3/departments/"+departmentId+"/children", params, nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range js.Get("data").Get("items").MustArray() {
+		res = append(res, item.(map[string]interface{})["department_id"])
+	}
+	if js.Get("data").Get("has_more").MustBool() == true {
+		pageToken = js.Get("data").Get("page_token").MustString()
+		more, err := a.DepartmentsChildrenContext(ctx, departmentId, pageToken)
+		res = append(res, more...)
+		if err != nil {
+			return res, err
 		}
 	}
 	return
 }
 
 // DepartmentGet GET https://open.feishu.cn/open-apis/contact/v3/departments/:department_id
-func (a *app) DepartmentGet(id string) (res map[string]interface{}) {
+func (a *app) DepartmentGet(id string) (map[string]interface{}, error) {
+	return a.DepartmentGetContext(context.Background(), id)
+}
+
+// DepartmentGetContext 同 DepartmentGet，支持 context.Context
+func (a *app) DepartmentGetContext(ctx context.Context, id string) (map[string]interface{}, error) {
 	params := url.Values{}
 	params.Add("department_id_type", "department_id")
-	req, _ := http.NewRequest(http.MethodGet, a.server+"/open-apis/contact/v3/departments/"+id+"?"+params.Encode(), nil)
-	a.token.SetLarkAccessToken(req.Header)
-	if response, err := http.DefaultClient.Do(req); err == nil {
-		if resp, err := io.ReadAll(response.Body); err == nil {
-			js, _ := json2.NewJson(resp)
-			if js.Get("code").MustInt() == 0 {
-				res = js.Get("data").Get("department").MustMap()
-			}
-		}
+	js, err := a.doHttp(ctx, http.MethodGet, "/open-apis/contact/v3/departments/"+id, params, nil)
+	if err != nil {
+		return nil, err
 	}
-	return
+	return js.Get("data").Get("department").MustMap(), nil
 }
 
 // UsersFindByDepartment GET https://open.feishu.cn/open-apis/contact/v3/users/find_by_department
-func (a *app) UsersFindByDepartment(id string, pageToken string) (res []interface{}) {
+func (a *app) UsersFindByDepartment(id string, pageToken string) (res []interface{}, err error) {
+	return a.UsersFindByDepartmentContext(context.Background(), id, pageToken)
+}
+
+// UsersFindByDepartmentContext 同 UsersFindByDepartment，支持 context.Context
+func (a *app) UsersFindByDepartmentContext(ctx context.Context, id string, pageToken string) (res []interface{}, err error) {
 	params := url.Values{}
 	if pageToken != "" {
 		params.Add("page_token", pageToken)
@@ -210,122 +357,95 @@ func (a *app) UsersFindByDepartment(id string, pageToken string) (res []interfac
 	params.Add("department_id_type", "department_id")
 	params.Add("department_id", id)
 	params.Add("page_size", "50")
-	req, _ := http.NewRequest(http.MethodGet, a.server+"/open-apis/contact/v3/users/find_by_department?"+params.Encode(), nil)
-	a.token.SetLarkAccessToken(req.Header)
-	if response, err := http.DefaultClient.Do(req); err == nil {
-		if resp, err := io.ReadAll(response.Body); err == nil {
-			js, _ := json2.NewJson(resp)
-			if js.Get("code").MustInt() == 0 {
-				if js.Get("data").Get("items") != nil {
-					res = js.Get("data").Get("items").MustArray()
-				}
-				if js.Get("data").Get("has_more").MustBool() == true {
-					pageToken = js.Get("data").Get("page_token").MustString()
-					res = append(res, a.DepartmentsChildren(id, pageToken)...)
-				}
-			}
+	js, err := a.doHttp(ctx, http.MethodGet, "/open-apis/contact/v3/users/find_by_department", params, nil)
+	if err != nil {
+		return nil, err
+	}
+	res = js.Get("data").Get("items").MustArray()
+	if js.Get("data").Get("has_more").MustBool() == true {
+		pageToken = js.Get("data").Get("page_token").MustString()
+		more, err := a.UsersFindByDepartmentContext(ctx, id, pageToken)
+		res = append(res, more...)
+		if err != nil {
+			return res, err
 		}
 	}
 	return
 }
 
 // UserGet GET https://open.feishu.cn/open-apis/contact/v3/users/:user_id
-func (a *app) UserGet(userId string) (res map[string]interface{}) {
+func (a *app) UserGet(userId string) (map[string]interface{}, error) {
+	return a.UserGetContext(context.Background(), userId)
+}
+
+// UserGetContext 同 UserGet，支持 context.Context
+func (a *app) UserGetContext(ctx context.Context, userId string) (map[string]interface{}, error) {
 	params := url.Values{}
 	params.Add("department_id_type", "department_id")
 	params.Add("user_id_type", "user_id")
-	req, _ := http.NewRequest(http.MethodGet, a.server+"/open-apis/contact/v3/users/"+userId+"?"+params.Encode(), nil)
-	a.token.SetLarkAccessToken(req.Header)
-	if response, err := http.DefaultClient.Do(req); err == nil {
-		if resp, err := io.ReadAll(response.Body); err == nil {
-			js, _ := json2.NewJson(resp)
-			if js.Get("code").MustInt() == 0 {
-				res = js.Get("data").Get("user").MustMap()
-			}
-		}
+	js, err := a.doHttp(ctx, http.MethodGet, "/open-apis/contact/v3/users/"+userId, params, nil)
+	if err != nil {
+		return nil, err
 	}
-	return
+	return js.Get("data").Get("user").MustMap(), nil
 }
 
 // UserIdGet GET https://open.feishu.cn/open-apis/contact/v3/users/:user_id
-func (a *app) UserIdGet(openId string) (res map[string]interface{}) {
+func (a *app) UserIdGet(openId string) (map[string]interface{}, error) {
+	return a.UserIdGetContext(context.Background(), openId)
+}
+
+// UserIdGetContext 同 UserIdGet，支持 context.Context
+func (a *app) UserIdGetContext(ctx context.Context, openId string) (map[string]interface{}, error) {
 	params := url.Values{}
 	params.Add("department_id_type", "department_id")
 	params.Add("user_id_type", "open_id")
-	req, _ := http.NewRequest(http.MethodGet, a.server+"/open-apis/contact/v3/users/"+openId+"?"+params.Encode(), nil)
-	a.token.SetLarkAccessToken(req.Header)
-	if response, err := http.DefaultClient.Do(req); err == nil {
-		if resp, err := io.ReadAll(response.Body); err == nil {
-			js, _ := json2.NewJson(resp)
-			if js.Get("code").MustInt() == 0 {
-				res = js.Get("data").Get("user").MustMap()
-			}
-		}
+	js, err := a.doHttp(ctx, http.MethodGet, "/open-apis/contact/v3/users/"+openId, params, nil)
+	if err != nil {
+		return nil, err
 	}
-	return
+	return js.Get("data").Get("user").MustMap(), nil
 }
 
 // AppAccessTokenInternal POST https://open.feishu.cn/open-apis/auth/v3/app_access_token/internal
-func (a *app) AppAccessTokenInternal() string {
-	appAccessToken := ""
-	appAccessToken, _ = a.token.Cache.Fetch("app_access_token" + a.token.Id)
-	if appAccessToken == "" {
-		payload, _ := json.Marshal(map[string]string{
-			"app_id":     a.config.AppID,
-			"app_secret": a.config.AppSecret,
-		})
-		req, _ := http.NewRequest(http.MethodPost, a.server+"/open-apis/auth/v3/app_access_token/internal", bytes.NewReader(payload))
-		if response, err := http.DefaultClient.Do(req); err == nil {
-			if resp, err := io.ReadAll(response.Body); err == nil {
-				js, _ := json2.NewJson(resp)
-				if js.Get("code").MustInt() == 0 {
-					appAccessToken = js.Get("app_access_token").MustString()
-					d := time.Duration(js.Get("expire").MustInt()) * time.Second
-					_ = a.token.Cache.Save("app_access_token:"+a.token.Id, appAccessToken, d)
-				}
-			}
-		}
+func (a *app) AppAccessTokenInternal() (string, error) {
+	return a.AppAccessTokenInternalContext(context.Background())
+}
+
+// AppAccessTokenInternalContext 同 AppAccessTokenInternal，支持 context.Context
+func (a *app) AppAccessTokenInternalContext(ctx context.Context) (string, error) {
+	appAccessToken, err := a.appToken.GetAccessTokenContext(ctx)
+	if err != nil {
+		return "", err
 	}
-	return "Bearer " + appAccessToken
+	return "Bearer " + appAccessToken, nil
 }
 
 // TicketGet POST https://open.feishu.cn/open-apis/jssdk/ticket/get
-func (a *app) TicketGet() (ticket string) {
-	ticket, _ = a.token.Cache.Fetch("ticket" + a.token.Id)
-	if ticket == "" {
-		req, _ := http.NewRequest(http.MethodPost, a.server+"/open-apis/jssdk/ticket/get", nil)
-		req.Header.Set("Authorization", a.AppAccessTokenInternal())
-		if response, err := http.DefaultClient.Do(req); err == nil {
-			if resp, err := io.ReadAll(response.Body); err == nil {
-				js, _ := json2.NewJson(resp)
-				if js.Get("code").MustInt() == 0 {
-					ticket = js.Get("data").Get("ticket").MustString()
-					d := time.Duration(js.Get("data").Get("expire_in").MustInt()) * time.Second
-					_ = a.token.Cache.Save("ticket:"+a.token.Id, ticket, d)
-				}
-			}
-		}
-	}
-	return
+func (a *app) TicketGet() (ticket string, err error) {
+	return a.TicketGetContext(context.Background())
+}
+
+// TicketGetContext 同 TicketGet，支持 context.Context
+func (a *app) TicketGetContext(ctx context.Context) (ticket string, err error) {
+	return a.ticket.GetAccessTokenContext(ctx)
+}
+
+func (a *app) AuthorizationCode(code string) (map[string]interface{}, error) {
+	return a.AuthorizationCodeContext(context.Background(), code)
 }
 
-func (a *app) AuthorizationCode(code string) (res map[string]interface{}) {
+// AuthorizationCodeContext 同 AuthorizationCode，支持 context.Context
+func (a *app) AuthorizationCodeContext(ctx context.Context, code string) (map[string]interface{}, error) {
 	payload, _ := json.Marshal(map[string]interface{}{
 		"grant_type": "authorization_code",
 		"code":       code,
 	})
-	req, _ := http.NewRequest(http.MethodPost, a.server+"/open-apis/authen/v1/access_token", bytes.NewReader(payload))
-	req.Header.Set("Authorization", a.AppAccessTokenInternal())
-	req.Header.Set("Content-Type", "application/json; charset=utf-8")
-	if response, err := http.DefaultClient.Do(req); err == nil {
-		if resp, err := io.ReadAll(response.Body); err == nil {
-			js, _ := json2.NewJson(resp)
-			if js.Get("code").MustInt() == 0 {
-				res = js.Get("data").MustMap()
-			}
-		}
+	js, err := a.doHttpWithAppToken(ctx, http.MethodPost, "/open-apis/authen/v1/access_token", nil, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
 	}
-	return
+	return js.Get("data").MustMap(), nil
 }
 
 type Message struct {
@@ -347,7 +467,12 @@ type MessageCard struct {
 }
 
 // MessageSend POST https://open.feishu.cn/open-apis/im/v1/messages
-func (a *app) MessageSend(msg Message) (err error) {
+func (a *app) MessageSend(msg Message) error {
+	return a.MessageSendContext(context.Background(), msg)
+}
+
+// MessageSendContext 同 MessageSend，支持 context.Context
+func (a *app) MessageSendContext(ctx context.Context, msg Message) error {
 	params := url.Values{}
 	params.Add("receive_id_type", "user_id")
 	reqMsg := MessageMsg{
@@ -388,15 +513,6 @@ func (a *app) MessageSend(msg Message) (err error) {
 	})
 	reqMsg.Content = string(content)
 	payload, _ := json.Marshal(reqMsg)
-	req, _ := http.NewRequest(http.MethodPost, a.server+"/open-apis/im/v1/messages?"+params.Encode(), bytes.NewReader(payload))
-	req.Header.Set("Authorization", a.AppAccessTokenInternal())
-	if response, err := http.DefaultClient.Do(req); err == nil {
-		if resp, err := io.ReadAll(response.Body); err == nil {
-			js, _ := json2.NewJson(resp)
-			if js.Get("code").MustInt() != 0 {
-				err = errors.New(js.Get("msg").MustString())
-			}
-		}
-	}
-	return
+	_, err := a.doHttpWithAppToken(ctx, http.MethodPost, "/open-apis/im/v1/messages", params, bytes.NewReader(payload))
+	return err
 }
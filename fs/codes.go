@@ -0,0 +1,23 @@
+package fs
+
+import "github.com/leapig/tpp/util"
+
+// errorKinds 飞书错误码分类
+// doc https://open.feishu.cn/document/server-docs/api-call-guide/generic-error-code
+var errorKinds = map[int]error{
+	99991661: util.ErrInvalidToken,
+	99991663: util.ErrInvalidToken,
+	99991664: util.ErrInvalidToken,
+	99991665: util.ErrInvalidToken,
+	99991668: util.ErrInvalidToken,
+	99991677: util.ErrInvalidToken,
+	99991400: util.ErrRateLimited,
+	99991672: util.ErrPermissionDenied,
+	99991679: util.ErrPermissionDenied,
+	40004:    util.ErrPermissionDenied,
+	41050:    util.ErrPermissionDenied,
+	40003:    util.ErrNotFound,
+	41012:    util.ErrNotFound,
+	99992351: util.ErrInvalidParam,
+	99992402: util.ErrInvalidParam,
+}
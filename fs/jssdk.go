@@ -0,0 +1,38 @@
+package fs
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/leapig/tpp/util"
+)
+
+// JsConfig h5sdk.config 签名参数，timestamp 为毫秒，可直接序列化后传给前端
+// doc https://open.feishu.cn/document/uYjL24iN/uQjMuQjMuQjM/authentication/h5sdk-config
+type JsConfig struct {
+	AppId     string `json:"appId"`
+	Timestamp int64  `json:"timestamp"`
+	NonceStr  string `json:"nonceStr"`
+	Signature string `json:"signature"`
+}
+
+// JsConfig 使用缓存的 jsapi_ticket 生成 url 页面的 h5sdk.config 签名参数
+func (a *app) JsConfig(url string) (*JsConfig, error) {
+	return a.JsConfigContext(context.Background(), url)
+}
+
+// JsConfigContext 同 JsConfig，支持 context.Context
+func (a *app) JsConfigContext(ctx context.Context, url string) (*JsConfig, error) {
+	ticket, err := a.ticket.GetAccessTokenContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	config := &JsConfig{
+		AppId:     a.config.AppID,
+		Timestamp: time.Now().UnixMilli(),
+		NonceStr:  util.GetRandString(16),
+	}
+	config.Signature = util.JsSdkSignature(ticket, config.NonceStr, strconv.FormatInt(config.Timestamp, 10), url)
+	return config, nil
+}
@@ -0,0 +1,184 @@
+package fs
+
+import "encoding/json"
+
+// 事件类型
+// doc https://open.feishu.cn/document/server-docs/event-subscription-guide/event-list
+const (
+	EventMessageReceive    = "im.message.receive_v1"
+	EventUserCreated       = "contact.user.created_v3"
+	EventDepartmentUpdated = "contact.department.updated_v3"
+	EventApprovalUpdated   = "approval.approval.updated_v4"
+	// EventApprovalInstance 审批实例状态变更，仅支持 1.0 版本事件
+	EventApprovalInstance = "approval_instance"
+	// EventApprovalTask 审批任务状态变更，仅支持 1.0 版本事件
+	EventApprovalTask = "approval_task"
+)
+
+// EventHeader 2.0 版本事件公共字段，1.0 版本事件的 uuid、type 分别填入 EventId、EventType
+type EventHeader struct {
+	EventId    string `json:"event_id"`
+	EventType  string `json:"event_type"`
+	CreateTime string `json:"create_time"`
+	Token      string `json:"token"`
+	AppId      string `json:"app_id"`
+	TenantKey  string `json:"tenant_key"`
+}
+
+// Event 未注册类型处理函数的事件，Raw 为解密后的明文JSON
+type Event struct {
+	Schema string          `json:"schema"`
+	Header EventHeader     `json:"header"`
+	Raw    json.RawMessage `json:"-"`
+}
+
+// UserId 用户ID
+type UserId struct {
+	UnionId string `json:"union_id"`
+	UserId  string `json:"user_id"`
+	OpenId  string `json:"open_id"`
+}
+
+// MessageReceiveEvent 接收消息事件，Content 为JSON格式的消息内容
+// doc https://open.feishu.cn/document/server-docs/im-v1/message/events/receive
+type MessageReceiveEvent struct {
+	Header EventHeader `json:"header"`
+	Event  struct {
+		Sender struct {
+			SenderId   UserId `json:"sender_id"`
+			SenderType string `json:"sender_type"`
+			TenantKey  string `json:"tenant_key"`
+		} `json:"sender"`
+		Message struct {
+			MessageId   string `json:"message_id"`
+			RootId      string `json:"root_id"`
+			ParentId    string `json:"parent_id"`
+			CreateTime  string `json:"create_time"`
+			ChatId      string `json:"chat_id"`
+			ChatType    string `json:"chat_type"`
+			MessageType string `json:"message_type"`
+			Content     string `json:"content"`
+			Mentions    []struct {
+				Key       string `json:"key"`
+				Id        UserId `json:"id"`
+				Name      string `json:"name"`
+				TenantKey string `json:"tenant_key"`
+			} `json:"mentions"`
+		} `json:"message"`
+	} `json:"event"`
+}
+
+// User 通讯录用户
+type User struct {
+	UnionId       string   `json:"union_id"`
+	UserId        string   `json:"user_id"`
+	OpenId        string   `json:"open_id"`
+	Name          string   `json:"name"`
+	EnName        string   `json:"en_name"`
+	Email         string   `json:"email"`
+	Mobile        string   `json:"mobile"`
+	Gender        int      `json:"gender"`
+	DepartmentIds []string `json:"department_ids"`
+	LeaderUserId  string   `json:"leader_user_id"`
+	JobTitle      string   `json:"job_title"`
+	EmployeeNo    string   `json:"employee_no"`
+	Status        struct {
+		IsFrozen    bool `json:"is_frozen"`
+		IsResigned  bool `json:"is_resigned"`
+		IsActivated bool `json:"is_activated"`
+	} `json:"status"`
+}
+
+// UserCreatedEvent 员工入职事件
+// doc https://open.feishu.cn/document/server-docs/contact-v3/user/events/created
+type UserCreatedEvent struct {
+	Header EventHeader `json:"header"`
+	Event  struct {
+		Object User `json:"object"`
+	} `json:"event"`
+}
+
+// Department 通讯录部门
+type Department struct {
+	Name               string `json:"name"`
+	ParentDepartmentId string `json:"parent_department_id"`
+	DepartmentId       string `json:"department_id"`
+	OpenDepartmentId   string `json:"open_department_id"`
+	LeaderUserId       string `json:"leader_user_id"`
+	ChatId             string `json:"chat_id"`
+	Order              string `json:"order"`
+	Status             struct {
+		IsDeleted bool `json:"is_deleted"`
+	} `json:"status"`
+}
+
+// DepartmentUpdatedEvent 部门信息变化事件，OldObject 仅包含变更前的字段
+// doc https://open.feishu.cn/document/server-docs/contact-v3/department/events/updated
+type DepartmentUpdatedEvent struct {
+	Header EventHeader `json:"header"`
+	Event  struct {
+		Object    Department `json:"object"`
+		OldObject Department `json:"old_object"`
+	} `json:"event"`
+}
+
+// ApprovalUpdatedEvent 审批定义更新事件
+// doc https://open.feishu.cn/document/server-docs/approval-v4/approval/events/updated
+type ApprovalUpdatedEvent struct {
+	Header EventHeader `json:"header"`
+	Event  struct {
+		Object struct {
+			ApprovalId       string `json:"approval_id"`
+			ApprovalCode     string `json:"approval_code"`
+			VersionId        string `json:"version_id"`
+			WidgetGroupType  int    `json:"widget_group_type"`
+			FormDefinitionId string `json:"form_definition_id"`
+			ProcessObj       string `json:"process_obj"`
+			Timestamp        string `json:"timestamp"`
+			Extra            string `json:"extra"`
+		} `json:"object"`
+	} `json:"event"`
+}
+
+// ApprovalInstanceEvent 审批实例状态变更事件（1.0 版本）
+// Status 为 PENDING、APPROVED、REJECTED、CANCELED、DELETED
+// doc https://open.feishu.cn/document/server-docs/approval-v4/event/common-event/approval-instance-event
+type ApprovalInstanceEvent struct {
+	UUID  string `json:"uuid"`
+	Token string `json:"token"`
+	Ts    string `json:"ts"`
+	Type  string `json:"type"`
+	Event struct {
+		AppId               string `json:"app_id"`
+		ApprovalCode        string `json:"approval_code"`
+		InstanceCode        string `json:"instance_code"`
+		InstanceOperateTime string `json:"instance_operate_time"`
+		OperateTime         string `json:"operate_time"`
+		Status              string `json:"status"`
+		TenantKey           string `json:"tenant_key"`
+		Type                string `json:"type"`
+		UUID                string `json:"uuid"`
+	} `json:"event"`
+}
+
+// ApprovalTaskEvent 审批任务状态变更事件（1.0 版本）
+// Status 为 PENDING、APPROVED、REJECTED、TRANSFERRED、DONE
+// doc https://open.feishu.cn/document/server-docs/approval-v4/event/common-event/approval-task-event
+type ApprovalTaskEvent struct {
+	UUID  string `json:"uuid"`
+	Token string `json:"token"`
+	Ts    string `json:"ts"`
+	Type  string `json:"type"`
+	Event struct {
+		AppId        string `json:"app_id"`
+		ApprovalCode string `json:"approval_code"`
+		InstanceCode string `json:"instance_code"`
+		TaskId       string `json:"task_id"`
+		UserId       string `json:"user_id"`
+		OpenId       string `json:"open_id"`
+		OperateTime  string `json:"operate_time"`
+		Status       string `json:"status"`
+		TenantKey    string `json:"tenant_key"`
+		Type         string `json:"type"`
+	} `json:"event"`
+}
@@ -0,0 +1,220 @@
+package fs
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/faabiosr/cachego"
+	"github.com/leapig/tpp/logger"
+	"github.com/leapig/tpp/util"
+)
+
+// maxPushSize 推送消息体上限
+const maxPushSize = 1 << 20
+
+// handler 解析明文JSON并调用处理函数
+type handler func(ctx context.Context, raw []byte) error
+
+// Server 飞书事件订阅回调服务，支持 url_verification 校验、签名校验与加密推送
+// doc https://open.feishu.cn/document/server-docs/event-subscription-guide/event-subscription-configure-/request-url-configuration-case
+type Server struct {
+	token    string
+	crypt    *util.LarkCrypto
+	handlers map[string]handler
+	fallback func(ctx context.Context, event *Event) error
+	cache    cachego.Cache
+	locker   util.Locker
+	guard    *util.CallbackGuard
+}
+
+// Server 创建回调服务，使用 Config.VerificationToken 校验事件来源，配置 Config.EncryptKey 时校验签名并解密
+// 两者均未配置时无法校验事件来源，回调服务拒绝所有请求
+func (a *app) Server() *Server {
+	s := &Server{
+		token:    a.config.VerificationToken,
+		handlers: map[string]handler{},
+		cache:    a.config.Cache,
+		locker:   a.config.Locker,
+	}
+	if a.config.EncryptKey != "" {
+		s.crypt = util.NewLarkCrypto(a.config.VerificationToken, a.config.EncryptKey)
+	}
+	return s
+}
+
+func on[T any](s *Server, eventType string, h func(ctx context.Context, event *T) error) *Server {
+	s.handlers[eventType] = func(ctx context.Context, raw []byte) error {
+		event := new(T)
+		if err := json.Unmarshal(raw, event); err != nil {
+			return err
+		}
+		return h(ctx, event)
+	}
+	return s
+}
+
+// OnMessageReceive 接收消息事件
+func (s *Server) OnMessageReceive(h func(ctx context.Context, event *MessageReceiveEvent) error) *Server {
+	return on(s, EventMessageReceive, h)
+}
+
+// OnUserCreated 员工入职事件
+func (s *Server) OnUserCreated(h func(ctx context.Context, event *UserCreatedEvent) error) *Server {
+	return on(s, EventUserCreated, h)
+}
+
+// OnDepartmentUpdated 部门信息变化事件
+func (s *Server) OnDepartmentUpdated(h func(ctx context.Context, event *DepartmentUpdatedEvent) error) *Server {
+	return on(s, EventDepartmentUpdated, h)
+}
+
+// OnApprovalUpdated 审批定义更新事件
+func (s *Server) OnApprovalUpdated(h func(ctx context.Context, event *ApprovalUpdatedEvent) error) *Server {
+	return on(s, EventApprovalUpdated, h)
+}
+
+// OnApprovalInstance 审批实例状态变更事件
+func (s *Server) OnApprovalInstance(h func(ctx context.Context, event *ApprovalInstanceEvent) error) *Server {
+	return on(s, EventApprovalInstance, h)
+}
+
+// OnApprovalTask 审批任务状态变更事件
+func (s *Server) OnApprovalTask(h func(ctx context.Context, event *ApprovalTaskEvent) error) *Server {
+	return on(s, EventApprovalTask, h)
+}
+
+// Guard 启用重放保护与去重，拒绝时间戳超出 window 的事件，window 内重复的 event_id 直接返回成功
+// 使用 Config.Cache 记录已处理的事件，配置 Config.Locker 时跨实例加锁去重，window 为 0 时使用 util.DefaultCallbackWindow
+func (s *Server) Guard(window time.Duration) *Server {
+	s.guard = util.NewCallbackGuard(s.cache, s.locker, window)
+	return s
+}
+
+// OnDefault 未注册类型的事件
+func (s *Server) OnDefault(h func(ctx context.Context, event *Event) error) *Server {
+	s.fallback = h
+	return s
+}
+
+// envelope 事件外层结构，兼容 url_verification、1.0 与 2.0 版本事件
+type envelope struct {
+	Schema    string      `json:"schema"`
+	Header    EventHeader `json:"header"`
+	Type      string      `json:"type"`
+	Token     string      `json:"token"`
+	Challenge string      `json:"challenge"`
+	UUID      string      `json:"uuid"`
+	Event     struct {
+		Type string `json:"type"`
+	} `json:"event"`
+}
+
+// ServeHTTP 解密事件、校验签名并分发，url_verification 请求原样返回 challenge
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.token == "" && s.crypt == nil {
+		http.Error(w, "verification token and encrypt key not configured", http.StatusInternalServerError)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxPushSize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	plaintext := body
+	if s.crypt != nil {
+		// 配置 Encrypt Key 时仅接受加密事件
+		decrypted, err := s.crypt.Decrypt(body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		plaintext = []byte(decrypted)
+	}
+	var env envelope
+	if err = json.Unmarshal(plaintext, &env); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	token := env.Token
+	if env.Schema != "" {
+		token = env.Header.Token
+	}
+	if s.token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+		http.Error(w, "invalid verification token", http.StatusForbidden)
+		return
+	}
+	if env.Type == "url_verification" {
+		w.Header().Set("Content-Type", util.ContentType)
+		_ = json.NewEncoder(w).Encode(map[string]string{"challenge": env.Challenge})
+		return
+	}
+	// url_verification 请求不校验时间戳与签名
+	if s.guard != nil {
+		if err = s.guard.Fresh(r.Header.Get("X-Lark-Request-Timestamp")); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+	}
+	// 配置 Encrypt Key 时事件必须携带有效签名
+	if s.crypt != nil {
+		if err = s.crypt.VerifySignature(r.Header.Get("X-Lark-Signature"), r.Header.Get("X-Lark-Request-Timestamp"), r.Header.Get("X-Lark-Request-Nonce"), body); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+	}
+	if err = s.dispatch(r.Context(), env, plaintext); err != nil {
+		logger.Errorf("fs event: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", util.ContentType)
+	_, _ = io.WriteString(w, "{}")
+}
+
+// dispatch 按事件类型调用处理函数，1.0 版本事件以 event.type 区分，重复事件直接返回
+func (s *Server) dispatch(ctx context.Context, env envelope, plaintext []byte) error {
+	header := env.Header
+	if env.Schema == "" {
+		header = EventHeader{EventId: env.UUID, EventType: env.Event.Type, Token: env.Token}
+	}
+	if header.EventType == "" {
+		return fmt.Errorf("failed to parse event: missing event type")
+	}
+	event := &Event{Schema: env.Schema, Header: header, Raw: plaintext}
+	if s.guard == nil {
+		return s.handle(ctx, event)
+	}
+	id := util.CallbackId(plaintext, header.EventId)
+	if err := s.guard.Accept(ctx, util.PlatformFS, id); err != nil {
+		if errors.Is(err, util.ErrDuplicateCallback) {
+			return nil
+		}
+		return err
+	}
+	err := s.handle(ctx, event)
+	if err != nil {
+		s.guard.Release(util.PlatformFS, id)
+	}
+	return err
+}
+
+// handle 调用事件类型对应的处理函数
+func (s *Server) handle(ctx context.Context, event *Event) error {
+	if h, ok := s.handlers[event.Header.EventType]; ok {
+		return h(ctx, event.Raw)
+	}
+	if s.fallback != nil {
+		return s.fallback(ctx, event)
+	}
+	return nil
+}
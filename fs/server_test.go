@@ -0,0 +1,157 @@
+package fs
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/faabiosr/cachego/sync"
+)
+
+const (
+	testToken      = "vtoken"
+	testEncryptKey = "test key"
+)
+
+// encrypt 按飞书规则加密事件：AES-256-CBC，密钥为 Encrypt Key 的 sha256，密文前 16 字节为 IV
+func encrypt(t *testing.T, plaintext string) []byte {
+	t.Helper()
+	key := sha256.Sum256([]byte(testEncryptKey))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	pad := aes.BlockSize - len(plaintext)%aes.BlockSize
+	src := append([]byte(plaintext), bytes.Repeat([]byte{byte(pad)}, pad)...)
+	buf := make([]byte, aes.BlockSize+len(src))
+	copy(buf, "0123456789abcdef")
+	cipher.NewCBCEncrypter(block, buf[:aes.BlockSize]).CryptBlocks(buf[aes.BlockSize:], src)
+	body, _ := json.Marshal(map[string]string{"encrypt": base64.StdEncoding.EncodeToString(buf)})
+	return body
+}
+
+func sign(timestamp, nonce string, body []byte) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(timestamp+nonce+testEncryptKey+string(body))))
+}
+
+func TestServerSignature(t *testing.T) {
+	event := `{"schema":"2.0","header":{"event_id":"e1","event_type":"im.message.receive_v1","token":"` + testToken + `"},"event":{}}`
+	challenge := `{"type":"url_verification","token":"` + testToken + `","challenge":"c1"}`
+	tests := []struct {
+		name      string
+		plaintext string
+		signature func(body []byte) string
+		status    int
+		handled   bool
+	}{
+		{"valid signature", event, func(body []byte) string { return sign("1", "n", body) }, http.StatusOK, true},
+		{"missing signature", event, func([]byte) string { return "" }, http.StatusForbidden, false},
+		{"wrong signature", event, func(body []byte) string { return sign("2", "n", body) }, http.StatusForbidden, false},
+		{"unsigned url_verification", challenge, func([]byte) string { return "" }, http.StatusOK, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handled := false
+			s := NewApp(Config{AppID: "cli_test", VerificationToken: testToken, EncryptKey: testEncryptKey}).Server().
+				OnMessageReceive(func(ctx context.Context, event *MessageReceiveEvent) error {
+					handled = true
+					return nil
+				})
+			body := encrypt(t, tt.plaintext)
+			r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+			r.Header.Set("X-Lark-Request-Timestamp", "1")
+			r.Header.Set("X-Lark-Request-Nonce", "n")
+			if signature := tt.signature(body); signature != "" {
+				r.Header.Set("X-Lark-Signature", signature)
+			}
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, r)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.status, w.Body)
+			}
+			if handled != tt.handled {
+				t.Errorf("handled = %v, want %v", handled, tt.handled)
+			}
+			if tt.plaintext == challenge && !strings.Contains(w.Body.String(), `"c1"`) {
+				t.Errorf("challenge body = %s", w.Body)
+			}
+		})
+	}
+}
+
+func TestServerRejectsPlaintextWithEncryptKey(t *testing.T) {
+	s := NewApp(Config{AppID: "cli_test", VerificationToken: testToken, EncryptKey: testEncryptKey}).Server()
+	body := `{"type":"url_verification","token":"` + testToken + `","challenge":"c1"}`
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+}
+
+func TestServerVerificationToken(t *testing.T) {
+	body := func(token string) string {
+		return `{"type":"url_verification","token":"` + token + `","challenge":"c1"}`
+	}
+	tests := []struct {
+		name   string
+		config Config
+		body   string
+		status int
+	}{
+		{"valid token", Config{AppID: "cli_test", VerificationToken: testToken}, body(testToken), http.StatusOK},
+		{"wrong token", Config{AppID: "cli_test", VerificationToken: testToken}, body("other"), http.StatusForbidden},
+		{"empty token", Config{AppID: "cli_test", VerificationToken: testToken}, body(""), http.StatusForbidden},
+		{"not configured", Config{AppID: "cli_test"}, body(""), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			NewApp(tt.config).Server().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body)))
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d, body %s", w.Code, tt.status, w.Body)
+			}
+		})
+	}
+}
+
+func TestServerGuardTimestamp(t *testing.T) {
+	event := `{"schema":"2.0","header":{"event_id":"e1","event_type":"im.message.receive_v1","token":"` + testToken + `"},"event":{}}`
+	challenge := `{"type":"url_verification","token":"` + testToken + `","challenge":"c1"}`
+	tests := []struct {
+		name      string
+		body      string
+		timestamp string
+		status    int
+	}{
+		{"missing timestamp", event, "", http.StatusForbidden},
+		{"stale timestamp", event, "1", http.StatusForbidden},
+		{"fresh timestamp", event, strconv.FormatInt(time.Now().Unix(), 10), http.StatusOK},
+		{"url_verification without timestamp", challenge, "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewApp(Config{AppID: "cli_test", VerificationToken: testToken, Cache: sync.New()}).Server().Guard(0)
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			if tt.timestamp != "" {
+				r.Header.Set("X-Lark-Request-Timestamp", tt.timestamp)
+			}
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, r)
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d, body %s", w.Code, tt.status, w.Body)
+			}
+		})
+	}
+}
@@ -4,17 +4,18 @@ go 1.21.0
 
 require (
 	github.com/bitly/go-simplejson v0.5.1
+	github.com/boombuler/barcode v1.0.2
 	github.com/faabiosr/cachego v0.22.2
+	github.com/go-pay/crypto v0.0.1
 	github.com/go-pay/gopay v1.5.104
 	github.com/natefinch/lumberjack v2.0.0+incompatible
 	github.com/nfnt/resize v0.0.0-20180221191011-83c6a9932646
 	go.uber.org/zap v1.27.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	github.com/BurntSushi/toml v1.4.0 // indirect
-	github.com/boombuler/barcode v1.0.2 // indirect
-	github.com/go-pay/crypto v0.0.1 // indirect
 	github.com/go-pay/xlog v0.0.3 // indirect
 	github.com/go-pay/xtime v0.0.2 // indirect
 	go.uber.org/multierr v1.10.0 // indirect
@@ -31,6 +31,12 @@ type Config struct {
 	LumberjackConfig *lumberjack.Logger `yaml:"lumberjack-config"`
 	ZapConfig        *zap.Config        `yaml:"zap-config"`
 	CallerSkip       int
+	// LogRequests 是否记录平台接口请求地址与状态码（脱敏后，debug 级别）
+	LogRequests bool `yaml:"log-requests"`
+	// LogBody 是否记录平台接口请求与响应内容（脱敏后，debug 级别）
+	LogBody bool `yaml:"log-body"`
+	// RedactFields 脱敏字段，为空时使用 DefaultRedactFields
+	RedactFields []string `yaml:"redact-fields"`
 }
 
 func InitLogger(conf *Config) {
@@ -65,6 +71,11 @@ func InitLogger(conf *Config) {
 
 	if conf != nil {
 		config.CallerSkip = conf.CallerSkip
+		SetLogRequests(conf.LogRequests)
+		SetLogBody(conf.LogBody)
+		if len(conf.RedactFields) > 0 {
+			SetRedactFields(conf.RedactFields)
+		}
 	}
 
 	if config.CallerSkip == 0 {
@@ -0,0 +1,96 @@
+package logger
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// DefaultRedactFields 默认脱敏字段：令牌、密钥、会话密钥、刷新令牌、手机号
+var DefaultRedactFields = []string{
+	"access_token", "accessToken", "component_access_token", "authorizer_access_token",
+	"tenant_access_token", "app_access_token", "user_access_token",
+	"refresh_token", "refreshToken", "authorizer_refresh_token",
+	"secret", "appsecret", "app_secret", "appSecret", "corpsecret", "component_appsecret", "client_secret",
+	"session_key", "ticket", "jsapiTicket", "component_verify_ticket",
+	"suite_ticket", "SuiteTicket", "suite_secret", "suite_access_token", "ComponentVerifyTicket",
+	"phoneNumber", "purePhoneNumber", "phone", "mobile", "telephone",
+}
+
+const redacted = "***"
+
+var (
+	redactMu      sync.RWMutex
+	logBody       bool
+	logRequests   bool
+	jsonPattern   *regexp.Regexp
+	queryPattern  *regexp.Regexp
+	xmlPattern    *regexp.Regexp
+	redactEnabled bool
+)
+
+func init() {
+	SetRedactFields(DefaultRedactFields)
+}
+
+// SetLogBody 设置是否记录请求与响应内容（脱敏后，debug 级别），默认不记录
+func SetLogBody(enabled bool) {
+	redactMu.Lock()
+	defer redactMu.Unlock()
+	logBody = enabled
+}
+
+// LogBody 是否记录请求与响应内容
+func LogBody() bool {
+	redactMu.RLock()
+	defer redactMu.RUnlock()
+	return logBody
+}
+
+// SetLogRequests 设置是否记录请求地址与状态码（脱敏后，debug 级别），默认不记录
+func SetLogRequests(enabled bool) {
+	redactMu.Lock()
+	defer redactMu.Unlock()
+	logRequests = enabled
+}
+
+// LogRequests 是否记录请求地址与状态码，开启 LogBody 时同样记录
+func LogRequests() bool {
+	redactMu.RLock()
+	defer redactMu.RUnlock()
+	return logRequests || logBody
+}
+
+// SetRedactFields 设置脱敏字段（字段名不区分大小写），为空时不脱敏
+func SetRedactFields(fields []string) {
+	redactMu.Lock()
+	defer redactMu.Unlock()
+	redactEnabled = len(fields) > 0
+	if !redactEnabled {
+		return
+	}
+	quoted := make([]string, len(fields))
+	for i, field := range fields {
+		quoted[i] = regexp.QuoteMeta(field)
+	}
+	names := strings.Join(quoted, "|")
+	// JSON 字段："access_token":"xxx"、"mobile":13800000000
+	jsonPattern = regexp.MustCompile(`(?i)("(?:` + names + `)"\s*:\s*)("(?:[^"\\]|\\.)*"|[0-9]+)`)
+	// 查询参数与表单：access_token=xxx
+	queryPattern = regexp.MustCompile(`(?i)((?:^|[?&])(?:` + names + `)=)[^&\s]*`)
+	// XML 节点：<Ticket><![CDATA[xxx]]></Ticket>
+	xmlPattern = regexp.MustCompile(`(?i)(<(` + names + `)>)(?:<!\[CDATA\[.*?\]\]>|[^<]*)(</)`)
+}
+
+// Redact 对 URL、JSON、表单及 XML 中的敏感字段脱敏
+func Redact(s string) string {
+	redactMu.RLock()
+	defer redactMu.RUnlock()
+	if !redactEnabled {
+		return s
+	}
+	s = jsonPattern.ReplaceAllString(s, `${1}"`+redacted+`"`)
+	s = queryPattern.ReplaceAllString(s, "${1}"+redacted)
+	s = xmlPattern.ReplaceAllString(s, "${1}"+redacted+"${3}")
+	return s
+}
@@ -0,0 +1,56 @@
+package logger
+
+import "testing"
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"json access_token", `{"access_token":"AT","expires_in":7200}`, `{"access_token":"***","expires_in":7200}`},
+		{"json secret with spaces", `{"secret" : "S", "appid":"wx1"}`, `{"secret" : "***", "appid":"wx1"}`},
+		{"json suite_ticket", `{"suite_id":"s1","suite_ticket":"T"}`, `{"suite_id":"s1","suite_ticket":"***"}`},
+		{"json escaped quote", `{"secret":"a\"b","x":1}`, `{"secret":"***","x":1}`},
+		{"json number", `{"mobile":13800000000}`, `{"mobile":"***"}`},
+		{"json case insensitive", `{"Access_Token":"AT"}`, `{"Access_Token":"***"}`},
+		{"query access_token", "https://api.weixin.qq.com/cgi-bin/user/info?access_token=AT&openid=o1", "https://api.weixin.qq.com/cgi-bin/user/info?access_token=***&openid=o1"},
+		{"query secret", "/cgi-bin/token?grant_type=client_credential&appid=wx1&secret=S", "/cgi-bin/token?grant_type=client_credential&appid=wx1&secret=***"},
+		{"form suite_ticket", "suite_id=s1&suite_ticket=T", "suite_id=s1&suite_ticket=***"},
+		{"query prefix", "?my_access_token=AT", "?my_access_token=AT"},
+		{"xml suite ticket", "<xml><SuiteId><![CDATA[s1]]></SuiteId><SuiteTicket><![CDATA[T]]></SuiteTicket></xml>", "<xml><SuiteId><![CDATA[s1]]></SuiteId><SuiteTicket>***</SuiteTicket></xml>"},
+		{"xml plain secret", "<xml><secret>S</secret></xml>", "<xml><secret>***</secret></xml>"},
+		{"xml access_token", "<xml><access_token><![CDATA[AT]]></access_token></xml>", "<xml><access_token>***</access_token></xml>"},
+		{"no sensitive field", `{"openid":"o1"}`, `{"openid":"o1"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Redact(tt.in); got != tt.want {
+				t.Errorf("Redact(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetRedactFields(t *testing.T) {
+	defer SetRedactFields(DefaultRedactFields)
+	SetRedactFields([]string{"unionid"})
+	if got, want := Redact(`{"unionid":"u1","secret":"S"}`), `{"unionid":"***","secret":"S"}`; got != want {
+		t.Errorf("Redact() = %q, want %q", got, want)
+	}
+	SetRedactFields(nil)
+	if got, want := Redact("access_token=AT"), "access_token=AT"; got != want {
+		t.Errorf("Redact() with no fields = %q, want %q", got, want)
+	}
+}
+
+func TestLogSwitchesDefaultOff(t *testing.T) {
+	if LogRequests() || LogBody() {
+		t.Fatalf("LogRequests() = %v, LogBody() = %v, want both off by default", LogRequests(), LogBody())
+	}
+	defer SetLogBody(false)
+	SetLogBody(true)
+	if !LogRequests() {
+		t.Error("LogRequests() = false with LogBody enabled")
+	}
+}
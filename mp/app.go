@@ -2,12 +2,11 @@ package mp
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
-	"fmt"
 	json2 "github.com/bitly/go-simplejson"
 	"github.com/faabiosr/cachego"
 	"github.com/faabiosr/cachego/file"
-	"github.com/leapig/tpp/logger"
 	"github.com/leapig/tpp/util"
 	"io"
 	"net/http"
@@ -19,67 +18,147 @@ import (
 type App interface {
 	Key() string
 	Id() string
-	Token() string
-	JsCode2Session(jsCode string) map[string]interface{}
-	GetWxACodeUnLimit(page, scene string) []byte
-	PostWxaBusinessGetUserPhoneNumber(code string) (res map[string]interface{})
+	Close()
+	Token() (string, error)
+	TokenContext(ctx context.Context) (string, error)
+	JsCode2Session(jsCode string) (map[string]interface{}, error)
+	JsCode2SessionContext(ctx context.Context, jsCode string) (map[string]interface{}, error)
+	GetWxACodeUnLimit(page, scene string) ([]byte, error)
+	GetWxACodeUnLimitContext(ctx context.Context, page, scene string) ([]byte, error)
+	PostWxaBusinessGetUserPhoneNumber(code string) (map[string]interface{}, error)
+	PostWxaBusinessGetUserPhoneNumberContext(ctx context.Context, code string) (map[string]interface{}, error)
 }
 
-type GetComponentAccessToken func() string
+// GetComponentAccessToken 获取第三方平台 component_access_token
+type GetComponentAccessToken func(ctx context.Context) (string, error)
 
 type Config struct {
-	Key            string        `json:"key"`
-	AppId          string        `json:"appid"`
-	Secret         string        `json:"secret"`
-	Version        string        `json:"version"`
-	ComponentAppid string        `json:"component_appid"`
-	ComponentToken string        `json:"component_token"`
-	Cache          cachego.Cache `json:"cache"`
+	Key            string        `json:"key" yaml:"key"`
+	AppId          string        `json:"appid" yaml:"appid"`
+	Secret         string        `json:"secret" yaml:"secret"`
+	Version        string        `json:"version" yaml:"version"`
+	ComponentAppid string        `json:"component_appid" yaml:"component_appid"`
+	ComponentToken string        `json:"component_token" yaml:"component_token"`
+	Cache          cachego.Cache `json:"cache" yaml:"-"`
+	// BaseURL 服务地址，为空时使用平台默认地址
+	BaseURL string `json:"base_url" yaml:"base_url"`
+	// Proxy 出口代理地址
+	Proxy string `json:"proxy" yaml:"proxy"`
+	// HTTPClient 自定义请求客户端，优先于 Transport 与 Proxy
+	HTTPClient *http.Client `json:"-" yaml:"-"`
+	// Transport 自定义 Transport
+	Transport http.RoundTripper `json:"-" yaml:"-"`
+	// Locker 多实例部署时的令牌刷新锁
+	Locker util.Locker `json:"-" yaml:"-"`
+	// Refresher 后台预刷新，为空时仅在令牌过期后刷新
+	Refresher *util.Refresher `json:"-" yaml:"-"`
+	// AccessToken 第三方平台托管的授权账号调用令牌，设置后不再使用 Secret 刷新
+	AccessToken *util.AccessToken `json:"-" yaml:"-"`
+	// GetComponentAccessToken 第三方平台代调用时获取 component_access_token，优先于 ComponentToken
+	GetComponentAccessToken GetComponentAccessToken `json:"-" yaml:"-"`
+}
+
+// component 是否由第三方平台代调用
+func (c Config) component() bool {
+	return c.AccessToken != nil || strings.HasPrefix(c.Secret, "refreshtoken@@@")
+}
+
+// componentToken 获取 component_access_token
+func (c Config) componentToken(ctx context.Context) (string, error) {
+	if c.GetComponentAccessToken != nil {
+		return c.GetComponentAccessToken(ctx)
+	}
+	return c.ComponentToken, nil
 }
 
 type app struct {
 	config Config
 	token  util.AccessToken
+	client *util.Client
 	server string
 }
 
 func NewApp(config Config) App {
-	server := "https://api.weixin.qq.com"
+	server := util.BaseURL(config.BaseURL, "https://api.weixin.qq.com")
 	if config.Cache == nil {
 		config.Cache = file.New(os.TempDir())
 	}
-	return &app{
+	client := &util.Client{Platform: util.PlatformMP, Kinds: errorKinds, HttpClient: util.NewHTTPClient(config.HTTPClient, config.Transport, config.Proxy)}
+	a := &app{
 		server: server,
 		config: config,
+		client: client,
 		token: util.AccessToken{
-			Id:    config.AppId + config.Secret,
-			Cache: config.Cache,
-			GetRefreshRequestFunc: func() (resp []byte) {
+			Id:     util.CacheKey(util.PlatformMP, config.AppId, config.Secret),
+			Cache:  config.Cache,
+			Locker: config.Locker,
+			GetRefreshRequestFunc: func(ctx context.Context) (resp []byte, err error) {
+				var req *http.Request
 				if strings.HasPrefix(config.Secret, "refreshtoken@@@") {
+					componentToken, tokenErr := config.componentToken(ctx)
+					if tokenErr != nil {
+						return nil, tokenErr
+					}
 					params := url.Values{}
-					params.Add("component_access_token", config.ComponentToken)
+					params.Add("component_access_token", componentToken)
 					payload, _ := json.Marshal(map[string]string{
 						"component_appid":          config.ComponentAppid,
 						"authorizer_appid":         config.AppId,
 						"authorizer_refresh_token": config.Secret,
 					})
-					req, _ := http.NewRequest(http.MethodPost, server+"/cgi-bin/component/api_authorizer_token?"+params.Encode(), bytes.NewReader(payload))
-					response, _ := http.DefaultClient.Do(req)
-					resp, _ = io.ReadAll(response.Body)
+					req, err = http.NewRequestWithContext(ctx, http.MethodPost, server+"/cgi-bin/component/api_authorizer_token?"+params.Encode(), bytes.NewReader(payload))
 				} else {
 					params := url.Values{}
 					params.Add("appid", config.AppId)
 					params.Add("secret", config.Secret)
 					params.Add("grant_type", "client_credential")
-					req, _ := http.NewRequest(http.MethodGet, server+"/cgi-bin/token?"+params.Encode(), nil)
-					response, _ := http.DefaultClient.Do(req)
-					resp, _ = io.ReadAll(response.Body)
+					req, err = http.NewRequestWithContext(ctx, http.MethodGet, server+"/cgi-bin/token?"+params.Encode(), nil)
+				}
+				if err != nil {
+					return nil, util.NewError(util.PlatformMP, err)
 				}
-				fmt.Printf("\n\n%s\n\n", string(resp))
+				resp, err = client.Fetch(req)
 				return
 			},
 		},
 	}
+	if config.AccessToken != nil {
+		a.token = *config.AccessToken
+	}
+	if config.Refresher != nil {
+		config.Refresher.Add(a.token)
+	}
+	return a
+}
+
+// doHttp 执行请求并解析JSON响应
+func (a *app) doHttp(ctx context.Context, method string, path string, params url.Values, body io.Reader) (*json2.Json, error) {
+	req, err := a.newRequest(ctx, method, path, params, body)
+	if err != nil {
+		return nil, err
+	}
+	return a.client.Do(req)
+}
+
+// doHttpWithToken 附加 access_token 后执行请求，令牌失效时刷新后重试一次
+func (a *app) doHttpWithToken(ctx context.Context, method string, path string, params url.Values, body io.Reader) (js *json2.Json, err error) {
+	payload, err := util.ReadBody(body)
+	if err != nil {
+		return nil, util.NewError(util.PlatformMP, err)
+	}
+	err = a.token.WithAccessToken(ctx, func(token string) error {
+		js, err = a.doHttp(ctx, method, path, util.WithToken(params, "access_token", token), util.BodyReader(payload))
+		return err
+	})
+	return
+}
+
+func (a *app) newRequest(ctx context.Context, method string, path string, params url.Values, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, a.server+path+"?"+params.Encode(), body)
+	if err != nil {
+		return nil, util.NewError(util.PlatformMP, err)
+	}
+	return req, nil
 }
 
 // Key 获取当前实例ID
@@ -92,101 +171,99 @@ func (a *app) Id() string {
 	return a.config.AppId
 }
 
+// Close 停止实例令牌的后台预刷新，实例不再使用时调用
+func (a *app) Close() {
+	if a.config.Refresher != nil {
+		a.config.Refresher.Remove(a.token)
+	}
+}
+
 // Token 校验是否配置是否正常（返回access_token）
-func (a *app) Token() string {
-	return a.token.GetAccessToken()
+func (a *app) Token() (string, error) {
+	return a.TokenContext(context.Background())
+}
+
+// TokenContext 同 Token，支持 context.Context
+func (a *app) TokenContext(ctx context.Context) (string, error) {
+	return a.token.GetAccessTokenContext(ctx)
 }
 
 // JsCode2Session
 // GET https://api.weixin.qq.com/sns/jscode2session?appid=APPID&secret=SECRET&js_code=JSCODE&grant_type=authorization_code
 // GET https://api.weixin.qq.com/sns/component/jscode2session?appid=APPID&js_code=JSCODE&grant_type=authorization_code&component_appid=COMPONENT_APPID&component_access_token=COMPONENT_ACCESS_TOKEN
-func (a *app) JsCode2Session(jsCode string) (res map[string]interface{}) {
-	if strings.HasPrefix(a.config.Secret, "refreshtoken@@@") {
-		params := url.Values{}
-		params.Add("component_access_token", a.config.ComponentToken)
-		params.Add("appid", a.config.AppId)
-		params.Add("grant_type", "client_credential")
-		params.Add("component_appid", a.config.ComponentAppid)
-		params.Add("js_code", jsCode)
-		if response, err := http.Get(a.server + "/sns/component/jscode2session?" + params.Encode()); err == nil {
-			if resp, err := io.ReadAll(response.Body); err == nil {
-				js, _ := json2.NewJson(resp)
-				logger.Debugf("JsCode2Session:%+v", js)
-				if js.Get("openid").MustString() != "" {
-					res = js.MustMap()
-				}
-			} else {
-				logger.Errorf("JsCode2Session:%+v", err)
-			}
-		} else {
-			logger.Errorf("JsCode2Session:%+v", err)
+func (a *app) JsCode2Session(jsCode string) (map[string]interface{}, error) {
+	return a.JsCode2SessionContext(context.Background(), jsCode)
+}
+
+// JsCode2SessionContext 同 JsCode2Session，支持 context.Context
+func (a *app) JsCode2SessionContext(ctx context.Context, jsCode string) (map[string]interface{}, error) {
+	params := url.Values{}
+	params.Add("appid", a.config.AppId)
+	params.Add("js_code", jsCode)
+	path := "/sns/jscode2session"
+	if a.config.component() {
+		componentToken, err := a.config.componentToken(ctx)
+		if err != nil {
+			return nil, err
 		}
+		params.Add("component_access_token", componentToken)
+		params.Add("grant_type", "authorization_code")
+		params.Add("component_appid", a.config.ComponentAppid)
+		path = "/sns/component/jscode2session"
 	} else {
-		params := url.Values{}
-		params.Add("appid", a.config.AppId)
 		params.Add("secret", a.config.Secret)
-		params.Add("js_code", jsCode)
 		params.Add("grant_type", "authorization_code")
-		if response, err := http.Get(a.server + "/sns/jscode2session?" + params.Encode()); err == nil {
-			if resp, err := io.ReadAll(response.Body); err == nil {
-				js, _ := json2.NewJson(resp)
-				logger.Debugf("JsCode2Session:%+v", js)
-				if js.Get("openid").MustString() != "" {
-					res = js.MustMap()
-				}
-			} else {
-				logger.Errorf("JsCode2Session:%+v", err)
-			}
-		} else {
-			logger.Errorf("JsCode2Session:%+v", err)
-		}
 	}
-	return
+	js, err := a.doHttp(ctx, http.MethodGet, path, params, nil)
+	if err != nil {
+		return nil, err
+	}
+	if js.Get("openid").MustString() == "" {
+		return nil, util.Errorf(util.PlatformMP, "JsCode2Session: empty openid")
+	}
+	return js.MustMap(), nil
 }
 
 // GetWxACodeUnLimit POST https://api.weixin.qq.com/wxa/getwxacodeunlimit
-func (a *app) GetWxACodeUnLimit(page, scene string) []byte {
-	params := url.Values{}
-	params = a.token.ApplyAccessToken(params)
+func (a *app) GetWxACodeUnLimit(page, scene string) ([]byte, error) {
+	return a.GetWxACodeUnLimitContext(context.Background(), page, scene)
+}
+
+// GetWxACodeUnLimitContext 同 GetWxACodeUnLimit，支持 context.Context
+func (a *app) GetWxACodeUnLimitContext(ctx context.Context, page, scene string) ([]byte, error) {
 	payload, _ := json.Marshal(map[string]interface{}{
 		"page":        page,
 		"scene":       scene,
 		"check_path":  false,
 		"env_version": a.config.Version,
 	})
-	req, _ := http.NewRequest(http.MethodPost, a.server+"/wxa/getwxacodeunlimit?"+params.Encode(), bytes.NewReader(payload))
-	if response, err := http.DefaultClient.Do(req); err == nil {
-		if resp, err := io.ReadAll(response.Body); err == nil {
-			return resp
-		} else {
-			logger.Errorf("GetWxACodeUnLimit:%+v", err)
+	var image []byte
+	err := a.token.WithAccessToken(ctx, func(token string) error {
+		params := url.Values{}
+		params.Add("access_token", token)
+		req, err := a.newRequest(ctx, http.MethodPost, "/wxa/getwxacodeunlimit", params, bytes.NewReader(payload))
+		if err != nil {
+			return err
 		}
-	} else {
-		logger.Errorf("GetWxACodeUnLimit:%+v", err)
-	}
-	return nil
+		image, err = a.client.Fetch(req)
+		return err
+	})
+	return image, err
 }
 
 // PostWxaBusinessGetUserPhoneNumber POST https://api.weixin.qq.com/wxa/business/getuserphonenumber
-func (a *app) PostWxaBusinessGetUserPhoneNumber(code string) (res map[string]interface{}) {
-	params := url.Values{}
-	params = a.token.ApplyAccessToken(params)
+func (a *app) PostWxaBusinessGetUserPhoneNumber(code string) (map[string]interface{}, error) {
+	return a.PostWxaBusinessGetUserPhoneNumberContext(context.Background(), code)
+}
+
+// PostWxaBusinessGetUserPhoneNumberContext 同 PostWxaBusinessGetUserPhoneNumber，支持 context.Context
+func (a *app) PostWxaBusinessGetUserPhoneNumberContext(ctx context.Context, code string) (map[string]interface{}, error) {
 	payload, _ := json.Marshal(map[string]interface{}{
 		"code": code,
 	})
-	req, _ := http.NewRequest(http.MethodPost, a.server+"/wxa/business/getuserphonenumber?"+params.Encode(), bytes.NewReader(payload))
-	if response, err := http.DefaultClient.Do(req); err == nil {
-		if resp, err := io.ReadAll(response.Body); err == nil {
-			js, _ := json2.NewJson(resp)
-			logger.Debugf("PostWxaBusinessGetUserPhoneNumber:%+v", js)
-			if js.Get("errcode").MustInt() == 0 {
-				res = js.Get("phone_info").MustMap()
-			}
-		} else {
-			logger.Errorf("PostWxaBusinessGetUserPhoneNumber:%+v", err)
-		}
-	} else {
-		logger.Errorf("PostWxaBusinessGetUserPhoneNumber:%+v", err)
+	js, err := a.doHttpWithToken(ctx, http.MethodPost, "/wxa/business/getuserphonenumber", url.Values{}, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
 	}
-	return
+	return js.Get("phone_info").MustMap(), nil
 }
@@ -0,0 +1,100 @@
+package mp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/faabiosr/cachego/sync"
+	"github.com/leapig/tpp/util"
+)
+
+// hostedToken 第三方平台托管的令牌，刷新时返回 token
+func hostedToken(token string) *util.AccessToken {
+	return &util.AccessToken{
+		Id:    "hosted:" + token,
+		Cache: sync.New(),
+		GetRefreshRequestFunc: func(ctx context.Context) ([]byte, error) {
+			return []byte(`{"access_token":"` + token + `","expires_in":7200}`), nil
+		},
+	}
+}
+
+func componentAccessToken(ctx context.Context) (string, error) {
+	return "CAT", nil
+}
+
+func TestToken(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+		want   string
+		path   string
+		query  url.Values
+	}{
+		{"secret", Config{AppId: "wx1", Secret: "s"}, "AT", "/cgi-bin/token", url.Values{"appid": {"wx1"}, "secret": {"s"}, "grant_type": {"client_credential"}}},
+		{"hosted access token", Config{AppId: "wx1", AccessToken: hostedToken("HOSTED")}, "HOSTED", "", nil},
+		{"authorizer refresh token", Config{AppId: "wx1", Secret: "refreshtoken@@@r1", ComponentAppid: "wxc", ComponentToken: "static", GetComponentAccessToken: componentAccessToken}, "AAT", "/cgi-bin/component/api_authorizer_token", url.Values{"component_access_token": {"CAT"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var path string
+			var query url.Values
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				path, query = r.URL.Path, r.URL.Query()
+				w.Header().Set("Content-Type", "application/json")
+				if r.URL.Path == "/cgi-bin/component/api_authorizer_token" {
+					_, _ = w.Write([]byte(`{"authorizer_access_token":"AAT","expires_in":7200}`))
+					return
+				}
+				_, _ = w.Write([]byte(`{"access_token":"AT","expires_in":7200}`))
+			}))
+			defer srv.Close()
+			tt.config.BaseURL = srv.URL
+			tt.config.Cache = sync.New()
+			got, err := NewApp(tt.config).Token()
+			if err != nil || got != tt.want {
+				t.Fatalf("Token() = %q, %v, want %q", got, err, tt.want)
+			}
+			if path != tt.path || (tt.query != nil && query.Encode() != tt.query.Encode()) {
+				t.Errorf("request = %s?%s, want %s?%s", path, query.Encode(), tt.path, tt.query.Encode())
+			}
+		})
+	}
+}
+
+func TestJsCode2Session(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+		path   string
+		query  url.Values
+	}{
+		{"secret", Config{AppId: "wx1", Secret: "s"}, "/sns/jscode2session", url.Values{"appid": {"wx1"}, "js_code": {"c1"}, "secret": {"s"}, "grant_type": {"authorization_code"}}},
+		{"component", Config{AppId: "wx1", ComponentAppid: "wxc", AccessToken: hostedToken("HOSTED"), GetComponentAccessToken: componentAccessToken}, "/sns/component/jscode2session",
+			url.Values{"appid": {"wx1"}, "js_code": {"c1"}, "component_appid": {"wxc"}, "component_access_token": {"CAT"}, "grant_type": {"authorization_code"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var path string
+			var query url.Values
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				path, query = r.URL.Path, r.URL.Query()
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(`{"openid":"o1","session_key":"sk"}`))
+			}))
+			defer srv.Close()
+			tt.config.BaseURL = srv.URL
+			tt.config.Cache = sync.New()
+			res, err := NewApp(tt.config).JsCode2Session("c1")
+			if err != nil || res["openid"] != "o1" {
+				t.Fatalf("JsCode2Session() = %v, %v", res, err)
+			}
+			if path != tt.path || query.Encode() != tt.query.Encode() {
+				t.Errorf("request = %s?%s, want %s?%s", path, query.Encode(), tt.path, tt.query.Encode())
+			}
+		})
+	}
+}
@@ -0,0 +1,21 @@
+package mp
+
+import "github.com/leapig/tpp/util"
+
+// errorKinds 小程序错误码分类
+// doc https://developers.weixin.qq.com/miniprogram/dev/framework/server-ability/backend-api.html
+var errorKinds = map[int]error{
+	40001: util.ErrInvalidToken,
+	40014: util.ErrInvalidToken,
+	42001: util.ErrInvalidToken,
+	40029: util.ErrInvalidParam,
+	40125: util.ErrInvalidParam,
+	40163: util.ErrInvalidParam,
+	41002: util.ErrInvalidParam,
+	45009: util.ErrRateLimited,
+	45011: util.ErrRateLimited,
+	45015: util.ErrRateLimited,
+	48001: util.ErrPermissionDenied,
+	61007: util.ErrPermissionDenied,
+	89503: util.ErrPermissionDenied,
+}
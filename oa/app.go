@@ -2,20 +2,17 @@ package oa
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
-	"errors"
-	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"os"
 	"strings"
-	"time"
 
 	json2 "github.com/bitly/go-simplejson"
 	"github.com/faabiosr/cachego"
 	"github.com/faabiosr/cachego/file"
-	"github.com/leapig/tpp/logger"
 	"github.com/leapig/tpp/util"
 )
 
@@ -25,83 +22,191 @@ const WXCARD = "wx_card"
 type App interface {
 	Key() string
 	Id() string
-	Token() string
-	GetAccountBasicInfo() (res map[string]interface{})
-	QrcodeCreate(scene string, limit bool) (res map[string]interface{})
-	TemplateGetAllPrivateTemplate() []interface{}
-	TemplateApiAddTemplate(templateIdShort int, keywordNameList []string) (templateId string)
-	TemplateDelPrivateTemplate(templateId string) (res bool)
+	Close()
+	Token() (string, error)
+	TokenContext(ctx context.Context) (string, error)
+	GetAccountBasicInfo() (map[string]interface{}, error)
+	GetAccountBasicInfoContext(ctx context.Context) (map[string]interface{}, error)
+	QrcodeCreate(scene string, limit bool) (map[string]interface{}, error)
+	QrcodeCreateContext(ctx context.Context, scene string, limit bool) (map[string]interface{}, error)
+	TemplateGetAllPrivateTemplate() ([]interface{}, error)
+	TemplateGetAllPrivateTemplateContext(ctx context.Context) ([]interface{}, error)
+	TemplateApiAddTemplate(templateIdShort int, keywordNameList []string) (string, error)
+	TemplateApiAddTemplateContext(ctx context.Context, templateIdShort int, keywordNameList []string) (string, error)
+	TemplateDelPrivateTemplate(templateId string) error
+	TemplateDelPrivateTemplateContext(ctx context.Context, templateId string) error
 	MessageTemplateSend(msg Message) error
-	UserGet() (res []interface{})
-	UserInfo(openId string) (res map[string]interface{})
-	GetCurrentSelfMenuInfo() (res map[string]interface{})
-	MenuCreate(button []Button) (err error)
-	MenuDelete() (res bool)
-	TicketGetTicket(ticketType string) (ticket string)
-	AuthorizationCode(code string) (res map[string]interface{})
-	CardCodeDecrypt(encryptCode string) (code string)
-	OpenGet() (res string)
-	OpenBind(openAppid string) (err error)
-	OpenUnBind(openAppid string) (err error)
-	OpenCreate() (res map[string]interface{})
-}
-
-type GetComponentAccessToken func() string
+	MessageTemplateSendContext(ctx context.Context, msg Message) error
+	UserGet() ([]interface{}, error)
+	UserGetContext(ctx context.Context) ([]interface{}, error)
+	UserInfo(openId string) (map[string]interface{}, error)
+	UserInfoContext(ctx context.Context, openId string) (map[string]interface{}, error)
+	GetCurrentSelfMenuInfo() (map[string]interface{}, error)
+	GetCurrentSelfMenuInfoContext(ctx context.Context) (map[string]interface{}, error)
+	MenuCreate(button []Button) error
+	MenuCreateContext(ctx context.Context, button []Button) error
+	MenuDelete() error
+	MenuDeleteContext(ctx context.Context) error
+	TicketGetTicket(ticketType string) (string, error)
+	TicketGetTicketContext(ctx context.Context, ticketType string) (string, error)
+	JsConfig(url string) (*JsConfig, error)
+	JsConfigContext(ctx context.Context, url string) (*JsConfig, error)
+	ChooseCardConfig(shopId, cardType, cardId string) (*ChooseCardConfig, error)
+	ChooseCardConfigContext(ctx context.Context, shopId, cardType, cardId string) (*ChooseCardConfig, error)
+	AddCardConfig(cardId string, ext CardExt) (*AddCardConfig, error)
+	AddCardConfigContext(ctx context.Context, cardId string, ext CardExt) (*AddCardConfig, error)
+	AuthorizationCode(code string) (map[string]interface{}, error)
+	AuthorizationCodeContext(ctx context.Context, code string) (map[string]interface{}, error)
+	CardCodeDecrypt(encryptCode string) (string, error)
+	CardCodeDecryptContext(ctx context.Context, encryptCode string) (string, error)
+	OpenGet() (string, error)
+	OpenGetContext(ctx context.Context) (string, error)
+	OpenBind(openAppid string) error
+	OpenBindContext(ctx context.Context, openAppid string) error
+	OpenUnBind(openAppid string) error
+	OpenUnBindContext(ctx context.Context, openAppid string) error
+	OpenCreate() (map[string]interface{}, error)
+	OpenCreateContext(ctx context.Context) (map[string]interface{}, error)
+	Server() *Server
+}
+
+// GetComponentAccessToken 获取第三方平台 component_access_token
+type GetComponentAccessToken func(ctx context.Context) (string, error)
 
 type Config struct {
-	Key            string        `json:"key"`
-	AppId          string        `json:"appid"`
-	Secret         string        `json:"secret"`
-	Token          string        `json:"token"`
-	AesKey         string        `json:"aes_key"`
-	ComponentAppid string        `json:"component_appid"`
-	ComponentToken string        `json:"component_token"`
-	Cache          cachego.Cache `json:"cache"`
+	Key            string        `json:"key" yaml:"key"`
+	AppId          string        `json:"appid" yaml:"appid"`
+	Secret         string        `json:"secret" yaml:"secret"`
+	Token          string        `json:"token" yaml:"token"`
+	AesKey         string        `json:"aes_key" yaml:"aes_key"`
+	ComponentAppid string        `json:"component_appid" yaml:"component_appid"`
+	ComponentToken string        `json:"component_token" yaml:"component_token"`
+	Cache          cachego.Cache `json:"cache" yaml:"-"`
+	// BaseURL 服务地址，为空时使用平台默认地址
+	BaseURL string `json:"base_url" yaml:"base_url"`
+	// Proxy 出口代理地址
+	Proxy string `json:"proxy" yaml:"proxy"`
+	// HTTPClient 自定义请求客户端，优先于 Transport 与 Proxy
+	HTTPClient *http.Client `json:"-" yaml:"-"`
+	// Transport 自定义 Transport
+	Transport http.RoundTripper `json:"-" yaml:"-"`
+	// Locker 多实例部署时的令牌刷新锁
+	Locker util.Locker `json:"-" yaml:"-"`
+	// Refresher 后台预刷新，为空时仅在令牌过期后刷新
+	Refresher *util.Refresher `json:"-" yaml:"-"`
+	// AccessToken 第三方平台托管的授权账号调用令牌，设置后不再使用 Secret 刷新
+	AccessToken *util.AccessToken `json:"-" yaml:"-"`
+	// GetComponentAccessToken 第三方平台代调用时获取 component_access_token，优先于 ComponentToken
+	GetComponentAccessToken GetComponentAccessToken `json:"-" yaml:"-"`
+}
+
+// component 是否由第三方平台代调用
+func (c Config) component() bool {
+	return c.AccessToken != nil || strings.HasPrefix(c.Secret, "refreshtoken@@@")
+}
+
+// componentToken 获取 component_access_token
+func (c Config) componentToken(ctx context.Context) (string, error) {
+	if c.GetComponentAccessToken != nil {
+		return c.GetComponentAccessToken(ctx)
+	}
+	return c.ComponentToken, nil
 }
 
 type app struct {
 	config Config
 	token  util.AccessToken
-	server string
+	// tickets 按类型托管缓存的 jsapi_ticket 与 wx_card api_ticket
+	tickets map[string]util.AccessToken
+	client  *util.Client
+	server  string
 }
 
 func NewApp(config Config) App {
-	server := "https://api.weixin.qq.com"
+	server := util.BaseURL(config.BaseURL, "https://api.weixin.qq.com")
 	if config.Cache == nil {
 		config.Cache = file.New(os.TempDir())
 	}
-	return &app{
+	client := &util.Client{Platform: util.PlatformOA, Kinds: errorKinds, HttpClient: util.NewHTTPClient(config.HTTPClient, config.Transport, config.Proxy)}
+	a := &app{
 		server: server,
 		config: config,
+		client: client,
 		token: util.AccessToken{
-			Id:    config.AppId + config.Secret,
-			Cache: config.Cache,
-			GetRefreshRequestFunc: func() (resp []byte) {
+			Id:     util.CacheKey(util.PlatformOA, config.AppId, config.Secret),
+			Cache:  config.Cache,
+			Locker: config.Locker,
+			GetRefreshRequestFunc: func(ctx context.Context) (resp []byte, err error) {
+				var req *http.Request
 				if strings.HasPrefix(config.Secret, "refreshtoken@@@") {
+					componentToken, tokenErr := config.componentToken(ctx)
+					if tokenErr != nil {
+						return nil, tokenErr
+					}
 					params := url.Values{}
-					params.Add("component_access_token", config.ComponentToken)
+					params.Add("component_access_token", componentToken)
 					payload, _ := json.Marshal(map[string]string{
 						"component_appid":          config.ComponentAppid,
 						"authorizer_appid":         config.AppId,
 						"authorizer_refresh_token": config.Secret,
 					})
-					req, _ := http.NewRequest(http.MethodPost, server+"/cgi-bin/component/api_authorizer_token?"+params.Encode(), bytes.NewReader(payload))
-					response, _ := http.DefaultClient.Do(req)
-					resp, _ = io.ReadAll(response.Body)
+					req, err = http.NewRequestWithContext(ctx, http.MethodPost, server+"/cgi-bin/component/api_authorizer_token?"+params.Encode(), bytes.NewReader(payload))
 				} else {
 					params := url.Values{}
 					params.Add("appid", config.AppId)
 					params.Add("secret", config.Secret)
 					params.Add("grant_type", "client_credential")
-					req, _ := http.NewRequest(http.MethodGet, server+"/cgi-bin/token?"+params.Encode(), nil)
-					response, _ := http.DefaultClient.Do(req)
-					resp, _ = io.ReadAll(response.Body)
+					req, err = http.NewRequestWithContext(ctx, http.MethodGet, server+"/cgi-bin/token?"+params.Encode(), nil)
+				}
+				if err != nil {
+					return nil, util.NewError(util.PlatformOA, err)
 				}
-				fmt.Printf("\n\n%s\n\n", string(resp))
+				resp, err = client.Fetch(req)
 				return
 			},
 		},
 	}
+	if config.AccessToken != nil {
+		a.token = *config.AccessToken
+	}
+	a.tickets = map[string]util.AccessToken{
+		JSAPI:  a.newTicket(JSAPI),
+		WXCARD: a.newTicket(WXCARD),
+	}
+	if config.Refresher != nil {
+		config.Refresher.Add(a.token)
+		// wx_card 需开通卡券权限，仅在使用时刷新
+		config.Refresher.Add(a.tickets[JSAPI])
+	}
+	return a
+}
+
+// doHttp 执行请求并解析JSON响应
+func (a *app) doHttp(ctx context.Context, method string, path string, params url.Values, body io.Reader) (*json2.Json, error) {
+	req, err := http.NewRequestWithContext(ctx, method, a.server+path+"?"+params.Encode(), body)
+	if err != nil {
+		return nil, util.NewError(util.PlatformOA, err)
+	}
+	return a.client.Do(req)
+}
+
+// doHttpWithToken 附加 access_token 后执行请求，令牌失效时刷新后重试一次
+func (a *app) doHttpWithToken(ctx context.Context, method string, path string, params url.Values, body io.Reader) (js *json2.Json, err error) {
+	payload, err := util.ReadBody(body)
+	if err != nil {
+		return nil, util.NewError(util.PlatformOA, err)
+	}
+	err = a.token.WithAccessToken(ctx, func(token string) error {
+		js, err = a.doHttp(ctx, method, path, util.WithToken(params, "access_token", token), util.BodyReader(payload))
+		return err
+	})
+	return
+}
+
+// postJSON 附加 access_token 后以JSON格式提交
+func (a *app) postJSON(ctx context.Context, path string, data interface{}) (*json2.Json, error) {
+	payload, _ := json.Marshal(data)
+	return a.doHttpWithToken(ctx, http.MethodPost, path, url.Values{}, bytes.NewReader(payload))
 }
 
 // Key 获取当前实例Key
@@ -114,114 +219,102 @@ func (a *app) Id() string {
 	return a.config.AppId
 }
 
+// Close 停止实例令牌的后台预刷新，实例不再使用时调用
+func (a *app) Close() {
+	if a.config.Refresher != nil {
+		a.config.Refresher.Remove(a.token, a.tickets[JSAPI])
+	}
+}
+
 // Token 校验是否配置是否正常（返回access_token）
-func (a *app) Token() string {
-	return a.token.GetAccessToken()
+func (a *app) Token() (string, error) {
+	return a.TokenContext(context.Background())
+}
+
+// TokenContext 同 Token，支持 context.Context
+func (a *app) TokenContext(ctx context.Context) (string, error) {
+	return a.token.GetAccessTokenContext(ctx)
 }
 
 // GetAccountBasicInfo GET https://api.weixin.qq.com/cgi-bin/account/getaccountbasicinfo?access_token=ACCESS_TOKEN
-func (a *app) GetAccountBasicInfo() (res map[string]interface{}) {
-	params := url.Values{}
-	params = a.token.ApplyAccessToken(params)
-	if response, err := http.Get(a.server + "/cgi-bin/account/getaccountbasicinfo?" + params.Encode()); err == nil {
-		if resp, err := io.ReadAll(response.Body); err == nil {
-			js, _ := json2.NewJson(resp)
-			logger.Debugf("GetAccountBasicInfo:%+v", js)
-			res = js.MustMap()
-		} else {
-			logger.Errorf("GetAccountBasicInfo:%+v", err)
-		}
-	} else {
-		logger.Errorf("GetAccountBasicInfo:%+v", err)
+func (a *app) GetAccountBasicInfo() (map[string]interface{}, error) {
+	return a.GetAccountBasicInfoContext(context.Background())
+}
+
+// GetAccountBasicInfoContext 同 GetAccountBasicInfo，支持 context.Context
+func (a *app) GetAccountBasicInfoContext(ctx context.Context) (map[string]interface{}, error) {
+	js, err := a.doHttpWithToken(ctx, http.MethodGet, "/cgi-bin/account/getaccountbasicinfo", url.Values{}, nil)
+	if err != nil {
+		return nil, err
 	}
-	return
+	return js.MustMap(), nil
 }
 
 // QrcodeCreate https://api.weixin.qq.com/cgi-bin/qrcode/create
-func (a *app) QrcodeCreate(scene string, limit bool) (res map[string]interface{}) {
-	params := url.Values{}
-	params = a.token.ApplyAccessToken(params)
+func (a *app) QrcodeCreate(scene string, limit bool) (map[string]interface{}, error) {
+	return a.QrcodeCreateContext(context.Background(), scene, limit)
+}
+
+// QrcodeCreateContext 同 QrcodeCreate，支持 context.Context
+func (a *app) QrcodeCreateContext(ctx context.Context, scene string, limit bool) (map[string]interface{}, error) {
 	actionName := "QR_STR_SCENE"
 	if limit {
 		actionName = "QR_LIMIT_STR_SCENE"
 	}
-	payload := []byte(`{ "action_name": "` + actionName + `", "action_info": {"scene": {"scene_str": "` + scene + `"}}}`)
-	req, _ := http.NewRequest(http.MethodPost, a.server+"/cgi-bin/qrcode/create?"+params.Encode(), bytes.NewReader(payload))
-	if response, err := http.DefaultClient.Do(req); err == nil {
-		if resp, err := io.ReadAll(response.Body); err == nil {
-			js, _ := json2.NewJson(resp)
-			logger.Debugf("QrcodeCreate:%+v", js)
-			res = js.MustMap()
-		} else {
-			logger.Errorf("QrcodeCreate:%+v", err)
-		}
-	} else {
-		logger.Errorf("QrcodeCreate:%+v", err)
+	js, err := a.postJSON(ctx, "/cgi-bin/qrcode/create", map[string]interface{}{
+		"action_name": actionName,
+		"action_info": map[string]interface{}{
+			"scene": map[string]interface{}{"scene_str": scene},
+		},
+	})
+	if err != nil {
+		return nil, err
 	}
-	return
+	return js.MustMap(), nil
 }
 
 // TemplateGetAllPrivateTemplate GET https://api.weixin.qq.com/cgi-bin/template/get_all_private_template?access_token=ACCESS_TOKEN
-func (a *app) TemplateGetAllPrivateTemplate() (res []interface{}) {
-	params := url.Values{}
-	params = a.token.ApplyAccessToken(params)
-	if response, err := http.Get(a.server + "/cgi-bin/template/get_all_private_template?" + params.Encode()); err == nil {
-		if resp, err := io.ReadAll(response.Body); err == nil {
-			js, _ := json2.NewJson(resp)
-			logger.Debugf("TemplateGetAllPrivateTemplate:%+v", js)
-			res = js.Get("template_list").MustArray()
-		} else {
-			logger.Errorf("TemplateGetAllPrivateTemplate:%+v", err)
-		}
-	} else {
-		logger.Errorf("TemplateGetAllPrivateTemplate:%+v", err)
+func (a *app) TemplateGetAllPrivateTemplate() ([]interface{}, error) {
+	return a.TemplateGetAllPrivateTemplateContext(context.Background())
+}
+
+// TemplateGetAllPrivateTemplateContext 同 TemplateGetAllPrivateTemplate，支持 context.Context
+func (a *app) TemplateGetAllPrivateTemplateContext(ctx context.Context) ([]interface{}, error) {
+	js, err := a.doHttpWithToken(ctx, http.MethodGet, "/cgi-bin/template/get_all_private_template", url.Values{}, nil)
+	if err != nil {
+		return nil, err
 	}
-	return
+	return js.Get("template_list").MustArray(), nil
 }
 
 // TemplateApiAddTemplate POST https://api.weixin.qq.com/cgi-bin/template/api_add_template?access_token=ACCESS_TOKEN
-func (a *app) TemplateApiAddTemplate(templateIdShort int, keywordNameList []string) (templateId string) {
-	params := url.Values{}
-	params = a.token.ApplyAccessToken(params)
-	payload, _ := json.Marshal(map[string]interface{}{
+func (a *app) TemplateApiAddTemplate(templateIdShort int, keywordNameList []string) (string, error) {
+	return a.TemplateApiAddTemplateContext(context.Background(), templateIdShort, keywordNameList)
+}
+
+// TemplateApiAddTemplateContext 同 TemplateApiAddTemplate，支持 context.Context
+func (a *app) TemplateApiAddTemplateContext(ctx context.Context, templateIdShort int, keywordNameList []string) (string, error) {
+	js, err := a.postJSON(ctx, "/cgi-bin/template/api_add_template", map[string]interface{}{
 		"template_id_short": templateIdShort,
 		"keyword_name_list": keywordNameList,
 	})
-	req, _ := http.NewRequest(http.MethodPost, a.server+"/cgi-bin/template/api_add_template?"+params.Encode(), bytes.NewReader(payload))
-	if response, err := http.DefaultClient.Do(req); err == nil {
-		if resp, err := io.ReadAll(response.Body); err == nil {
-			js, _ := json2.NewJson(resp)
-			logger.Debugf("TemplateApiAddTemplate:%+v", js)
-			templateId = js.Get("template_id").MustString()
-		} else {
-			logger.Errorf("TemplateApiAddTemplate:%+v", err)
-		}
-	} else {
-		logger.Errorf("TemplateApiAddTemplate:%+v", err)
+	if err != nil {
+		return "", err
 	}
-	return
+	return js.Get("template_id").MustString(), nil
 }
 
 // TemplateDelPrivateTemplate POST https://api.weixin.qq.com/cgi-bin/template/del_private_template?access_token=ACCESS_TOKEN
-func (a *app) TemplateDelPrivateTemplate(templateId string) (res bool) {
-	params := url.Values{}
-	params = a.token.ApplyAccessToken(params)
-	payload, _ := json.Marshal(map[string]interface{}{
+func (a *app) TemplateDelPrivateTemplate(templateId string) error {
+	return a.TemplateDelPrivateTemplateContext(context.Background(), templateId)
+}
+
+// TemplateDelPrivateTemplateContext 同 TemplateDelPrivateTemplate，支持 context.Context
+func (a *app) TemplateDelPrivateTemplateContext(ctx context.Context, templateId string) error {
+	_, err := a.postJSON(ctx, "/cgi-bin/template/del_private_template", map[string]interface{}{
 		"template_id": templateId,
 	})
-	req, _ := http.NewRequest(http.MethodPost, a.server+"/cgi-bin/template/del_private_template?"+params.Encode(), bytes.NewReader(payload))
-	if response, err := http.DefaultClient.Do(req); err == nil {
-		if resp, err := io.ReadAll(response.Body); err == nil {
-			js, _ := json2.NewJson(resp)
-			logger.Debugf("TemplateDelPrivateTemplate:%+v", js)
-			res = js.Get("errcode").MustInt() == 0
-		} else {
-			logger.Errorf("TemplateDelPrivateTemplate:%+v", err)
-		}
-	} else {
-		logger.Errorf("TemplateDelPrivateTemplate:%+v", err)
-	}
-	return
+	return err
 }
 
 // Message 微信模板消息结构体
@@ -240,97 +333,75 @@ type Miniprogram struct {
 }
 
 // MessageTemplateSend POST https://api.weixin.qq.com/cgi-bin/message/template/send?access_token=ACCESS_TOKEN
-func (a *app) MessageTemplateSend(msg Message) (err error) {
-	params := url.Values{}
-	params = a.token.ApplyAccessToken(params)
-	payload, _ := json.Marshal(msg)
-	req, _ := http.NewRequest(http.MethodPost, a.server+"/cgi-bin/message/template/send?"+params.Encode(), bytes.NewReader(payload))
-	if response, err := http.DefaultClient.Do(req); err == nil {
-		if resp, err := io.ReadAll(response.Body); err == nil {
-			js, _ := json2.NewJson(resp)
-			logger.Debugf("MessageTemplateSend:%+v", js)
-			if js.Get("errcode").MustInt() != 0 {
-				return errors.New(js.Get("errmsg").MustString())
-			}
-		} else {
-			logger.Errorf("MessageTemplateSend:%+v", err)
-		}
-	} else {
-		logger.Errorf("MessageTemplateSend:%+v", err)
-	}
-	return
+func (a *app) MessageTemplateSend(msg Message) error {
+	return a.MessageTemplateSendContext(context.Background(), msg)
 }
 
-func (a *app) UserGet() (res []interface{}) {
-	resp := a.userGet("")
-	total, _ := resp["total"].(json.Number).Int64()
-	res = append(res, resp["data"].(map[string]interface{})["openid"].([]interface{})...)
+// MessageTemplateSendContext 同 MessageTemplateSend，支持 context.Context
+func (a *app) MessageTemplateSendContext(ctx context.Context, msg Message) error {
+	_, err := a.postJSON(ctx, "/cgi-bin/message/template/send", msg)
+	return err
+}
+
+// UserGet 获取全部关注者openid
+func (a *app) UserGet() (res []interface{}, err error) {
+	return a.UserGetContext(context.Background())
+}
+
+// UserGetContext 同 UserGet，支持 context.Context
+func (a *app) UserGetContext(ctx context.Context) (res []interface{}, err error) {
+	nextOpenid := ""
 	for {
-		if int(total) == len(res) {
+		js, err := a.userGet(ctx, nextOpenid)
+		if err != nil {
+			return res, err
+		}
+		res = append(res, js.GetPath("data", "openid").MustArray()...)
+		nextOpenid = js.Get("next_openid").MustString()
+		if nextOpenid == "" || js.Get("count").MustInt() == 0 || len(res) >= js.Get("total").MustInt() {
 			break
 		}
-		resp = a.userGet(resp["next_openid"].(string))
-		res = append(res, resp["data"].(map[string]interface{})["openid"].([]interface{})...)
 	}
 	return
 }
 
-// UserGet GET https://api.weixin.qq.com/cgi-bin/user/get?access_token=ACCESS_TOKEN&next_openid=NEXT_OPENID
-func (a *app) userGet(nextOpenid string) (res map[string]interface{}) {
+// userGet GET https://api.weixin.qq.com/cgi-bin/user/get?access_token=ACCESS_TOKEN&next_openid=NEXT_OPENID
+func (a *app) userGet(ctx context.Context, nextOpenid string) (*json2.Json, error) {
 	params := url.Values{}
 	if nextOpenid != "" {
 		params.Add("next_openid", nextOpenid)
 	}
-	params = a.token.ApplyAccessToken(params)
-	if response, err := http.Get(a.server + "/cgi-bin/user/get?" + params.Encode()); err == nil {
-		if resp, err := io.ReadAll(response.Body); err == nil {
-			js, _ := json2.NewJson(resp)
-			logger.Debugf("UserGet:%+v", js)
-			res = js.MustMap()
-		} else {
-			logger.Errorf("UserGet:%+v", err)
-		}
-	} else {
-		logger.Errorf("UserGet:%+v", err)
-	}
-	return
+	return a.doHttpWithToken(ctx, http.MethodGet, "/cgi-bin/user/get", params, nil)
 }
 
 // UserInfo GET https://api.weixin.qq.com/cgi-bin/user/info?access_token=ACCESS_TOKEN&openid=OPENID&lang=zh_CN
-func (a *app) UserInfo(openId string) (res map[string]interface{}) {
+func (a *app) UserInfo(openId string) (map[string]interface{}, error) {
+	return a.UserInfoContext(context.Background(), openId)
+}
+
+// UserInfoContext 同 UserInfo，支持 context.Context
+func (a *app) UserInfoContext(ctx context.Context, openId string) (map[string]interface{}, error) {
 	params := url.Values{}
 	params.Add("openid", openId)
-	params = a.token.ApplyAccessToken(params)
-	if response, err := http.Get(a.server + "/cgi-bin/user/info?" + params.Encode()); err == nil {
-		if resp, err := io.ReadAll(response.Body); err == nil {
-			js, _ := json2.NewJson(resp)
-			logger.Debugf("UserInfo:%+v", js)
-			res = js.MustMap()
-		} else {
-			logger.Errorf("UserInfo:%+v", err)
-		}
-	} else {
-		logger.Errorf("UserInfo:%+v", err)
+	js, err := a.doHttpWithToken(ctx, http.MethodGet, "/cgi-bin/user/info", params, nil)
+	if err != nil {
+		return nil, err
 	}
-	return
+	return js.MustMap(), nil
 }
 
 // GetCurrentSelfMenuInfo GET https://api.weixin.qq.com/cgi-bin/get_current_selfmenu_info?access_token=ACCESS_TOKEN
-func (a *app) GetCurrentSelfMenuInfo() (res map[string]interface{}) {
-	params := url.Values{}
-	params = a.token.ApplyAccessToken(params)
-	if response, err := http.Get(a.server + "/cgi-bin/get_current_selfmenu_info?" + params.Encode()); err == nil {
-		if resp, err := io.ReadAll(response.Body); err == nil {
-			js, _ := json2.NewJson(resp)
-			logger.Debugf("GetCurrentSelfMenuInfo:%+v", js)
-			res = js.MustMap()
-		} else {
-			logger.Errorf("GetCurrentSelfMenuInfo:%+v", err)
-		}
-	} else {
-		logger.Errorf("GetCurrentSelfMenuInfo:%+v", err)
+func (a *app) GetCurrentSelfMenuInfo() (map[string]interface{}, error) {
+	return a.GetCurrentSelfMenuInfoContext(context.Background())
+}
+
+// GetCurrentSelfMenuInfoContext 同 GetCurrentSelfMenuInfo，支持 context.Context
+func (a *app) GetCurrentSelfMenuInfoContext(ctx context.Context) (map[string]interface{}, error) {
+	js, err := a.doHttpWithToken(ctx, http.MethodGet, "/cgi-bin/get_current_selfmenu_info", url.Values{}, nil)
+	if err != nil {
+		return nil, err
 	}
-	return
+	return js.MustMap(), nil
 }
 
 // Button 公众号菜单结构体
@@ -348,229 +419,171 @@ type Button struct {
 
 // MenuCreate POST https://api.weixin.qq.com/cgi-bin/menu/create?access_token=ACCESS_TOKEN
 func (a *app) MenuCreate(button []Button) error {
-	params := url.Values{}
-	params = a.token.ApplyAccessToken(params)
+	return a.MenuCreateContext(context.Background(), button)
+}
+
+// MenuCreateContext 同 MenuCreate，支持 context.Context
+func (a *app) MenuCreateContext(ctx context.Context, button []Button) error {
 	// 特殊json编码，处理json.Marshal()值中& < >符号转义问题
 	bf := bytes.NewBuffer([]byte{})
 	jsonEncoder := json.NewEncoder(bf)
 	jsonEncoder.SetEscapeHTML(false)
 	_ = jsonEncoder.Encode(map[string][]Button{"button": button})
-	req, _ := http.NewRequest(http.MethodPost, a.server+"/cgi-bin/menu/create?"+params.Encode(), bf)
-	if response, err := http.DefaultClient.Do(req); err == nil {
-		if resp, err := io.ReadAll(response.Body); err == nil {
-			js, _ := json2.NewJson(resp)
-			logger.Debugf("MenuCreate:%+v", js)
-			if js.Get("errcode").MustInt() != 0 {
-				return errors.New(js.Get("errmsg").MustString())
-			}
-		} else {
-			logger.Errorf("MenuCreate:%+v", err)
-		}
-	} else {
-		logger.Errorf("MenuCreate:%+v", err)
-	}
-	return nil
+	_, err := a.doHttpWithToken(ctx, http.MethodPost, "/cgi-bin/menu/create", url.Values{}, bf)
+	return err
 }
 
 // MenuDelete GET https://api.weixin.qq.com/cgi-bin/menu/delete?access_token=ACCESS_TOKEN
-func (a *app) MenuDelete() (res bool) {
-	params := url.Values{}
-	params = a.token.ApplyAccessToken(params)
-	if response, err := http.Get(a.server + "/cgi-bin/menu/delete?" + params.Encode()); err == nil {
-		if resp, err := io.ReadAll(response.Body); err == nil {
-			js, _ := json2.NewJson(resp)
-			logger.Debugf("MenuDelete:%+v", js)
-			res = js.Get("errcode").MustInt() == 0
-		} else {
-			logger.Errorf("MenuDelete:%+v", err)
-		}
-	} else {
-		logger.Errorf("MenuDelete:%+v", err)
-	}
-	return
+func (a *app) MenuDelete() error {
+	return a.MenuDeleteContext(context.Background())
+}
+
+// MenuDeleteContext 同 MenuDelete，支持 context.Context
+func (a *app) MenuDeleteContext(ctx context.Context) error {
+	_, err := a.doHttpWithToken(ctx, http.MethodGet, "/cgi-bin/menu/delete", url.Values{}, nil)
+	return err
 }
 
 // TicketGetTicket GET https://api.weixin.qq.com/cgi-bin/ticket/getticket?access_token=ACCESS_TOKEN&type=jsapi
-func (a *app) TicketGetTicket(ticketType string) (ticket string) {
-	ticket, _ = a.token.Cache.Fetch("ticket" + a.token.Id)
-	if ticket == "" {
-		params := url.Values{}
-		params.Add("type", ticketType)
-		params = a.token.ApplyAccessToken(params)
-		if response, err := http.Get(a.server + "/cgi-bin/ticket/getticket?" + params.Encode()); err == nil {
-			if resp, err := io.ReadAll(response.Body); err == nil {
-				js, _ := json2.NewJson(resp)
-				logger.Debugf("TicketGetTicket:%+v", js)
-				if js.Get("errcode").MustInt() == 0 {
-					ticket = js.Get("ticket").MustString()
-					d := time.Duration(js.Get("expires_in").MustInt()) * time.Second
-					_ = a.token.Cache.Save("ticket:"+a.token.Id, ticket, d)
-				}
-			} else {
-				logger.Errorf("TicketGetTicket:%+v", err)
+// ticketType 为 JSAPI 或 WXCARD，按类型缓存至过期
+func (a *app) TicketGetTicket(ticketType string) (ticket string, err error) {
+	return a.TicketGetTicketContext(context.Background(), ticketType)
+}
+
+// TicketGetTicketContext 同 TicketGetTicket，支持 context.Context
+func (a *app) TicketGetTicketContext(ctx context.Context, ticketType string) (ticket string, err error) {
+	t, ok := a.tickets[ticketType]
+	if !ok {
+		return "", &util.Error{Platform: util.PlatformOA, ErrMsg: "unsupported ticket type: " + ticketType, Kind: util.ErrInvalidParam}
+	}
+	return t.GetAccessTokenContext(ctx)
+}
+
+// newTicket 创建 ticketType 类型的票据，与 access_token 共用缓存与刷新锁
+func (a *app) newTicket(ticketType string) util.AccessToken {
+	return util.AccessToken{
+		Id:     "ticket:" + ticketType + ":" + a.token.Id,
+		Cache:  a.config.Cache,
+		Locker: a.config.Locker,
+		GetRefreshRequestFunc: func(ctx context.Context) ([]byte, error) {
+			js, err := a.getTicket(ctx, ticketType)
+			if err != nil {
+				return nil, err
 			}
-		} else {
-			logger.Errorf("TicketGetTicket:%+v", err)
-		}
+			return js.Encode()
+		},
+		Parse: util.ParseJSON("ticket", "expires_in"),
 	}
-	return
+}
+
+func (a *app) getTicket(ctx context.Context, ticketType string) (*json2.Json, error) {
+	params := url.Values{}
+	params.Add("type", ticketType)
+	return a.doHttpWithToken(ctx, http.MethodGet, "/cgi-bin/ticket/getticket", params, nil)
 }
 
 // AuthorizationCode GET https://api.weixin.qq.com/sns/oauth2/access_token?appid=APPID&secret=SECRET&code=CODE&grant_type=authorization_code
-func (a *app) AuthorizationCode(code string) (res map[string]interface{}) {
+func (a *app) AuthorizationCode(code string) (map[string]interface{}, error) {
+	return a.AuthorizationCodeContext(context.Background(), code)
+}
+
+// AuthorizationCodeContext 同 AuthorizationCode，支持 context.Context
+func (a *app) AuthorizationCodeContext(ctx context.Context, code string) (map[string]interface{}, error) {
 	params := url.Values{}
 	params.Add("appid", a.config.AppId)
 	params.Add("code", code)
 	params.Add("grant_type", "authorization_code")
-	if strings.HasPrefix(a.config.Secret, "refreshtoken@@@") {
-		params.Add("component_appid", a.config.ComponentAppid)
-		params.Add("component_access_token", a.config.ComponentToken)
-		if response, err := http.Get(a.server + "/sns/oauth2/component/access_token?" + params.Encode()); err == nil {
-			if resp, err := io.ReadAll(response.Body); err == nil {
-				js, _ := json2.NewJson(resp)
-				logger.Debugf("AuthorizationCode:%+v", js)
-				if js.Get("Openid") != nil {
-					res = js.MustMap()
-				}
-			} else {
-				logger.Errorf("AuthorizationCode:%+v", err)
-			}
-		} else {
-			logger.Errorf("AuthorizationCode:%+v", err)
+	path := "/sns/oauth2/access_token"
+	if a.config.component() {
+		componentToken, err := a.config.componentToken(ctx)
+		if err != nil {
+			return nil, err
 		}
+		params.Add("component_appid", a.config.ComponentAppid)
+		params.Add("component_access_token", componentToken)
+		path = "/sns/oauth2/component/access_token"
 	} else {
 		params.Add("secret", a.config.Secret)
-		if response, err := http.Get(a.server + "/sns/oauth2/access_token?" + params.Encode()); err == nil {
-			if resp, err := io.ReadAll(response.Body); err == nil {
-				js, _ := json2.NewJson(resp)
-				logger.Debugf("AuthorizationCode:%+v", js)
-				if js.Get("Openid") != nil {
-					res = js.MustMap()
-				}
-			} else {
-				logger.Errorf("AuthorizationCode:%+v", err)
-			}
-		} else {
-			logger.Errorf("AuthorizationCode:%+v", err)
-		}
 	}
-	return
+	js, err := a.doHttp(ctx, http.MethodGet, path, params, nil)
+	if err != nil {
+		return nil, err
+	}
+	return js.MustMap(), nil
 }
 
 // CardCodeDecrypt POST https://api.weixin.qq.com/card/code/decrypt?access_token=TOKEN
-func (a *app) CardCodeDecrypt(encryptCode string) (code string) {
-	params := url.Values{}
-	params = a.token.ApplyAccessToken(params)
-	payload, _ := json.Marshal(map[string]interface{}{
+func (a *app) CardCodeDecrypt(encryptCode string) (string, error) {
+	return a.CardCodeDecryptContext(context.Background(), encryptCode)
+}
+
+// CardCodeDecryptContext 同 CardCodeDecrypt，支持 context.Context
+func (a *app) CardCodeDecryptContext(ctx context.Context, encryptCode string) (string, error) {
+	js, err := a.postJSON(ctx, "/card/code/decrypt", map[string]interface{}{
 		"encrypt_code": encryptCode,
 	})
-	req, _ := http.NewRequest(http.MethodPost, a.server+"/card/code/decrypt?"+params.Encode(), bytes.NewReader(payload))
-	if response, err := http.DefaultClient.Do(req); err == nil {
-		if resp, err := io.ReadAll(response.Body); err == nil {
-			js, _ := json2.NewJson(resp)
-			logger.Debugf("CardCodeDecrypt:%+v", js)
-			code = js.Get("code").MustString()
-		} else {
-			logger.Errorf("CardCodeDecrypt:%+v", err)
-		}
-	} else {
-		logger.Errorf("CardCodeDecrypt:%+v", err)
+	if err != nil {
+		return "", err
 	}
-	return
+	return js.Get("code").MustString(), nil
 }
 
 // OpenGet POST https://api.weixin.qq.com/cgi-bin/open/get?access_token=ACCESS_TOKEN
-func (a *app) OpenGet() (res string) {
-	params := url.Values{}
-	params = a.token.ApplyAccessToken(params)
-	payload, _ := json.Marshal(map[string]interface{}{
+func (a *app) OpenGet() (string, error) {
+	return a.OpenGetContext(context.Background())
+}
+
+// OpenGetContext 同 OpenGet，支持 context.Context
+func (a *app) OpenGetContext(ctx context.Context) (string, error) {
+	js, err := a.postJSON(ctx, "/cgi-bin/open/get", map[string]interface{}{
 		"appid": a.config.AppId,
 	})
-	req, _ := http.NewRequest(http.MethodPost, a.server+"/cgi-bin/open/get?"+params.Encode(), bytes.NewReader(payload))
-	if response, err := http.DefaultClient.Do(req); err == nil {
-		if resp, err := io.ReadAll(response.Body); err == nil {
-			js, _ := json2.NewJson(resp)
-			logger.Debugf("OpenGet:%+v", js)
-			if js.Get("errcode").MustInt() == 0 {
-				res = js.Get("open_appid").MustString()
-			}
-		} else {
-			logger.Errorf("OpenGet:%+v", err)
-		}
-	} else {
-		logger.Errorf("OpenGet:%+v", err)
+	if err != nil {
+		return "", err
 	}
-	return
+	return js.Get("open_appid").MustString(), nil
 }
 
 // OpenBind POST https://api.weixin.qq.com/cgi-bin/open/bind?access_token=ACCESS_TOKEN
-func (a *app) OpenBind(openAppid string) (err error) {
-	params := url.Values{}
-	params = a.token.ApplyAccessToken(params)
-	payload, _ := json.Marshal(map[string]interface{}{
+func (a *app) OpenBind(openAppid string) error {
+	return a.OpenBindContext(context.Background(), openAppid)
+}
+
+// OpenBindContext 同 OpenBind，支持 context.Context
+func (a *app) OpenBindContext(ctx context.Context, openAppid string) error {
+	_, err := a.postJSON(ctx, "/cgi-bin/open/bind", map[string]interface{}{
 		"appid":      a.config.AppId,
 		"open_appid": openAppid,
 	})
-	req, _ := http.NewRequest(http.MethodPost, a.server+"/cgi-bin/open/bind?"+params.Encode(), bytes.NewReader(payload))
-	if response, err := http.DefaultClient.Do(req); err == nil {
-		if resp, err := io.ReadAll(response.Body); err == nil {
-			js, _ := json2.NewJson(resp)
-			logger.Debugf("OpenBind:%+v", js)
-			if js.Get("errcode").MustInt() != 0 {
-				return errors.New(js.Get("errmsg").MustString())
-			}
-		} else {
-			logger.Errorf("OpenBind:%+v", err)
-		}
-	} else {
-		logger.Errorf("OpenBind:%+v", err)
-	}
-	return
+	return err
 }
 
 // OpenUnBind POST https://api.weixin.qq.com/cgi-bin/open/unbind?access_token=ACCESS_TOKEN
-func (a *app) OpenUnBind(openAppid string) (err error) {
-	params := url.Values{}
-	params = a.token.ApplyAccessToken(params)
-	payload, _ := json.Marshal(map[string]interface{}{
+func (a *app) OpenUnBind(openAppid string) error {
+	return a.OpenUnBindContext(context.Background(), openAppid)
+}
+
+// OpenUnBindContext 同 OpenUnBind，支持 context.Context
+func (a *app) OpenUnBindContext(ctx context.Context, openAppid string) error {
+	_, err := a.postJSON(ctx, "/cgi-bin/open/unbind", map[string]interface{}{
 		"appid":      a.config.AppId,
 		"open_appid": openAppid,
 	})
-	req, _ := http.NewRequest(http.MethodPost, a.server+"/cgi-bin/open/unbind?"+params.Encode(), bytes.NewReader(payload))
-	if response, err := http.DefaultClient.Do(req); err == nil {
-		if resp, err := io.ReadAll(response.Body); err == nil {
-			js, _ := json2.NewJson(resp)
-			logger.Debugf("OpenUnBind:%+v", js)
-			if js.Get("errcode").MustInt() != 0 {
-				return errors.New(js.Get("errmsg").MustString())
-			}
-		} else {
-			logger.Errorf("OpenUnBind:%+v", err)
-		}
-	} else {
-		logger.Errorf("OpenUnBind:%+v", err)
-	}
-	return
+	return err
 }
 
 // OpenCreate POST https://api.weixin.qq.com/cgi-bin/open/create?access_token=ACCESS_TOKEN
-func (a *app) OpenCreate() (res map[string]interface{}) {
-	params := url.Values{}
-	params = a.token.ApplyAccessToken(params)
-	payload, _ := json.Marshal(map[string]interface{}{
+func (a *app) OpenCreate() (map[string]interface{}, error) {
+	return a.OpenCreateContext(context.Background())
+}
+
+// OpenCreateContext 同 OpenCreate，支持 context.Context
+func (a *app) OpenCreateContext(ctx context.Context) (map[string]interface{}, error) {
+	js, err := a.postJSON(ctx, "/cgi-bin/open/create", map[string]interface{}{
 		"appid": a.config.AppId,
 	})
-	req, _ := http.NewRequest(http.MethodPost, a.server+"/cgi-bin/open/create?"+params.Encode(), bytes.NewReader(payload))
-	if response, err := http.DefaultClient.Do(req); err == nil {
-		if resp, err := io.ReadAll(response.Body); err == nil {
-			js, _ := json2.NewJson(resp)
-			logger.Debugf("OpenCreate:%+v", js)
-			res = js.MustMap()
-		} else {
-			logger.Errorf("OpenCreate:%+v", err)
-		}
-	} else {
-		logger.Errorf("OpenCreate:%+v", err)
+	if err != nil {
+		return nil, err
 	}
-	return
+	return js.MustMap(), nil
 }
@@ -0,0 +1,25 @@
+package oa
+
+import "github.com/leapig/tpp/util"
+
+// errorKinds 公众号错误码分类
+// doc https://developers.weixin.qq.com/doc/offiaccount/Getting_Started/Global_Return_Code.html
+var errorKinds = map[int]error{
+	40001: util.ErrInvalidToken,
+	40014: util.ErrInvalidToken,
+	42001: util.ErrInvalidToken,
+	40003: util.ErrInvalidParam,
+	40029: util.ErrInvalidParam,
+	40037: util.ErrInvalidParam,
+	40163: util.ErrInvalidParam,
+	45009: util.ErrRateLimited,
+	45011: util.ErrRateLimited,
+	45047: util.ErrRateLimited,
+	48001: util.ErrPermissionDenied,
+	48004: util.ErrPermissionDenied,
+	50001: util.ErrPermissionDenied,
+	50002: util.ErrPermissionDenied,
+	61007: util.ErrPermissionDenied,
+	46004: util.ErrNotFound,
+	46003: util.ErrNotFound,
+}
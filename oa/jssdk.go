@@ -0,0 +1,113 @@
+package oa
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/leapig/tpp/util"
+)
+
+// JsConfig wx.config 签名参数，可直接序列化后传给前端
+// doc https://developers.weixin.qq.com/doc/offiaccount/OA_Web_Apps/JS-SDK.html#62
+type JsConfig struct {
+	AppId     string `json:"appId"`
+	Timestamp int64  `json:"timestamp"`
+	NonceStr  string `json:"nonceStr"`
+	Signature string `json:"signature"`
+}
+
+// JsConfig 使用缓存的 jsapi_ticket 生成 url 页面的 wx.config 签名参数
+func (a *app) JsConfig(url string) (*JsConfig, error) {
+	return a.JsConfigContext(context.Background(), url)
+}
+
+// JsConfigContext 同 JsConfig，支持 context.Context
+func (a *app) JsConfigContext(ctx context.Context, url string) (*JsConfig, error) {
+	ticket, err := a.tickets[JSAPI].GetAccessTokenContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	config := &JsConfig{
+		AppId:     a.config.AppId,
+		Timestamp: time.Now().Unix(),
+		NonceStr:  util.GetRandString(16),
+	}
+	config.Signature = util.JsSdkSignature(ticket, config.NonceStr, strconv.FormatInt(config.Timestamp, 10), url)
+	return config, nil
+}
+
+// ChooseCardConfig wx.chooseCard 签名参数，使用 wx_card 类型的 api_ticket
+// doc https://developers.weixin.qq.com/doc/offiaccount/OA_Web_Apps/JS-SDK.html#54
+type ChooseCardConfig struct {
+	ShopId    string `json:"shopId"`
+	CardType  string `json:"cardType"`
+	CardId    string `json:"cardId"`
+	Timestamp int64  `json:"timestamp"`
+	NonceStr  string `json:"nonceStr"`
+	SignType  string `json:"signType"`
+	CardSign  string `json:"cardSign"`
+}
+
+// ChooseCardConfig 生成 wx.chooseCard 签名参数，shopId、cardType、cardId 为空时不限制
+func (a *app) ChooseCardConfig(shopId, cardType, cardId string) (*ChooseCardConfig, error) {
+	return a.ChooseCardConfigContext(context.Background(), shopId, cardType, cardId)
+}
+
+// ChooseCardConfigContext 同 ChooseCardConfig，支持 context.Context
+func (a *app) ChooseCardConfigContext(ctx context.Context, shopId, cardType, cardId string) (*ChooseCardConfig, error) {
+	ticket, err := a.tickets[WXCARD].GetAccessTokenContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	config := &ChooseCardConfig{
+		ShopId:    shopId,
+		CardType:  cardType,
+		CardId:    cardId,
+		Timestamp: time.Now().Unix(),
+		NonceStr:  util.GetRandString(16),
+		SignType:  "SHA1",
+	}
+	config.CardSign = util.SortSha1(ticket, a.config.AppId, shopId, strconv.FormatInt(config.Timestamp, 10), config.NonceStr, cardId, cardType)
+	return config, nil
+}
+
+// CardExt wx.addCard 的 cardExt，Code、OpenId 仅在卡券为自定义 code 或指定用户领取时填写
+// doc https://developers.weixin.qq.com/doc/offiaccount/Cards_and_Offer/Create_a_Coupon_Voucher_or_Card.html#5
+type CardExt struct {
+	Code                string `json:"code,omitempty"`
+	OpenId              string `json:"openid,omitempty"`
+	Timestamp           string `json:"timestamp"`
+	NonceStr            string `json:"nonce_str"`
+	FixedBeginTimestamp int64  `json:"fixed_begintimestamp,omitempty"`
+	OuterStr            string `json:"outer_str,omitempty"`
+	Signature           string `json:"signature"`
+}
+
+// AddCardConfig wx.addCard 的 cardList 元素，CardExt 为序列化后的 JSON 字符串
+type AddCardConfig struct {
+	CardId  string `json:"cardId"`
+	CardExt string `json:"cardExt"`
+}
+
+// AddCardConfig 生成 wx.addCard 签名参数，ext 的 Timestamp、NonceStr、Signature 由本方法填写
+func (a *app) AddCardConfig(cardId string, ext CardExt) (*AddCardConfig, error) {
+	return a.AddCardConfigContext(context.Background(), cardId, ext)
+}
+
+// AddCardConfigContext 同 AddCardConfig，支持 context.Context
+func (a *app) AddCardConfigContext(ctx context.Context, cardId string, ext CardExt) (*AddCardConfig, error) {
+	ticket, err := a.tickets[WXCARD].GetAccessTokenContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ext.Timestamp = strconv.FormatInt(time.Now().Unix(), 10)
+	ext.NonceStr = util.GetRandString(16)
+	ext.Signature = util.SortSha1(ticket, ext.Timestamp, cardId, ext.Code, ext.OpenId, ext.NonceStr)
+	b, err := json.Marshal(ext)
+	if err != nil {
+		return nil, util.NewError(util.PlatformOA, err)
+	}
+	return &AddCardConfig{CardId: cardId, CardExt: string(b)}, nil
+}
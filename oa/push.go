@@ -0,0 +1,107 @@
+package oa
+
+// 消息类型
+const (
+	MsgTypeText  = "text"
+	MsgTypeImage = "image"
+	MsgTypeVoice = "voice"
+	MsgTypeEvent = "event"
+)
+
+// 事件类型
+const (
+	EventSubscribe             = "subscribe"
+	EventUnsubscribe           = "unsubscribe"
+	EventScan                  = "SCAN"
+	EventClick                 = "CLICK"
+	EventView                  = "VIEW"
+	EventTemplateSendJobFinish = "TEMPLATESENDJOBFINISH"
+)
+
+// PushHeader 推送消息公共字段
+type PushHeader struct {
+	ToUserName   string `xml:"ToUserName"`
+	FromUserName string `xml:"FromUserName"`
+	CreateTime   int64  `xml:"CreateTime"`
+	MsgType      string `xml:"MsgType"`
+}
+
+// Push 未注册类型处理函数的推送，Raw 为解密后的明文XML
+type Push struct {
+	PushHeader
+	MsgId int64  `xml:"MsgId"`
+	Event string `xml:"Event"`
+	Raw   []byte `xml:"-"`
+}
+
+// TextMessage 文本消息
+// doc https://developers.weixin.qq.com/doc/offiaccount/Message_Management/Receiving_standard_messages.html
+type TextMessage struct {
+	PushHeader
+	MsgId   int64  `xml:"MsgId"`
+	Content string `xml:"Content"`
+}
+
+// ImageMessage 图片消息
+type ImageMessage struct {
+	PushHeader
+	MsgId   int64  `xml:"MsgId"`
+	PicUrl  string `xml:"PicUrl"`
+	MediaId string `xml:"MediaId"`
+}
+
+// VoiceMessage 语音消息，Recognition 为开通语音识别后的识别结果
+type VoiceMessage struct {
+	PushHeader
+	MsgId       int64  `xml:"MsgId"`
+	MediaId     string `xml:"MediaId"`
+	Format      string `xml:"Format"`
+	Recognition string `xml:"Recognition"`
+}
+
+// SubscribeEvent 关注事件，扫描带参数二维码关注时 EventKey 为 qrscene_ 前缀的场景值
+// doc https://developers.weixin.qq.com/doc/offiaccount/Message_Management/Receiving_event_pushes.html
+type SubscribeEvent struct {
+	PushHeader
+	Event    string `xml:"Event"`
+	EventKey string `xml:"EventKey"`
+	Ticket   string `xml:"Ticket"`
+}
+
+// UnsubscribeEvent 取消关注事件
+type UnsubscribeEvent struct {
+	PushHeader
+	Event string `xml:"Event"`
+}
+
+// ScanEvent 已关注用户扫描带参数二维码事件
+type ScanEvent struct {
+	PushHeader
+	Event    string `xml:"Event"`
+	EventKey string `xml:"EventKey"`
+	Ticket   string `xml:"Ticket"`
+}
+
+// ClickEvent 点击菜单拉取消息事件，EventKey 为菜单 key
+type ClickEvent struct {
+	PushHeader
+	Event    string `xml:"Event"`
+	EventKey string `xml:"EventKey"`
+}
+
+// ViewEvent 点击菜单跳转链接事件，EventKey 为跳转URL
+type ViewEvent struct {
+	PushHeader
+	Event    string `xml:"Event"`
+	EventKey string `xml:"EventKey"`
+	MenuId   string `xml:"MenuId"`
+}
+
+// TemplateSendJobFinishEvent 模板消息发送结果事件
+// doc https://developers.weixin.qq.com/doc/offiaccount/Message_Management/Template_Message_Interface.html
+type TemplateSendJobFinishEvent struct {
+	PushHeader
+	Event  string `xml:"Event"`
+	MsgID  int64  `xml:"MsgID"`
+	Status string `xml:"Status"`
+}
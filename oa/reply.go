@@ -0,0 +1,177 @@
+package oa
+
+import (
+	"encoding/xml"
+	"fmt"
+
+	"github.com/leapig/tpp/util"
+)
+
+// 被动回复消息类型
+// doc https://developers.weixin.qq.com/doc/offiaccount/Message_Management/Passive_user_reply_message.html
+const (
+	ReplyTypeText                    = "text"
+	ReplyTypeImage                   = "image"
+	ReplyTypeVoice                   = "voice"
+	ReplyTypeVideo                   = "video"
+	ReplyTypeMusic                   = "music"
+	ReplyTypeNews                    = "news"
+	ReplyTypeTransferCustomerService = "transfer_customer_service"
+)
+
+// TextReply 回复文本消息
+type TextReply struct {
+	Content string
+}
+
+func (r TextReply) Marshal(toUserName, fromUserName string, createTime int64) ([]byte, error) {
+	return xml.Marshal(struct {
+		util.ReplyHeader
+		Content util.CDATA `xml:"Content"`
+	}{util.NewReplyHeader(toUserName, fromUserName, createTime, ReplyTypeText), util.CDATA{Value: r.Content}})
+}
+
+type mediaReply struct {
+	MediaId util.CDATA `xml:"MediaId"`
+}
+
+// ImageReply 回复图片消息，MediaId 为素材ID
+type ImageReply struct {
+	MediaId string
+}
+
+func (r ImageReply) Marshal(toUserName, fromUserName string, createTime int64) ([]byte, error) {
+	return xml.Marshal(struct {
+		util.ReplyHeader
+		Image mediaReply `xml:"Image"`
+	}{util.NewReplyHeader(toUserName, fromUserName, createTime, ReplyTypeImage), mediaReply{util.CDATA{Value: r.MediaId}}})
+}
+
+// VoiceReply 回复语音消息，MediaId 为素材ID
+type VoiceReply struct {
+	MediaId string
+}
+
+func (r VoiceReply) Marshal(toUserName, fromUserName string, createTime int64) ([]byte, error) {
+	return xml.Marshal(struct {
+		util.ReplyHeader
+		Voice mediaReply `xml:"Voice"`
+	}{util.NewReplyHeader(toUserName, fromUserName, createTime, ReplyTypeVoice), mediaReply{util.CDATA{Value: r.MediaId}}})
+}
+
+// VideoReply 回复视频消息
+type VideoReply struct {
+	MediaId     string
+	Title       string
+	Description string
+}
+
+type videoReply struct {
+	MediaId     util.CDATA `xml:"MediaId"`
+	Title       util.CDATA `xml:"Title"`
+	Description util.CDATA `xml:"Description"`
+}
+
+func (r VideoReply) Marshal(toUserName, fromUserName string, createTime int64) ([]byte, error) {
+	return xml.Marshal(struct {
+		util.ReplyHeader
+		Video videoReply `xml:"Video"`
+	}{util.NewReplyHeader(toUserName, fromUserName, createTime, ReplyTypeVideo), videoReply{
+		MediaId:     util.CDATA{Value: r.MediaId},
+		Title:       util.CDATA{Value: r.Title},
+		Description: util.CDATA{Value: r.Description},
+	}})
+}
+
+// MusicReply 回复音乐消息，ThumbMediaId 为缩略图素材ID
+type MusicReply struct {
+	Title        string
+	Description  string
+	MusicUrl     string
+	HQMusicUrl   string
+	ThumbMediaId string
+}
+
+type musicReply struct {
+	Title        util.CDATA `xml:"Title"`
+	Description  util.CDATA `xml:"Description"`
+	MusicUrl     util.CDATA `xml:"MusicUrl"`
+	HQMusicUrl   util.CDATA `xml:"HQMusicUrl"`
+	ThumbMediaId util.CDATA `xml:"ThumbMediaId"`
+}
+
+func (r MusicReply) Marshal(toUserName, fromUserName string, createTime int64) ([]byte, error) {
+	return xml.Marshal(struct {
+		util.ReplyHeader
+		Music musicReply `xml:"Music"`
+	}{util.NewReplyHeader(toUserName, fromUserName, createTime, ReplyTypeMusic), musicReply{
+		Title:        util.CDATA{Value: r.Title},
+		Description:  util.CDATA{Value: r.Description},
+		MusicUrl:     util.CDATA{Value: r.MusicUrl},
+		HQMusicUrl:   util.CDATA{Value: r.HQMusicUrl},
+		ThumbMediaId: util.CDATA{Value: r.ThumbMediaId},
+	}})
+}
+
+// Article 图文消息条目
+type Article struct {
+	Title       string
+	Description string
+	PicUrl      string
+	Url         string
+}
+
+type articleItem struct {
+	Title       util.CDATA `xml:"Title"`
+	Description util.CDATA `xml:"Description"`
+	PicUrl      util.CDATA `xml:"PicUrl"`
+	Url         util.CDATA `xml:"Url"`
+}
+
+// maxArticles 图文消息条目上限
+const maxArticles = 8
+
+// NewsReply 回复图文消息，最多 8 条
+type NewsReply struct {
+	Articles []Article
+}
+
+func (r NewsReply) Marshal(toUserName, fromUserName string, createTime int64) ([]byte, error) {
+	if len(r.Articles) == 0 || len(r.Articles) > maxArticles {
+		return nil, fmt.Errorf("news reply requires 1 to %d articles, got %d", maxArticles, len(r.Articles))
+	}
+	items := make([]articleItem, len(r.Articles))
+	for i, article := range r.Articles {
+		items[i] = articleItem{
+			Title:       util.CDATA{Value: article.Title},
+			Description: util.CDATA{Value: article.Description},
+			PicUrl:      util.CDATA{Value: article.PicUrl},
+			Url:         util.CDATA{Value: article.Url},
+		}
+	}
+	return xml.Marshal(struct {
+		util.ReplyHeader
+		ArticleCount int           `xml:"ArticleCount"`
+		Articles     []articleItem `xml:"Articles>item"`
+	}{util.NewReplyHeader(toUserName, fromUserName, createTime, ReplyTypeNews), len(items), items})
+}
+
+// TransferCustomerServiceReply 将消息转发到客服，KfAccount 为空时由系统分配客服
+// doc https://developers.weixin.qq.com/doc/offiaccount/Customer_Service/Forwarding_of_messages_to_service_center.html
+type TransferCustomerServiceReply struct {
+	KfAccount string
+}
+
+func (r TransferCustomerServiceReply) Marshal(toUserName, fromUserName string, createTime int64) ([]byte, error) {
+	type transInfo struct {
+		KfAccount util.CDATA `xml:"KfAccount"`
+	}
+	var info *transInfo
+	if r.KfAccount != "" {
+		info = &transInfo{util.CDATA{Value: r.KfAccount}}
+	}
+	return xml.Marshal(struct {
+		util.ReplyHeader
+		TransInfo *transInfo `xml:"TransInfo,omitempty"`
+	}{util.NewReplyHeader(toUserName, fromUserName, createTime, ReplyTypeTransferCustomerService), info})
+}
@@ -0,0 +1,250 @@
+package oa
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/faabiosr/cachego"
+	"github.com/leapig/tpp/logger"
+	"github.com/leapig/tpp/util"
+)
+
+// maxPushSize 推送消息体上限
+const maxPushSize = 1 << 20
+
+// Reply 被动回复消息，Marshal 的 toUserName 为粉丝 openid，fromUserName 为公众号原始ID
+type Reply = util.Reply
+
+// RawReply 已序列化的明文XML回复
+type RawReply = util.RawReply
+
+// handler 解析明文XML并调用处理函数
+type handler func(ctx context.Context, raw []byte) (Reply, error)
+
+// Server 公众号消息与事件回调服务，支持明文、兼容与安全模式
+// doc https://developers.weixin.qq.com/doc/offiaccount/Basic_Information/Access_Overview.html
+type Server struct {
+	token    string
+	crypt    *util.WXBizMsgCrypt
+	handlers map[string]handler
+	fallback func(ctx context.Context, push *Push) (Reply, error)
+	cache    cachego.Cache
+	locker   util.Locker
+	guard    *util.CallbackGuard
+}
+
+// Server 创建回调服务，使用 Config.Token 与 Config.AesKey 校验、解密推送
+func (a *app) Server() *Server {
+	s := &Server{
+		token:    a.config.Token,
+		handlers: map[string]handler{},
+		cache:    a.config.Cache,
+		locker:   a.config.Locker,
+	}
+	if a.config.AesKey != "" {
+		// 第三方平台代收消息时以第三方平台 appid 加密
+		receiverId := a.config.AppId
+		if a.config.component() && a.config.ComponentAppid != "" {
+			receiverId = a.config.ComponentAppid
+		}
+		s.crypt = util.NewWXBizMsgCrypt(receiverId, a.config.Token, a.config.AesKey)
+	}
+	return s
+}
+
+func on[T any](s *Server, key string, h func(ctx context.Context, msg *T) (Reply, error)) *Server {
+	s.handlers[key] = func(ctx context.Context, raw []byte) (Reply, error) {
+		msg := new(T)
+		if err := xml.Unmarshal(raw, msg); err != nil {
+			return nil, err
+		}
+		return h(ctx, msg)
+	}
+	return s
+}
+
+// OnText 文本消息
+func (s *Server) OnText(h func(ctx context.Context, msg *TextMessage) (Reply, error)) *Server {
+	return on(s, MsgTypeText, h)
+}
+
+// OnImage 图片消息
+func (s *Server) OnImage(h func(ctx context.Context, msg *ImageMessage) (Reply, error)) *Server {
+	return on(s, MsgTypeImage, h)
+}
+
+// OnVoice 语音消息
+func (s *Server) OnVoice(h func(ctx context.Context, msg *VoiceMessage) (Reply, error)) *Server {
+	return on(s, MsgTypeVoice, h)
+}
+
+// OnSubscribe 关注事件
+func (s *Server) OnSubscribe(h func(ctx context.Context, event *SubscribeEvent) (Reply, error)) *Server {
+	return on(s, MsgTypeEvent+":"+EventSubscribe, h)
+}
+
+// OnUnsubscribe 取消关注事件
+func (s *Server) OnUnsubscribe(h func(ctx context.Context, event *UnsubscribeEvent) (Reply, error)) *Server {
+	return on(s, MsgTypeEvent+":"+EventUnsubscribe, h)
+}
+
+// OnScan 已关注用户扫码事件
+func (s *Server) OnScan(h func(ctx context.Context, event *ScanEvent) (Reply, error)) *Server {
+	return on(s, MsgTypeEvent+":"+EventScan, h)
+}
+
+// OnClick 点击菜单拉取消息事件
+func (s *Server) OnClick(h func(ctx context.Context, event *ClickEvent) (Reply, error)) *Server {
+	return on(s, MsgTypeEvent+":"+EventClick, h)
+}
+
+// OnView 点击菜单跳转链接事件
+func (s *Server) OnView(h func(ctx context.Context, event *ViewEvent) (Reply, error)) *Server {
+	return on(s, MsgTypeEvent+":"+EventView, h)
+}
+
+// OnTemplateSendJobFinish 模板消息发送结果事件
+func (s *Server) OnTemplateSendJobFinish(h func(ctx context.Context, event *TemplateSendJobFinishEvent) (Reply, error)) *Server {
+	return on(s, MsgTypeEvent+":"+EventTemplateSendJobFinish, h)
+}
+
+// Guard 启用重放保护与去重，拒绝时间戳超出 window 的推送，window 内重复的推送直接回复 success
+// 使用 Config.Cache 记录已处理的推送，配置 Config.Locker 时跨实例加锁去重，window 为 0 时使用 util.DefaultCallbackWindow
+func (s *Server) Guard(window time.Duration) *Server {
+	s.guard = util.NewCallbackGuard(s.cache, s.locker, window)
+	return s
+}
+
+// OnDefault 未注册类型的消息与事件
+func (s *Server) OnDefault(h func(ctx context.Context, push *Push) (Reply, error)) *Server {
+	s.fallback = h
+	return s
+}
+
+// ServeHTTP GET 请求校验服务器地址，POST 请求解密推送并分发，处理函数的返回值作为被动回复
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	timestamp, nonce := query.Get("timestamp"), query.Get("nonce")
+	switch r.Method {
+	case http.MethodGet:
+		if !s.verify(query.Get("signature"), timestamp, nonce) {
+			http.Error(w, "invalid signature", http.StatusForbidden)
+			return
+		}
+		_, _ = io.WriteString(w, query.Get("echostr"))
+	case http.MethodPost:
+		if s.guard != nil {
+			if err := s.guard.Fresh(timestamp); err != nil {
+				http.Error(w, err.Error(), http.StatusForbidden)
+				return
+			}
+		}
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxPushSize))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		encrypted := query.Get("encrypt_type") == "aes"
+		var plaintext []byte
+		if encrypted {
+			if s.crypt == nil {
+				http.Error(w, "aes key not configured", http.StatusInternalServerError)
+				return
+			}
+			var cryptErr *util.CryptError
+			if plaintext, _, cryptErr = s.crypt.DecryptMsg(query.Get("msg_signature"), timestamp, nonce, body); cryptErr != nil {
+				http.Error(w, cryptErr.ErrMsg, http.StatusForbidden)
+				return
+			}
+		} else {
+			if !s.verify(query.Get("signature"), timestamp, nonce) {
+				http.Error(w, "invalid signature", http.StatusForbidden)
+				return
+			}
+			plaintext = body
+		}
+		push, reply, err := s.dispatch(r.Context(), plaintext)
+		if err != nil {
+			logger.Errorf("oa push: %v", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if reply == nil {
+			_, _ = io.WriteString(w, "success")
+			return
+		}
+		var out []byte
+		if encrypted {
+			out, err = util.EncryptReply(s.crypt, reply, push.FromUserName, push.ToUserName, timestamp, nonce)
+		} else {
+			out, err = reply.Marshal(push.FromUserName, push.ToUserName, time.Now().Unix())
+		}
+		if err != nil {
+			logger.Errorf("oa reply: %v", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+		_, _ = w.Write(out)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// verify 明文模式签名校验
+func (s *Server) verify(signature, timestamp, nonce string) bool {
+	return signature != "" && util.SortSha1(s.token, timestamp, nonce) == signature
+}
+
+// dispatch 解析推送类型并调用处理函数，无回复或重复推送时 Reply 为 nil
+func (s *Server) dispatch(ctx context.Context, plaintext []byte) (*Push, Reply, error) {
+	push := &Push{Raw: plaintext}
+	if err := xml.Unmarshal(plaintext, push); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse push: %w", err)
+	}
+	if s.guard == nil {
+		reply, err := s.handle(ctx, push)
+		return push, reply, err
+	}
+	id := push.id()
+	if err := s.guard.Accept(ctx, util.PlatformOA, id); err != nil {
+		if errors.Is(err, util.ErrDuplicateCallback) {
+			return push, nil, nil
+		}
+		return nil, nil, err
+	}
+	reply, err := s.handle(ctx, push)
+	if err != nil {
+		s.guard.Release(util.PlatformOA, id)
+	}
+	return push, reply, err
+}
+
+// id 推送去重ID，消息使用 MsgId，事件使用 FromUserName 与 CreateTime
+func (p *Push) id() string {
+	if p.MsgId != 0 {
+		return strconv.FormatInt(p.MsgId, 10)
+	}
+	return p.FromUserName + ":" + strconv.FormatInt(p.CreateTime, 10)
+}
+
+// handle 调用推送类型对应的处理函数
+func (s *Server) handle(ctx context.Context, push *Push) (Reply, error) {
+	key := push.MsgType
+	if push.MsgType == MsgTypeEvent {
+		key += ":" + push.Event
+	}
+	if h, ok := s.handlers[key]; ok {
+		return h(ctx, push.Raw)
+	}
+	if s.fallback != nil {
+		return s.fallback(ctx, push)
+	}
+	return nil, nil
+}
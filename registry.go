@@ -0,0 +1,250 @@
+package tpp
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/faabiosr/cachego"
+	"github.com/leapig/tpp/ap"
+	"github.com/leapig/tpp/dt"
+	"github.com/leapig/tpp/fs"
+	"github.com/leapig/tpp/mp"
+	"github.com/leapig/tpp/oa"
+	"github.com/leapig/tpp/util"
+	"github.com/leapig/tpp/wk"
+	"github.com/leapig/tpp/wo"
+	"github.com/leapig/tpp/ww"
+)
+
+// Shared 注册表内实例共享的依赖，实例 Config 中对应字段为空时使用
+type Shared struct {
+	// Cache 令牌缓存
+	Cache cachego.Cache
+	// HTTPClient 请求客户端，共享连接池，实例配置了 HTTPClient、Transport 或 Proxy 时不使用
+	HTTPClient *http.Client
+	// Locker 多实例部署时的令牌刷新锁
+	Locker util.Locker
+}
+
+// Registry 多租户实例注册表，按平台与 Config.Key 管理实例，支持并发查找与运行时替换
+type Registry struct {
+	shared Shared
+	mu     sync.RWMutex
+	apps   map[string]map[string]interface{}
+}
+
+// NewRegistry 创建注册表，shared 为各实例共享的缓存、请求客户端与刷新锁
+func NewRegistry(shared Shared) *Registry {
+	return &Registry{
+		shared: shared,
+		apps:   map[string]map[string]interface{}{},
+	}
+}
+
+// closer 配置 Refresher 的实例，替换或移除时停止后台预刷新
+type closer interface {
+	Close()
+}
+
+func register[T any](r *Registry, platform, key string, app func() T) (T, error) {
+	var zero T
+	if key == "" {
+		return zero, &util.Error{Platform: platform, ErrMsg: "empty config key", Kind: util.ErrInvalidParam}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.apps[platform] == nil {
+		r.apps[platform] = map[string]interface{}{}
+	}
+	// 先停止旧实例的预刷新，新实例使用相同凭证时重新注册
+	if old, ok := r.apps[platform][key].(closer); ok {
+		old.Close()
+	}
+	instance := app()
+	r.apps[platform][key] = instance
+	return instance, nil
+}
+
+func lookup[T any](r *Registry, platform, key string) (T, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if app, ok := r.apps[platform][key].(T); ok {
+		return app, nil
+	}
+	var zero T
+	return zero, &util.Error{Platform: platform, ErrMsg: "app not registered: " + key, Kind: util.ErrNotFound}
+}
+
+// Remove 移除 platform 平台 key 对应的实例并停止其后台预刷新，实例不存在时返回 false
+func (r *Registry) Remove(platform, key string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	app, ok := r.apps[platform][key]
+	if !ok {
+		return false
+	}
+	if c, ok := app.(closer); ok {
+		c.Close()
+	}
+	delete(r.apps[platform], key)
+	return true
+}
+
+// Keys 获取 platform 平台已注册实例的 Key，按字典序排列
+func (r *Registry) Keys(platform string) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	keys := make([]string, 0, len(r.apps[platform]))
+	for key := range r.apps[platform] {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// RegisterWW 注册企业微信实例，已存在相同 Key 的实例时替换
+func (r *Registry) RegisterWW(config ww.Config) (ww.App, error) {
+	if config.Cache == nil {
+		config.Cache = r.shared.Cache
+	}
+	if config.HTTPClient == nil && config.Transport == nil && config.Proxy == "" {
+		config.HTTPClient = r.shared.HTTPClient
+	}
+	if config.Locker == nil {
+		config.Locker = r.shared.Locker
+	}
+	return register(r, util.PlatformWW, config.Key, func() ww.App { return ww.NewApp(config) })
+}
+
+// WW 获取企业微信实例，未注册时返回 ErrNotFound
+func (r *Registry) WW(key string) (ww.App, error) {
+	return lookup[ww.App](r, util.PlatformWW, key)
+}
+
+// RegisterMP 注册微信小程序实例，已存在相同 Key 的实例时替换
+func (r *Registry) RegisterMP(config mp.Config) (mp.App, error) {
+	if config.Cache == nil {
+		config.Cache = r.shared.Cache
+	}
+	if config.HTTPClient == nil && config.Transport == nil && config.Proxy == "" {
+		config.HTTPClient = r.shared.HTTPClient
+	}
+	if config.Locker == nil {
+		config.Locker = r.shared.Locker
+	}
+	return register(r, util.PlatformMP, config.Key, func() mp.App { return mp.NewApp(config) })
+}
+
+// MP 获取微信小程序实例，未注册时返回 ErrNotFound
+func (r *Registry) MP(key string) (mp.App, error) {
+	return lookup[mp.App](r, util.PlatformMP, key)
+}
+
+// RegisterOA 注册微信公众号实例，已存在相同 Key 的实例时替换
+func (r *Registry) RegisterOA(config oa.Config) (oa.App, error) {
+	if config.Cache == nil {
+		config.Cache = r.shared.Cache
+	}
+	if config.HTTPClient == nil && config.Transport == nil && config.Proxy == "" {
+		config.HTTPClient = r.shared.HTTPClient
+	}
+	if config.Locker == nil {
+		config.Locker = r.shared.Locker
+	}
+	return register(r, util.PlatformOA, config.Key, func() oa.App { return oa.NewApp(config) })
+}
+
+// OA 获取微信公众号实例，未注册时返回 ErrNotFound
+func (r *Registry) OA(key string) (oa.App, error) {
+	return lookup[oa.App](r, util.PlatformOA, key)
+}
+
+// RegisterWK 注册腾讯微卡实例，已存在相同 Key 的实例时替换
+func (r *Registry) RegisterWK(config wk.Config) (wk.App, error) {
+	if config.Cache == nil {
+		config.Cache = r.shared.Cache
+	}
+	if config.HTTPClient == nil && config.Transport == nil && config.Proxy == "" {
+		config.HTTPClient = r.shared.HTTPClient
+	}
+	if config.Locker == nil {
+		config.Locker = r.shared.Locker
+	}
+	return register(r, util.PlatformWK, config.Key, func() wk.App { return wk.NewApp(config) })
+}
+
+// WK 获取腾讯微卡实例，未注册时返回 ErrNotFound
+func (r *Registry) WK(key string) (wk.App, error) {
+	return lookup[wk.App](r, util.PlatformWK, key)
+}
+
+// RegisterWO 注册微信开放平台实例，已存在相同 Key 的实例时替换
+func (r *Registry) RegisterWO(config wo.Config) (wo.App, error) {
+	if config.Cache == nil {
+		config.Cache = r.shared.Cache
+	}
+	if config.HTTPClient == nil && config.Transport == nil && config.Proxy == "" {
+		config.HTTPClient = r.shared.HTTPClient
+	}
+	if config.Locker == nil {
+		config.Locker = r.shared.Locker
+	}
+	return register(r, util.PlatformWO, config.Key, func() wo.App { return wo.NewApp(config) })
+}
+
+// WO 获取微信开放平台实例，未注册时返回 ErrNotFound
+func (r *Registry) WO(key string) (wo.App, error) {
+	return lookup[wo.App](r, util.PlatformWO, key)
+}
+
+// RegisterAP 注册支付宝实例，已存在相同 Key 的实例时替换，支付宝实例不缓存令牌，仅共享 HTTPClient
+func (r *Registry) RegisterAP(config ap.Config) (ap.App, error) {
+	if config.HTTPClient == nil && config.Transport == nil && config.Proxy == "" {
+		config.HTTPClient = r.shared.HTTPClient
+	}
+	return register(r, util.PlatformAP, config.Key, func() ap.App { return ap.NewApp(config) })
+}
+
+// AP 获取支付宝实例，未注册时返回 ErrNotFound
+func (r *Registry) AP(key string) (ap.App, error) {
+	return lookup[ap.App](r, util.PlatformAP, key)
+}
+
+// RegisterDT 注册钉钉实例，已存在相同 Key 的实例时替换
+func (r *Registry) RegisterDT(config dt.Config) (dt.App, error) {
+	if config.Cache == nil {
+		config.Cache = r.shared.Cache
+	}
+	if config.HTTPClient == nil && config.Transport == nil && config.Proxy == "" {
+		config.HTTPClient = r.shared.HTTPClient
+	}
+	if config.Locker == nil {
+		config.Locker = r.shared.Locker
+	}
+	return register(r, util.PlatformDT, config.Key, func() dt.App { return dt.NewApp(config) })
+}
+
+// DT 获取钉钉实例，未注册时返回 ErrNotFound
+func (r *Registry) DT(key string) (dt.App, error) {
+	return lookup[dt.App](r, util.PlatformDT, key)
+}
+
+// RegisterFS 注册飞书实例，已存在相同 Key 的实例时替换
+func (r *Registry) RegisterFS(config fs.Config) (fs.App, error) {
+	if config.Cache == nil {
+		config.Cache = r.shared.Cache
+	}
+	if config.HTTPClient == nil && config.Transport == nil && config.Proxy == "" {
+		config.HTTPClient = r.shared.HTTPClient
+	}
+	if config.Locker == nil {
+		config.Locker = r.shared.Locker
+	}
+	return register(r, util.PlatformFS, config.Key, func() fs.App { return fs.NewApp(config) })
+}
+
+// FS 获取飞书实例，未注册时返回 ErrNotFound
+func (r *Registry) FS(key string) (fs.App, error) {
+	return lookup[fs.App](r, util.PlatformFS, key)
+}
@@ -0,0 +1,157 @@
+package tpp
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/faabiosr/cachego/sync"
+	"github.com/leapig/tpp/ap"
+	"github.com/leapig/tpp/oa"
+	"github.com/leapig/tpp/util"
+)
+
+// countingTransport 记录请求次数并返回固定的令牌响应，expiresIn 为空时有效期 7200 秒
+type countingTransport struct {
+	calls     int32
+	expiresIn string
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	atomic.AddInt32(&t.calls, 1)
+	expiresIn := t.expiresIn
+	if expiresIn == "" {
+		expiresIn = "7200"
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(`{"access_token":"AT","expires_in":` + expiresIn + `}`)),
+		Request:    req,
+	}, nil
+}
+
+func TestRegistrySharedHTTPClient(t *testing.T) {
+	shared := &countingTransport{}
+	own := &countingTransport{}
+	r := NewRegistry(Shared{Cache: sync.New(), HTTPClient: &http.Client{Transport: shared}})
+	tests := []struct {
+		name   string
+		config oa.Config
+		want   *countingTransport
+	}{
+		{"shared", oa.Config{Key: "shared", AppId: "wx1", Secret: "s"}, shared},
+		{"own transport", oa.Config{Key: "own", AppId: "wx2", Secret: "s", Transport: own}, own},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, err := r.RegisterOA(tt.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			before := atomic.LoadInt32(&tt.want.calls)
+			if _, err = app.Token(); err != nil {
+				t.Fatal(err)
+			}
+			if atomic.LoadInt32(&tt.want.calls) != before+1 {
+				t.Errorf("request did not use the expected transport")
+			}
+		})
+	}
+	if shared.calls != 1 || own.calls != 1 {
+		t.Errorf("shared calls = %d, own calls = %d, want 1 and 1", shared.calls, own.calls)
+	}
+}
+
+func TestRegistryLookup(t *testing.T) {
+	r := NewRegistry(Shared{Cache: sync.New()})
+	if _, err := r.RegisterOA(oa.Config{AppId: "wx1"}); !errors.Is(err, ErrInvalidParam) {
+		t.Fatalf("RegisterOA() without key error = %v", err)
+	}
+	if _, err := r.RegisterOA(oa.Config{Key: "a", AppId: "wx1"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.RegisterOA(oa.Config{Key: "a", AppId: "wx2"}); err != nil {
+		t.Fatal(err)
+	}
+	app, err := r.OA("a")
+	if err != nil || app.Id() != "wx2" {
+		t.Fatalf("OA() = %v, %v, want replaced instance", app, err)
+	}
+	if _, err = r.MP("a"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("MP() error = %v, want ErrNotFound", err)
+	}
+	if !r.Remove(util.PlatformOA, "a") || r.Remove(util.PlatformOA, "a") {
+		t.Error("Remove() should succeed once")
+	}
+	if keys := r.Keys(util.PlatformOA); len(keys) != 0 {
+		t.Errorf("Keys() = %v, want empty", keys)
+	}
+}
+
+func TestRegistrySharedHTTPClientAP(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shared := &countingTransport{}
+	r := NewRegistry(Shared{HTTPClient: &http.Client{Transport: shared}})
+	app, err := r.RegisterAP(ap.Config{Key: "a", AppId: "2021", PrivateKey: base64.StdEncoding.EncodeToString(x509.MarshalPKCS1PrivateKey(key))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 响应不是支付宝格式，只校验请求经过共享客户端
+	_, _ = app.SystemOauthToken("c1")
+	if shared.calls != 1 {
+		t.Errorf("shared calls = %d, want 1", shared.calls)
+	}
+}
+
+func TestRegistryStopsRefresh(t *testing.T) {
+	tests := []struct {
+		name string
+		stop func(r *Registry) error
+	}{
+		{"remove", func(r *Registry) error {
+			r.Remove(util.PlatformOA, "a")
+			return nil
+		}},
+		{"replace", func(r *Registry) error {
+			_, err := r.RegisterOA(oa.Config{Key: "a", AppId: "wx2", Secret: "s"})
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := &countingTransport{expiresIn: "1"}
+			refresher := util.NewRefresher(0.5)
+			defer refresher.Stop()
+			r := NewRegistry(Shared{Cache: sync.New(), HTTPClient: &http.Client{Transport: transport}})
+			if _, err := r.RegisterOA(oa.Config{Key: "a", AppId: "wx1", Secret: "s", Refresher: refresher}); err != nil {
+				t.Fatal(err)
+			}
+			deadline := time.Now().Add(2 * time.Second)
+			for atomic.LoadInt32(&transport.calls) == 0 && time.Now().Before(deadline) {
+				time.Sleep(10 * time.Millisecond)
+			}
+			if err := tt.stop(r); err != nil {
+				t.Fatal(err)
+			}
+			// 等待进行中的刷新结束
+			time.Sleep(50 * time.Millisecond)
+			before := atomic.LoadInt32(&transport.calls)
+			time.Sleep(1500 * time.Millisecond)
+			if after := atomic.LoadInt32(&transport.calls); before == 0 || after != before {
+				t.Errorf("refresh calls before = %d, after = %d, want no refresh after stop", before, after)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package util
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/faabiosr/cachego"
+)
+
+// CacheKey 生成平台命名空间下的缓存键，凭证经 SHA-256 摘要，缓存中不出现明文密钥
+func CacheKey(platform string, credentials ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(credentials, "\x00")))
+	return platform + ":" + hex.EncodeToString(sum[:16])
+}
+
+// ErrDecrypt 缓存值解密失败（密钥不匹配或数据被篡改）
+var ErrDecrypt = errors.New("tpp: unable to decrypt cached value")
+
+// encryptedCache 以 AES-GCM 加密缓存值，缓存键作为附加数据，值无法被挪用到其他键
+type encryptedCache struct {
+	cache cachego.Cache
+	aead  cipher.AEAD
+}
+
+// NewEncryptedCache 包装任意 cachego.Cache，写入前加密、读取后解密
+// key 为 16、24 或 32 字节，分别对应 AES-128、AES-192、AES-256
+func NewEncryptedCache(cache cachego.Cache, key []byte) (cachego.Cache, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &encryptedCache{cache: cache, aead: aead}, nil
+}
+
+func (c *encryptedCache) Contains(key string) bool {
+	return c.cache.Contains(key)
+}
+
+func (c *encryptedCache) Delete(key string) error {
+	return c.cache.Delete(key)
+}
+
+func (c *encryptedCache) Fetch(key string) (string, error) {
+	value, err := c.cache.Fetch(key)
+	if err != nil || value == "" {
+		return value, err
+	}
+	return c.open(key, value)
+}
+
+func (c *encryptedCache) FetchMulti(keys []string) map[string]string {
+	result := make(map[string]string)
+	for key, value := range c.cache.FetchMulti(keys) {
+		if plain, err := c.open(key, value); err == nil {
+			result[key] = plain
+		}
+	}
+	return result
+}
+
+func (c *encryptedCache) Flush() error {
+	return c.cache.Flush()
+}
+
+func (c *encryptedCache) Save(key string, value string, lifeTime time.Duration) error {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return err
+	}
+	sealed := c.aead.Seal(nonce, nonce, []byte(value), []byte(key))
+	return c.cache.Save(key, base64.StdEncoding.EncodeToString(sealed), lifeTime)
+}
+
+func (c *encryptedCache) open(key, value string) (string, error) {
+	sealed, err := base64.StdEncoding.DecodeString(value)
+	if err != nil || len(sealed) < c.aead.NonceSize() {
+		return "", ErrDecrypt
+	}
+	nonce, ciphertext := sealed[:c.aead.NonceSize()], sealed[c.aead.NonceSize():]
+	plain, err := c.aead.Open(nil, nonce, ciphertext, []byte(key))
+	if err != nil {
+		return "", ErrDecrypt
+	}
+	return string(plain), nil
+}
@@ -0,0 +1,74 @@
+package util
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/faabiosr/cachego"
+	"github.com/faabiosr/cachego/sync"
+)
+
+func TestEncryptedCache(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	tests := []struct {
+		name   string
+		tamper func(backend cachego.Cache)
+		want   string
+		err    error
+	}{
+		{"round trip", func(cachego.Cache) {}, "secret-token", nil},
+		{"tampered ciphertext", func(backend cachego.Cache) {
+			stored, _ := backend.Fetch("k")
+			sealed, _ := base64.StdEncoding.DecodeString(stored)
+			sealed[len(sealed)-1] ^= 1
+			_ = backend.Save("k", base64.StdEncoding.EncodeToString(sealed), time.Minute)
+		}, "", ErrDecrypt},
+		{"moved to another key", func(backend cachego.Cache) {
+			stored, _ := backend.Fetch("other")
+			_ = backend.Save("k", stored, time.Minute)
+		}, "", ErrDecrypt},
+		{"not base64", func(backend cachego.Cache) {
+			_ = backend.Save("k", "%%%", time.Minute)
+		}, "", ErrDecrypt},
+		{"too short", func(backend cachego.Cache) {
+			_ = backend.Save("k", base64.StdEncoding.EncodeToString([]byte("short")), time.Minute)
+		}, "", ErrDecrypt},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := sync.New()
+			c, err := NewEncryptedCache(backend, key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = c.Save("k", "secret-token", time.Minute); err != nil {
+				t.Fatal(err)
+			}
+			_ = c.Save("other", "other-token", time.Minute)
+			if stored, _ := backend.Fetch("k"); strings.Contains(stored, "secret-token") {
+				t.Fatalf("backend stores plaintext %q", stored)
+			}
+			tt.tamper(backend)
+			got, err := c.Fetch("k")
+			if !errors.Is(err, tt.err) || got != tt.want {
+				t.Errorf("Fetch() = %q, %v, want %q, %v", got, err, tt.want, tt.err)
+			}
+		})
+	}
+}
+
+func TestEncryptedCacheWrongKey(t *testing.T) {
+	backend := sync.New()
+	a, _ := NewEncryptedCache(backend, []byte("0123456789abcdef"))
+	b, _ := NewEncryptedCache(backend, []byte("fedcba9876543210"))
+	_ = a.Save("k", "v", time.Minute)
+	if _, err := b.Fetch("k"); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("Fetch() error = %v, want ErrDecrypt", err)
+	}
+	if _, err := NewEncryptedCache(backend, []byte("short")); err == nil {
+		t.Error("NewEncryptedCache() with invalid key length succeeded")
+	}
+}
@@ -0,0 +1,125 @@
+package util
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/faabiosr/cachego"
+)
+
+// DefaultCallbackWindow 回调时间戳允许偏差与去重记录保留时间
+const DefaultCallbackWindow = 5 * time.Minute
+
+// callbackLockTTL 去重记录检查与写入期间的锁最长持有时间
+const callbackLockTTL = 10 * time.Second
+
+var (
+	// ErrStaleCallback 回调时间戳超出允许偏差
+	ErrStaleCallback = errors.New("tpp: stale callback timestamp")
+	// ErrDuplicateCallback 回调已处理（平台重试）
+	ErrDuplicateCallback = errors.New("tpp: duplicate callback")
+)
+
+// CallbackGuard 回调重放保护与去重，平台在超时或未收到成功响应时会重复推送
+type CallbackGuard struct {
+	cache  cachego.Cache
+	locker Locker
+	window time.Duration
+	mu     sync.Mutex
+	keys   map[string]*keyLock
+}
+
+// keyLock 同一推送的进程内锁，refs 为持有与等待的调用方数量，归零时删除
+type keyLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+// NewCallbackGuard 基于 cachego.Cache 记录已处理的推送，window 为时间戳允许偏差与去重记录保留时间，默认 DefaultCallbackWindow
+// 进程内同一推送的检查与写入互斥执行，不同推送互不阻塞；多实例部署时需使用共享缓存，并配置 locker 使各实例对同一推送串行检查，
+// 未配置 locker 时 cachego.Cache 不支持原子写入，跨实例去重仅为尽力而为
+func NewCallbackGuard(cache cachego.Cache, locker Locker, window time.Duration) *CallbackGuard {
+	if window <= 0 {
+		window = DefaultCallbackWindow
+	}
+	return &CallbackGuard{cache: cache, locker: locker, window: window, keys: map[string]*keyLock{}}
+}
+
+// Fresh 校验时间戳（秒或毫秒），为空或与当前时间相差超过 window 时返回 ErrStaleCallback
+func (g *CallbackGuard) Fresh(timestamp string) error {
+	n, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrStaleCallback
+	}
+	t := time.Unix(n, 0)
+	if n > 1e12 {
+		t = time.UnixMilli(n)
+	}
+	if d := time.Since(t); d > g.window || d < -g.window {
+		return ErrStaleCallback
+	}
+	return nil
+}
+
+func (g *CallbackGuard) key(platform, id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return "callback:" + platform + ":" + hex.EncodeToString(sum[:16])
+}
+
+// Accept 记录推送ID，window 内重复出现时返回 ErrDuplicateCallback
+func (g *CallbackGuard) Accept(ctx context.Context, platform, id string) error {
+	key := g.key(platform, id)
+	defer g.lock(key)()
+	if g.locker != nil {
+		unlock, err := g.locker.Lock(ctx, "lock:"+key, callbackLockTTL)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
+	if g.cache.Contains(key) {
+		return ErrDuplicateCallback
+	}
+	return g.cache.Save(key, "1", g.window)
+}
+
+// lock 获取 key 对应的进程内锁，返回的函数用于释放
+func (g *CallbackGuard) lock(key string) func() {
+	g.mu.Lock()
+	l, ok := g.keys[key]
+	if !ok {
+		l = &keyLock{}
+		g.keys[key] = l
+	}
+	l.refs++
+	g.mu.Unlock()
+
+	l.mu.Lock()
+	return func() {
+		l.mu.Unlock()
+		g.mu.Lock()
+		if l.refs--; l.refs == 0 {
+			delete(g.keys, key)
+		}
+		g.mu.Unlock()
+	}
+}
+
+// Release 删除推送记录，处理失败时调用以便平台重试
+func (g *CallbackGuard) Release(platform, id string) {
+	_ = g.cache.Delete(g.key(platform, id))
+}
+
+// CallbackId 拼接推送ID，各部分均为空时使用推送内容
+func CallbackId(payload []byte, parts ...string) string {
+	if strings.Join(parts, "") == "" {
+		return string(payload)
+	}
+	return strings.Join(parts, ":")
+}
@@ -0,0 +1,163 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	gosync "sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/faabiosr/cachego/sync"
+)
+
+func TestCallbackGuardFresh(t *testing.T) {
+	g := NewCallbackGuard(sync.New(), nil, time.Minute)
+	now := time.Now()
+	tests := []struct {
+		name      string
+		timestamp string
+		want      error
+	}{
+		{"empty", "", ErrStaleCallback},
+		{"now", strconv.FormatInt(now.Unix(), 10), nil},
+		{"inside window", strconv.FormatInt(now.Add(-time.Minute+5*time.Second).Unix(), 10), nil},
+		{"past window", strconv.FormatInt(now.Add(-time.Minute-5*time.Second).Unix(), 10), ErrStaleCallback},
+		{"future window", strconv.FormatInt(now.Add(time.Minute+5*time.Second).Unix(), 10), ErrStaleCallback},
+		{"milliseconds", strconv.FormatInt(now.UnixMilli(), 10), nil},
+		{"stale milliseconds", strconv.FormatInt(now.Add(-2*time.Minute).UnixMilli(), 10), ErrStaleCallback},
+		{"not a number", "abc", ErrStaleCallback},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := g.Fresh(tt.timestamp); !errors.Is(err, tt.want) {
+				t.Errorf("Fresh(%q) = %v, want %v", tt.timestamp, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCallbackGuardAccept(t *testing.T) {
+	ctx := context.Background()
+	g := NewCallbackGuard(sync.New(), nil, time.Minute)
+	steps := []struct {
+		name     string
+		platform string
+		id       string
+		release  bool
+		want     error
+	}{
+		{"first", PlatformOA, "1", false, nil},
+		{"duplicate", PlatformOA, "1", false, ErrDuplicateCallback},
+		{"other platform", PlatformWW, "1", false, nil},
+		{"other id", PlatformOA, "2", true, nil},
+		{"after release", PlatformOA, "2", false, nil},
+	}
+	for _, step := range steps {
+		if err := g.Accept(ctx, step.platform, step.id); !errors.Is(err, step.want) {
+			t.Fatalf("%s: Accept() = %v, want %v", step.name, err, step.want)
+		}
+		if step.release {
+			g.Release(step.platform, step.id)
+		}
+	}
+}
+
+func TestCallbackGuardAcceptConcurrent(t *testing.T) {
+	tests := []struct {
+		name   string
+		locker Locker
+	}{
+		{"in process", nil},
+		{"file locker", NewFileLocker(t.TempDir())},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := sync.New()
+			guards := []*CallbackGuard{NewCallbackGuard(cache, tt.locker, time.Minute)}
+			if tt.locker != nil {
+				// 两个 Guard 共享缓存与锁，模拟多实例
+				guards = append(guards, NewCallbackGuard(cache, tt.locker, time.Minute))
+			}
+			var accepted int32
+			var wg gosync.WaitGroup
+			for i := 0; i < 20; i++ {
+				wg.Add(1)
+				go func(g *CallbackGuard) {
+					defer wg.Done()
+					if g.Accept(context.Background(), PlatformDT, "event") == nil {
+						atomic.AddInt32(&accepted, 1)
+					}
+				}(guards[i%len(guards)])
+			}
+			wg.Wait()
+			if accepted != 1 {
+				t.Errorf("accepted = %d, want 1", accepted)
+			}
+		})
+	}
+}
+
+// blockingLocker 获取 block 对应的锁时阻塞至 release 关闭
+type blockingLocker struct {
+	block   string
+	locked  chan struct{}
+	release chan struct{}
+}
+
+func (l *blockingLocker) Lock(ctx context.Context, key string, ttl time.Duration) (func(), error) {
+	if key == l.block {
+		close(l.locked)
+		<-l.release
+	}
+	return func() {}, nil
+}
+
+func TestCallbackGuardAcceptPerKey(t *testing.T) {
+	g := NewCallbackGuard(sync.New(), nil, time.Minute)
+	l := &blockingLocker{block: "lock:" + g.key(PlatformDT, "slow"), locked: make(chan struct{}), release: make(chan struct{})}
+	g.locker = l
+	done := make(chan error, 1)
+	go func() {
+		done <- g.Accept(context.Background(), PlatformDT, "slow")
+	}()
+	<-l.locked
+	// 其他推送不等待阻塞中的锁
+	accepted := make(chan error, 1)
+	go func() {
+		accepted <- g.Accept(context.Background(), PlatformOA, "fast")
+	}()
+	select {
+	case err := <-accepted:
+		if err != nil {
+			t.Fatalf("Accept(fast) = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Accept(fast) blocked by another key")
+	}
+	close(l.release)
+	if err := <-done; err != nil {
+		t.Fatalf("Accept(slow) = %v", err)
+	}
+	if len(g.keys) != 0 {
+		t.Errorf("keys = %d, want 0", len(g.keys))
+	}
+}
+
+func TestCallbackId(t *testing.T) {
+	tests := []struct {
+		payload string
+		parts   []string
+		want    string
+	}{
+		{"raw", nil, "raw"},
+		{"raw", []string{"", ""}, "raw"},
+		{"raw", []string{"a", "1"}, "a:1"},
+	}
+	for _, tt := range tests {
+		if got := CallbackId([]byte(tt.payload), tt.parts...); got != tt.want {
+			t.Errorf("CallbackId(%q, %v) = %q, want %q", tt.payload, tt.parts, got, tt.want)
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"crypto/cipher"
 	"crypto/sha1"
 	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/base64"
 	"encoding/binary"
 	"encoding/json"
@@ -128,6 +129,9 @@ func (self *WXBizMsgCrypt) pKCS7Unpadding(plaintext []byte, block_size int) ([]b
 		return nil, NewCryptError(DecryptAESError, "pKCS7Unpadding text not a multiple of the block size")
 	}
 	padding_len := int(plaintext[plaintext_len-1])
+	if padding_len < 1 || padding_len > block_size || !bytes.Equal(plaintext[plaintext_len-padding_len:], bytes.Repeat([]byte{byte(padding_len)}, padding_len)) {
+		return nil, NewCryptError(DecryptAESError, "pKCS7Unpadding invalid padding")
+	}
 	return plaintext[:plaintext_len-padding_len], nil
 }
 
@@ -216,7 +220,7 @@ func (self *WXBizMsgCrypt) ParsePlainText(plaintext []byte) ([]byte, uint32, []b
 	}
 	random := plaintext[:16]
 	msg_len := binary.BigEndian.Uint32(plaintext[16:20])
-	if text_len < (20 + msg_len) {
+	if msg_len > text_len-20 {
 		return nil, 0, nil, nil, NewCryptError(IllegalBuffer, "plain is to small 2")
 	}
 
@@ -244,7 +248,6 @@ func (self *WXBizMsgCrypt) VerifyURL(msg_signature, timestamp, nonce, echostr st
 	}
 
 	if len(self.receiver_id) > 0 && strings.Compare(string(receiver_id), self.receiver_id) != 0 {
-		fmt.Println(string(receiver_id), self.receiver_id, len(receiver_id), len(self.receiver_id))
 		return nil, string(receiver_id), NewCryptError(ValidateCorpidError, "receiver_id is not equil")
 	}
 
@@ -310,9 +313,8 @@ type LarkCrypto struct {
 	Block          cipher.Block
 }
 
-func NewLarkCrypto() *LarkCrypto {
-	token := "91BxDcWOdJ1PUBngaOkgsH9B"
-	encodingAESKey := "yOocubSvv527UCbHOL2TkTPygLUcbBAgSaZgtKvZlAs"
+// NewLarkCrypto 飞书事件订阅解密，token 为 Verification Token，encodingAESKey 为 Encrypt Key
+func NewLarkCrypto(token, encodingAESKey string) *LarkCrypto {
 	c := &LarkCrypto{
 		Token:          token,
 		EncodingAESKey: encodingAESKey,
@@ -320,16 +322,35 @@ func NewLarkCrypto() *LarkCrypto {
 	return c
 }
 
+// GetDecryptMsg 校验签名并解密事件，签名为空时返回错误
 func (self *LarkCrypto) GetDecryptMsg(signature, timestamp, nonce string, msg []byte) (string, error) {
-	// 签名验证
-	if signature != "" {
-		if strings.Compare(signature, self.callSignature(timestamp, nonce, string(msg))) != 0 {
-			return "", errors.New("signature not equal")
-		}
-	}
-	var msgJson map[string]interface{}
-	_ = json.Unmarshal(msg, &msgJson)
-	buf, err := base64.StdEncoding.DecodeString(msgJson["encrypt"].(string))
+	if err := self.VerifySignature(signature, timestamp, nonce, msg); err != nil {
+		return "", err
+	}
+	return self.Decrypt(msg)
+}
+
+// VerifySignature 校验 X-Lark-Signature，签名为空或不一致时返回错误
+// url_verification 请求不带签名，需在解密后单独处理
+func (self *LarkCrypto) VerifySignature(signature, timestamp, nonce string, msg []byte) error {
+	if signature == "" {
+		return errors.New("missing signature")
+	}
+	if subtle.ConstantTimeCompare([]byte(signature), []byte(self.callSignature(timestamp, nonce, string(msg)))) != 1 {
+		return errors.New("signature not equal")
+	}
+	return nil
+}
+
+// Decrypt 解密 {"encrypt":"..."} 格式的事件，不校验签名
+func (self *LarkCrypto) Decrypt(msg []byte) (string, error) {
+	var msgJson struct {
+		Encrypt string `json:"encrypt"`
+	}
+	if err := json.Unmarshal(msg, &msgJson); err != nil || msgJson.Encrypt == "" {
+		return "", errors.New("missing encrypt")
+	}
+	buf, err := base64.StdEncoding.DecodeString(msgJson.Encrypt)
 	if err != nil {
 		return "", fmt.Errorf("base64StdEncode Error[%v]", err)
 	}
@@ -343,25 +364,27 @@ func (self *LarkCrypto) GetDecryptMsg(signature, timestamp, nonce string, msg []
 	}
 	iv := buf[:aes.BlockSize]
 	buf = buf[aes.BlockSize:]
-	if len(buf)%aes.BlockSize != 0 {
+	if len(buf) == 0 || len(buf)%aes.BlockSize != 0 {
 		return "", errors.New("ciphertext is not a multiple of the block size")
 	}
 	mode := cipher.NewCBCDecrypter(block, iv)
 	mode.CryptBlocks(buf, buf)
+	// 去除 PKCS#7 补位，补位不合法时说明密钥不匹配或密文被篡改
+	n := int(buf[len(buf)-1])
+	if n < 1 || n > aes.BlockSize || !bytes.Equal(buf[len(buf)-n:], bytes.Repeat([]byte{byte(n)}, n)) {
+		return "", errors.New("invalid padding")
+	}
+	buf = buf[:len(buf)-n]
 	// token验证
 	i := strings.Index(string(buf), self.Token)
 	if i == -1 {
 		return "", errors.New("token not equal")
 	}
-	n := strings.Index(string(buf), "{")
-	if n == -1 {
-		n = 0
+	plaintext := string(buf)
+	if n, m := strings.Index(plaintext, "{"), strings.LastIndex(plaintext, "}"); n != -1 && m > n {
+		plaintext = plaintext[n : m+1]
 	}
-	m := strings.LastIndex(string(buf), "}")
-	if m == -1 {
-		m = len(buf) - 1
-	}
-	return string(buf[n : m+1]), nil
+	return plaintext, nil
 }
 
 func (self *LarkCrypto) callSignature(timestamp, nonce, msg string) string {
@@ -501,3 +524,21 @@ func GetRandStringWithCharset(length int, charset string) string {
 func GetRandString(length int) string {
 	return GetRandStringWithCharset(length, charset)
 }
+
+// SortSha1 字典序排序后拼接并计算 SHA1，用于微信明文模式的 signature 校验
+func SortSha1(s ...string) string {
+	sorted := append([]string(nil), s...)
+	sort.Strings(sorted)
+	sum := sha1.Sum([]byte(strings.Join(sorted, "")))
+	return fmt.Sprintf("%x", sum)
+}
+
+// JsSdkSignature 生成 JS-SDK 配置签名，url 为当前网页完整地址，不包含 # 及其后面部分
+// 微信、企业微信、钉钉、飞书均使用 jsapi_ticket、noncestr、timestamp、url 拼接后的 SHA1
+func JsSdkSignature(ticket, nonceStr, timestamp, url string) string {
+	if i := strings.IndexByte(url, '#'); i >= 0 {
+		url = url[:i]
+	}
+	sum := sha1.Sum([]byte("jsapi_ticket=" + ticket + "&noncestr=" + nonceStr + "&timestamp=" + timestamp + "&url=" + url))
+	return fmt.Sprintf("%x", sum)
+}
@@ -0,0 +1,223 @@
+package util
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+)
+
+// 企业微信官方示例
+const (
+	wxToken      = "QDG6eK"
+	wxReceiverId = "wx5823bf96d3bd56c7"
+	wxAesKey     = "jWmYm7qr5nMoAUwZRjGtBxmz3KA1tkAj3ykkR6q2B2C"
+	wxEchoStr    = "P9nAzCzyDtyTWESHep1vC5X9xho/qYX3Zpb4yKa9SKld1DsH3Iyt3tP3zNdtp+4RPcs8TgAE7OaBO+FZXvnaqQ=="
+)
+
+func TestWXBizMsgCryptVerifyURL(t *testing.T) {
+	tests := []struct {
+		name       string
+		receiverId string
+		signature  string
+		echoStr    string
+		want       string
+		errCode    int
+	}{
+		{"official sample", wxReceiverId, "5c45ff5e21c57e6ad56bac8758b79b1d9ac89fd3", wxEchoStr, "1616140317555161061", 0},
+		{"wrong signature", wxReceiverId, flip("5c45ff5e21c57e6ad56bac8758b79b1d9ac89fd3"), wxEchoStr, "", ValidateSignatureError},
+		{"empty signature", wxReceiverId, "", wxEchoStr, "", ValidateSignatureError},
+		{"wrong receiver", "wx0000000000000000", "5c45ff5e21c57e6ad56bac8758b79b1d9ac89fd3", wxEchoStr, "", ValidateCorpidError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewWXBizMsgCrypt(tt.receiverId, wxToken, wxAesKey)
+			got, _, cryptErr := c.VerifyURL(tt.signature, "1409659589", "263014780", tt.echoStr)
+			if cryptErr != nil {
+				if cryptErr.ErrCode != tt.errCode {
+					t.Fatalf("VerifyURL() error = %+v, want code %d", cryptErr, tt.errCode)
+				}
+				return
+			}
+			if tt.errCode != 0 || string(got) != tt.want {
+				t.Errorf("VerifyURL() = %q, want %q, code %d", got, tt.want, tt.errCode)
+			}
+		})
+	}
+}
+
+// flip 修改首个字符
+func flip(s string) string {
+	if s[0] == '0' {
+		return "1" + s[1:]
+	}
+	return "0" + s[1:]
+}
+
+func TestWXBizMsgCryptRoundTrip(t *testing.T) {
+	c := NewWXBizMsgCrypt(wxReceiverId, wxToken, wxAesKey)
+	reply := "<xml><Content><![CDATA[你好]]></Content></xml>"
+	out, cryptErr := c.EncryptMsg(reply, "1409659813", "1372623149")
+	if cryptErr != nil {
+		t.Fatalf("EncryptMsg() error = %+v", cryptErr)
+	}
+	var sent WXBizMsg4Send
+	if err := xml.Unmarshal(out, &sent); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name      string
+		signature string
+		post      string
+		errCode   int
+	}{
+		{"valid", sent.Signature.Value, string(out), 0},
+		{"wrong signature", flip(sent.Signature.Value), string(out), ValidateSignatureError},
+		{"tampered ciphertext", sent.Signature.Value, "<xml><Encrypt><![CDATA[" + flip(sent.Encrypt.Value) + "]]></Encrypt></xml>", ValidateSignatureError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, cryptErr := c.DecryptMsg(tt.signature, "1409659813", "1372623149", []byte(tt.post))
+			if cryptErr != nil {
+				if cryptErr.ErrCode != tt.errCode {
+					t.Fatalf("DecryptMsg() error = %+v, want code %d", cryptErr, tt.errCode)
+				}
+				return
+			}
+			if tt.errCode != 0 || string(got) != reply {
+				t.Errorf("DecryptMsg() = %q, want %q, code %d", got, reply, tt.errCode)
+			}
+		})
+	}
+}
+
+func TestWXBizMsgCryptParsePlainText(t *testing.T) {
+	c := NewWXBizMsgCrypt(wxReceiverId, wxToken, wxAesKey)
+	// pad 补齐到 32 字节的倍数
+	pad := func(b []byte) []byte {
+		n := 32 - len(b)%32
+		return append(b, bytes.Repeat([]byte{byte(n)}, n)...)
+	}
+	valid := pad(append([]byte("0123456789abcdef\x00\x00\x00\x02hi"), wxReceiverId...))
+	tests := []struct {
+		name      string
+		plaintext []byte
+		errCode   int
+	}{
+		{"valid", valid, 0},
+		{"empty", nil, DecryptAESError},
+		{"not block size", valid[:31], DecryptAESError},
+		{"padding larger than text", append(bytes.Repeat([]byte{0}, 31), 0xff), DecryptAESError},
+		{"zero padding", make([]byte, 32), DecryptAESError},
+		{"inconsistent padding", append(bytes.Repeat([]byte{0}, 30), 1, 2), DecryptAESError},
+		{"message length overflow", pad([]byte("0123456789abcdef\xff\xff\xff\xffhi")), IllegalBuffer},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, msg, receiverId, cryptErr := c.ParsePlainText(tt.plaintext)
+			if cryptErr != nil {
+				if cryptErr.ErrCode != tt.errCode {
+					t.Fatalf("ParsePlainText() error = %+v, want code %d", cryptErr, tt.errCode)
+				}
+				return
+			}
+			if tt.errCode != 0 || string(msg) != "hi" || string(receiverId) != wxReceiverId {
+				t.Errorf("ParsePlainText() = %q, %q, want code %d", msg, receiverId, tt.errCode)
+			}
+		})
+	}
+}
+
+// 钉钉官方示例
+const (
+	dtToken     = "123456"
+	dtAesKey    = "4g5j64qlyl3zvetqxz5jiocdr586fn2zvjpa8zls3ij"
+	dtSuiteKey  = "suite4xxxxxxxxxxxxxxx"
+	dtTimestamp = "1445827045067"
+	dtNonce     = "nEXhMP4r"
+	dtEncrypt   = "1a3NBxmCFwkCJvfoQ7WhJHB+iX3qHPsc9JbaDznE1i03peOk1LaOQoRz3+nlyGNhwmwJ3vDMG+OzrHMeiZI7gTRWVdUBmfxjZ8Ej23JVYa9VrYeJ5as7XM/ZpulX8NEQis44w53h1qAgnC3PRzM7Zc/D6Ibr0rgUathB6zRHP8PYrfgnNOS9PhSBdHlegK+AGGanfwjXuQ9+0pZcy0w9lQ=="
+	dtSignature = "5a65ceeef9aab2d149439f82dc191dd6c5cbe2c0"
+)
+
+func TestDingTalkCrypto(t *testing.T) {
+	tests := []struct {
+		name      string
+		suiteKey  string
+		signature string
+		want      string
+		wantErr   bool
+	}{
+		{"official sample", dtSuiteKey, dtSignature, `{"EventType":"check_create_suite_url","Random":"LPIdSnlF","TestSuiteKey":"suite4xxxxxxxxxxxxxxx"}`, false},
+		{"wrong signature", dtSuiteKey, flip(dtSignature), "", true},
+		{"empty signature", dtSuiteKey, "", "", true},
+		{"wrong suite key", "suite4yyyyyyyyyyyyyyy", dtSignature, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewDingTalkCrypto(dtToken, dtAesKey, tt.suiteKey)
+			got, err := c.GetDecryptMsg(tt.signature, dtTimestamp, dtNonce, dtEncrypt)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("GetDecryptMsg() = %q, %v, want %q, wantErr %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDingTalkCryptoRoundTrip(t *testing.T) {
+	c := NewDingTalkCrypto(dtToken, dtAesKey, dtSuiteKey)
+	encrypted, signature, err := c.GetEncryptMsg("success", dtTimestamp, dtNonce)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := c.GetDecryptMsg(signature, dtTimestamp, dtNonce, encrypted); err != nil || got != "success" {
+		t.Errorf("GetDecryptMsg() = %q, %v, want success", got, err)
+	}
+}
+
+// 飞书官方示例
+func TestLarkCrypto(t *testing.T) {
+	tests := []struct {
+		name    string
+		token   string
+		key     string
+		body    string
+		want    string
+		wantErr bool
+	}{
+		{"official sample", "", "test key", `{"encrypt":"P37w+VZImNgPEO1RBhJ6RtKl7n6zymIbEG1pReEzghk="}`, "hello world", false},
+		{"wrong key", "", "other key", `{"encrypt":"P37w+VZImNgPEO1RBhJ6RtKl7n6zymIbEG1pReEzghk="}`, "", true},
+		{"token not in plaintext", "vtoken", "test key", `{"encrypt":"P37w+VZImNgPEO1RBhJ6RtKl7n6zymIbEG1pReEzghk="}`, "", true},
+		{"iv only", "", "test key", `{"encrypt":"AAAAAAAAAAAAAAAAAAAAAA=="}`, "", true},
+		{"missing encrypt", "", "test key", `{"type":"url_verification"}`, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewLarkCrypto(tt.token, tt.key).Decrypt([]byte(tt.body))
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("Decrypt() = %q, %v, want %q, wantErr %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLarkCryptoVerifySignature(t *testing.T) {
+	c := NewLarkCrypto("", "test key")
+	body := []byte(`{"encrypt":"P37w+VZImNgPEO1RBhJ6RtKl7n6zymIbEG1pReEzghk="}`)
+	valid := c.callSignature("1", "n", string(body))
+	tests := []struct {
+		name      string
+		signature string
+		timestamp string
+		wantErr   bool
+	}{
+		{"valid", valid, "1", false},
+		{"empty", "", "1", true},
+		{"other timestamp", valid, "2", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := c.VerifySignature(tt.signature, tt.timestamp, "n", body); (err != nil) != tt.wantErr {
+				t.Errorf("VerifySignature() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	json2 "github.com/bitly/go-simplejson"
+)
+
+// 平台标识
+const (
+	PlatformWW = "ww"
+	PlatformMP = "mp"
+	PlatformOA = "oa"
+	PlatformWK = "wk"
+	PlatformWO = "wo"
+	PlatformAP = "ap"
+	PlatformDT = "dt"
+	PlatformFS = "fs"
+)
+
+// 常见错误分类，配合 errors.Is 使用
+var (
+	ErrInvalidToken     = errors.New("tpp: invalid access token")
+	ErrRateLimited      = errors.New("tpp: rate limited")
+	ErrPermissionDenied = errors.New("tpp: permission denied")
+	ErrNotFound         = errors.New("tpp: not found")
+	ErrInvalidParam     = errors.New("tpp: invalid parameter")
+)
+
+// Error 平台接口调用错误
+type Error struct {
+	// Platform 平台标识
+	Platform string
+	// StatusCode HTTP状态码，请求未发出时为0
+	StatusCode int
+	// ErrCode 平台错误码（微信 errcode、飞书 code、钉钉 errcode）
+	ErrCode int
+	// ErrMsg 平台错误信息（微信 errmsg、飞书 msg、钉钉 errmsg/message）
+	ErrMsg string
+	// SubCode 平台子错误码（钉钉新版接口 code、支付宝 sub_code）
+	SubCode string
+	// RequestId 请求ID（微信 rid、飞书 log_id、钉钉 request_id）
+	RequestId string
+	// Body 原始响应体
+	Body []byte
+	// Kind 错误分类
+	Kind error
+	// Err 底层错误（网络、解析等）
+	Err error
+}
+
+func (e *Error) Error() string {
+	var b strings.Builder
+	b.WriteString("tpp[" + e.Platform + "]:")
+	if e.StatusCode != 0 && e.StatusCode != http.StatusOK {
+		b.WriteString(" status=" + strconv.Itoa(e.StatusCode))
+	}
+	if e.ErrCode != 0 {
+		b.WriteString(" errcode=" + strconv.Itoa(e.ErrCode))
+	}
+	if e.SubCode != "" {
+		b.WriteString(" subcode=" + e.SubCode)
+	}
+	if e.ErrMsg != "" {
+		b.WriteString(" errmsg=" + e.ErrMsg)
+	}
+	if e.RequestId != "" {
+		b.WriteString(" request_id=" + e.RequestId)
+	}
+	if e.Err != nil {
+		b.WriteString(" " + e.Err.Error())
+	}
+	return b.String()
+}
+
+// Unwrap 返回底层错误
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is 判断错误分类
+func (e *Error) Is(target error) bool {
+	return e.Kind != nil && e.Kind == target
+}
+
+// NewError 构造网络、解析等非业务错误
+func NewError(platform string, err error) *Error {
+	return &Error{Platform: platform, Err: err}
+}
+
+// Errorf 构造带格式化信息的非业务错误
+func Errorf(platform string, format string, args ...interface{}) *Error {
+	return &Error{Platform: platform, Err: fmt.Errorf(format, args...)}
+}
+
+var ridPattern = regexp.MustCompile(`rid:\s*([0-9a-zA-Z-]+)`)
+
+// CheckResponse 校验平台响应，业务错误码非0或HTTP状态异常时返回*Error
+// kinds 为平台错误码到错误分类的映射
+func CheckResponse(platform string, kinds map[int]error, response *http.Response, body []byte) (*json2.Json, error) {
+	e := &Error{
+		Platform:   platform,
+		StatusCode: response.StatusCode,
+		Body:       body,
+		RequestId:  requestIdFromHeader(response.Header),
+	}
+	js, err := json2.NewJson(body)
+	if err != nil {
+		if response.StatusCode >= http.StatusBadRequest {
+			e.Kind = statusKind(response.StatusCode)
+			return nil, e
+		}
+		e.Err = fmt.Errorf("failed to parse JSON response: %w", err)
+		return nil, e
+	}
+	if errCode, ok := js.CheckGet("errcode"); ok {
+		// 微信、企业微信、钉钉旧版接口、微卡
+		e.ErrCode = errCode.MustInt()
+		e.ErrMsg = js.Get("errmsg").MustString()
+		e.SubCode = js.Get("sub_code").MustString()
+		if rid := ridPattern.FindStringSubmatch(e.ErrMsg); len(rid) > 1 {
+			e.RequestId = rid[1]
+		}
+		if id := js.Get("request_id").MustString(); id != "" {
+			e.RequestId = id
+		}
+	} else if code, ok := js.CheckGet("code"); ok {
+		if n, err := code.Int(); err == nil {
+			// 飞书
+			e.ErrCode = n
+			e.ErrMsg = js.Get("msg").MustString()
+			if id := js.GetPath("error", "log_id").MustString(); id != "" {
+				e.RequestId = id
+			}
+		} else {
+			// 钉钉新版接口
+			e.SubCode = code.MustString()
+			e.ErrMsg = js.Get("message").MustString()
+			if id := js.Get("requestid").MustString(); id != "" {
+				e.RequestId = id
+			}
+		}
+	}
+	if e.ErrCode != 0 {
+		e.Kind = kinds[e.ErrCode]
+		if e.Kind == nil {
+			e.Kind = statusKind(response.StatusCode)
+		}
+		return js, e
+	}
+	if response.StatusCode >= http.StatusBadRequest {
+		e.Kind = statusKind(response.StatusCode)
+		return js, e
+	}
+	return js, nil
+}
+
+func requestIdFromHeader(header http.Header) string {
+	for _, key := range []string{"X-Tt-Logid", "X-Request-Id", "X-Acs-Request-Id"} {
+		if id := header.Get(key); id != "" {
+			return id
+		}
+	}
+	return ""
+}
+
+func statusKind(status int) error {
+	switch status {
+	case http.StatusUnauthorized:
+		return ErrInvalidToken
+	case http.StatusForbidden:
+		return ErrPermissionDenied
+	case http.StatusNotFound:
+		return ErrNotFound
+	case http.StatusTooManyRequests:
+		return ErrRateLimited
+	case http.StatusBadRequest:
+		return ErrInvalidParam
+	}
+	return nil
+}
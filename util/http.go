@@ -0,0 +1,71 @@
+package util
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	defaultTransport     *http.Transport
+	defaultTransportOnce sync.Once
+)
+
+// DefaultTransport 共享的连接池 Transport
+func DefaultTransport() *http.Transport {
+	defaultTransportOnce.Do(func() {
+		defaultTransport = &http.Transport{
+			Proxy:                 http.ProxyFromEnvironment,
+			MaxIdleConns:          100,              // 最大空闲连接数
+			MaxIdleConnsPerHost:   10,               // 每个主机的最大空闲连接
+			IdleConnTimeout:       90 * time.Second, // 空闲连接保持时间
+			TLSHandshakeTimeout:   10 * time.Second,
+			ExpectContinueTimeout: 1 * time.Second,
+		}
+	})
+	return defaultTransport
+}
+
+// DefaultTimeout 默认请求总超时时间
+const DefaultTimeout = 30 * time.Second
+
+// NewHTTPClient 根据配置构造 http.Client
+// client 非空时直接使用，忽略 transport 与 proxy；
+// transport 为空时使用共享连接池；proxy 为出口代理地址（如 http://10.0.0.1:3128），
+// 仅对 *http.Transport 生效，地址无效时请求返回解析错误
+func NewHTTPClient(client *http.Client, transport http.RoundTripper, proxy string) *http.Client {
+	if client != nil {
+		return client
+	}
+	if transport == nil {
+		transport = DefaultTransport()
+	}
+	if proxy != "" {
+		if t, ok := transport.(*http.Transport); ok {
+			t = t.Clone()
+			u, err := url.Parse(proxy)
+			if err != nil {
+				t.Proxy = func(*http.Request) (*url.URL, error) {
+					return nil, err
+				}
+			} else {
+				t.Proxy = http.ProxyURL(u)
+			}
+			transport = t
+		}
+	}
+	return &http.Client{
+		Transport: transport,
+		Timeout:   DefaultTimeout,
+	}
+}
+
+// BaseURL 返回去除末尾斜杠的服务地址，未配置时使用默认值
+func BaseURL(baseURL, def string) string {
+	if baseURL == "" {
+		return def
+	}
+	return strings.TrimRight(baseURL, "/")
+}
@@ -0,0 +1,118 @@
+package util
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Locker 分布式锁，多实例部署时保证同一令牌仅由一个实例刷新，可基于 Redis 等实现
+type Locker interface {
+	// Lock 阻塞直至获取 key 对应的锁或 ctx 结束，ttl 为锁最长持有时间，超时后视为失效
+	// 返回的 unlock 用于释放锁
+	Lock(ctx context.Context, key string, ttl time.Duration) (unlock func(), err error)
+}
+
+// lockPollInterval 获取锁失败时的重试间隔
+const lockPollInterval = 50 * time.Millisecond
+
+type fileLocker struct {
+	dir string
+}
+
+// NewFileLocker 基于文件的锁，适用于共享同一目录（本机或网络存储）的多个实例
+func NewFileLocker(dir string) Locker {
+	return &fileLocker{dir: dir}
+}
+
+func (l *fileLocker) Lock(ctx context.Context, key string, ttl time.Duration) (func(), error) {
+	sum := sha256.Sum256([]byte(key))
+	path := filepath.Join(l.dir, "tpp-"+hex.EncodeToString(sum[:])+".lock")
+	owner, err := lockToken()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		err := createLockFile(path, owner)
+		if err == nil {
+			// 仅删除自己持有的锁，锁过期后被其他实例接管时不影响新的持有者
+			return func() {
+				if data, err := os.ReadFile(path); err == nil && string(data) == owner {
+					_ = os.Remove(path)
+				}
+			}, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+		// 持有者异常退出时锁文件残留，超过 ttl 后清除
+		if ttl > 0 {
+			if err = removeStaleLock(path, ttl); err != nil {
+				return nil, err
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(lockPollInterval):
+		}
+	}
+}
+
+// lockToken 生成锁持有者标识
+func lockToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// createLockFile 创建锁文件并写入持有者标识，文件已存在时返回 os.ErrExist
+func createLockFile(path, owner string) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = f.WriteString(owner)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = os.Remove(path)
+	}
+	return err
+}
+
+// removeStaleLock 清除超过 ttl 的锁文件
+// 先将锁文件重命名到唯一路径再核对持有者标识，若期间锁已被其他实例清除并重新获取，则恢复该锁而不删除
+func removeStaleLock(path string, ttl time.Duration) error {
+	info, err := os.Stat(path)
+	if err != nil || time.Since(info.ModTime()) <= ttl {
+		return nil
+	}
+	stale, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	suffix, err := lockToken()
+	if err != nil {
+		return err
+	}
+	aside := path + "." + suffix
+	if err = os.Rename(path, aside); err != nil {
+		return nil
+	}
+	if data, err := os.ReadFile(aside); err == nil && string(data) != string(stale) {
+		// 移走的是新持有者的锁，不覆盖已存在的锁文件
+		_ = os.Link(aside, path)
+	}
+	_ = os.Remove(aside)
+	return nil
+}
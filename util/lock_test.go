@@ -0,0 +1,105 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	gosync "sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFileLockerExclusive(t *testing.T) {
+	l := NewFileLocker(t.TempDir())
+	var holders, peak int32
+	var wg gosync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			unlock, err := l.Lock(context.Background(), "k", time.Minute)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			n := atomic.AddInt32(&holders, 1)
+			for {
+				m := atomic.LoadInt32(&peak)
+				if n <= m || atomic.CompareAndSwapInt32(&peak, m, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			atomic.AddInt32(&holders, -1)
+			unlock()
+		}()
+	}
+	wg.Wait()
+	if peak != 1 {
+		t.Errorf("max concurrent holders = %d, want 1", peak)
+	}
+}
+
+func TestFileLockerContext(t *testing.T) {
+	l := NewFileLocker(t.TempDir())
+	unlock, err := l.Lock(context.Background(), "k", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unlock()
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err = l.Lock(ctx, "k", time.Minute); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Lock() error = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestFileLockerStale(t *testing.T) {
+	dir := t.TempDir()
+	l := NewFileLocker(dir)
+	ctx := context.Background()
+	staleUnlock, err := l.Lock(ctx, "k", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths, _ := filepath.Glob(filepath.Join(dir, "*.lock"))
+	if len(paths) != 1 {
+		t.Fatalf("lock files = %v", paths)
+	}
+	old := time.Now().Add(-time.Minute)
+	if err = os.Chtimes(paths[0], old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	unlock, err := l.Lock(ctx, "k", time.Second)
+	if err != nil {
+		t.Fatalf("Lock() over stale lock error = %v", err)
+	}
+	// 过期的持有者释放锁时不能删除新持有者的锁
+	staleUnlock()
+	if _, err = os.Stat(paths[0]); err != nil {
+		t.Fatalf("lock file removed by stale owner: %v", err)
+	}
+	unlock()
+	if _, err = os.Stat(paths[0]); !os.IsNotExist(err) {
+		t.Errorf("lock file not removed by owner: %v", err)
+	}
+	if rest, _ := filepath.Glob(filepath.Join(dir, "*")); len(rest) != 0 {
+		t.Errorf("leftover files = %v", rest)
+	}
+}
+
+func TestRemoveStaleLockKeepsFreshLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tpp-k.lock")
+	if err := createLockFile(path, "fresh"); err != nil {
+		t.Fatal(err)
+	}
+	if err := removeStaleLock(path, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(path); err != nil || string(data) != "fresh" {
+		t.Errorf("lock file = %q, %v, want fresh", data, err)
+	}
+}
@@ -0,0 +1,97 @@
+package util
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/leapig/tpp/logger"
+)
+
+// DefaultRefreshFraction 默认在有效期的 80% 处预刷新
+const DefaultRefreshFraction = 0.8
+
+// refreshRetryInterval 预刷新失败后的重试间隔
+const refreshRetryInterval = 30 * time.Second
+
+// Refresher 在令牌、票据到期前后台预刷新，避免到期后首个请求承担刷新耗时
+type Refresher struct {
+	fraction float64
+	ctx      context.Context
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+	mu       sync.Mutex
+	keys     map[string]context.CancelFunc
+}
+
+// NewRefresher fraction 为预刷新时机占有效期的比例，取值 (0,1)，否则使用 DefaultRefreshFraction
+func NewRefresher(fraction float64) *Refresher {
+	if fraction <= 0 || fraction >= 1 {
+		fraction = DefaultRefreshFraction
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Refresher{
+		fraction: fraction,
+		ctx:      ctx,
+		cancel:   cancel,
+		keys:     map[string]context.CancelFunc{},
+	}
+}
+
+// Add 注册令牌并开始后台预刷新，同一令牌重复注册或 Stop 后注册无效
+func (r *Refresher) Add(token AccessToken) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.keys[token.key()]; ok || r.ctx.Err() != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(r.ctx)
+	r.keys[token.key()] = cancel
+	r.wg.Add(1)
+	go r.run(ctx, token)
+}
+
+// Remove 停止令牌的后台预刷新，未注册的令牌忽略，移除后可重新 Add
+func (r *Refresher) Remove(tokens ...AccessToken) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, token := range tokens {
+		if cancel, ok := r.keys[token.key()]; ok {
+			cancel()
+			delete(r.keys, token.key())
+		}
+	}
+}
+
+// Stop 停止所有后台预刷新并等待退出
+func (r *Refresher) Stop() {
+	r.mu.Lock()
+	r.cancel()
+	r.mu.Unlock()
+	r.wg.Wait()
+}
+
+func (r *Refresher) run(ctx context.Context, token AccessToken) {
+	defer r.wg.Done()
+	for {
+		at, err := token.preRefresh(ctx, r.fraction)
+		if ctx.Err() != nil {
+			return
+		}
+		wait := time.Until(at)
+		if err != nil {
+			logger.Errorf("token pre-refresh failed: %v", err)
+			wait = refreshRetryInterval
+		} else if at.IsZero() {
+			// 令牌无有效期，无需预刷新
+			return
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package util
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/faabiosr/cachego/sync"
+)
+
+func TestRefresherRemove(t *testing.T) {
+	var calls int32
+	token := AccessToken{
+		Id:                    "refresher:remove",
+		Cache:                 sync.New(),
+		GetRefreshRequestFunc: countingRefresh(&calls, 0, `{"access_token":"T","expires_in":7200}`),
+	}
+	r := NewRefresher(0)
+	defer r.Stop()
+	r.Add(token)
+	r.Add(token)
+	deadline := time.Now().Add(time.Second)
+	for atomic.LoadInt32(&calls) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if calls != 1 {
+		t.Fatalf("refresh calls = %d, want 1", calls)
+	}
+
+	r.Remove(token)
+	// 移除后后台协程退出，不等待 Stop
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("pre-refresh goroutine still running after Remove")
+	}
+	r.Remove(token)
+
+	r.Add(token)
+	r.mu.Lock()
+	_, ok := r.keys[token.key()]
+	r.mu.Unlock()
+	if !ok {
+		t.Error("Add() after Remove did not register the token")
+	}
+}
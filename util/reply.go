@@ -0,0 +1,52 @@
+package util
+
+import (
+	"encoding/xml"
+	"fmt"
+	"time"
+)
+
+// Reply 微信被动回复消息，公众号与企业微信共用
+type Reply interface {
+	// Marshal 生成明文XML，toUserName 为推送的 FromUserName，fromUserName 为推送的 ToUserName
+	Marshal(toUserName, fromUserName string, createTime int64) ([]byte, error)
+}
+
+// RawReply 已序列化的明文XML回复
+type RawReply []byte
+
+func (r RawReply) Marshal(string, string, int64) ([]byte, error) {
+	return r, nil
+}
+
+// ReplyHeader 被动回复公共字段，嵌入各平台的回复消息结构
+type ReplyHeader struct {
+	XMLName      xml.Name `xml:"xml"`
+	ToUserName   CDATA    `xml:"ToUserName"`
+	FromUserName CDATA    `xml:"FromUserName"`
+	CreateTime   int64    `xml:"CreateTime"`
+	MsgType      CDATA    `xml:"MsgType"`
+}
+
+func NewReplyHeader(toUserName, fromUserName string, createTime int64, msgType string) ReplyHeader {
+	return ReplyHeader{
+		ToUserName:   CDATA{Value: toUserName},
+		FromUserName: CDATA{Value: fromUserName},
+		CreateTime:   createTime,
+		MsgType:      CDATA{Value: msgType},
+	}
+}
+
+// EncryptReply 生成被动回复并以 WXBizMsgCrypt 加密，返回 WXBizMsg4Send 格式的XML
+// 公众号 toUserName 为粉丝 openid，fromUserName 为公众号原始ID；企业微信 toUserName 为成员 UserID，fromUserName 为企业ID
+func EncryptReply(crypt *WXBizMsgCrypt, reply Reply, toUserName, fromUserName, timestamp, nonce string) ([]byte, error) {
+	plaintext, err := reply.Marshal(toUserName, fromUserName, time.Now().Unix())
+	if err != nil {
+		return nil, err
+	}
+	encrypted, cryptErr := crypt.EncryptMsg(string(plaintext), timestamp, nonce)
+	if cryptErr != nil {
+		return nil, fmt.Errorf("failed to encrypt reply: %s", cryptErr.ErrMsg)
+	}
+	return encrypted, nil
+}
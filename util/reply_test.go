@@ -0,0 +1,53 @@
+package util
+
+import (
+	"encoding/xml"
+	"testing"
+)
+
+// textReply 嵌入 ReplyHeader 的回复消息
+type textReply struct {
+	Content string
+}
+
+func (r textReply) Marshal(toUserName, fromUserName string, createTime int64) ([]byte, error) {
+	return xml.Marshal(struct {
+		ReplyHeader
+		Content CDATA `xml:"Content"`
+	}{NewReplyHeader(toUserName, fromUserName, createTime, "text"), CDATA{Value: r.Content}})
+}
+
+func TestReplyMarshal(t *testing.T) {
+	tests := []struct {
+		name  string
+		reply Reply
+		want  string
+	}{
+		{"header", textReply{Content: "hi"}, "<xml><ToUserName><![CDATA[to]]></ToUserName><FromUserName><![CDATA[from]]></FromUserName><CreateTime>1</CreateTime><MsgType><![CDATA[text]]></MsgType><Content><![CDATA[hi]]></Content></xml>"},
+		{"raw", RawReply("<xml>raw</xml>"), "<xml>raw</xml>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.reply.Marshal("to", "from", 1)
+			if err != nil || string(got) != tt.want {
+				t.Errorf("Marshal() = %s, %v, want %s", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncryptReply(t *testing.T) {
+	c := NewWXBizMsgCrypt(wxReceiverId, wxToken, wxAesKey)
+	out, err := EncryptReply(c, RawReply("<xml>raw</xml>"), "to", "from", "1409659813", "1372623149")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sent WXBizMsg4Send
+	if err = xml.Unmarshal(out, &sent); err != nil {
+		t.Fatal(err)
+	}
+	got, _, cryptErr := c.DecryptMsg(sent.Signature.Value, "1409659813", "1372623149", out)
+	if cryptErr != nil || string(got) != "<xml>raw</xml>" {
+		t.Errorf("DecryptMsg() = %q, %+v", got, cryptErr)
+	}
+}
@@ -0,0 +1,126 @@
+package util
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+
+	json2 "github.com/bitly/go-simplejson"
+	"github.com/leapig/tpp/logger"
+)
+
+// Client 平台接口请求客户端，统一处理网络错误与业务错误码
+type Client struct {
+	// Platform 平台标识
+	Platform string
+	// Kinds 平台错误码分类
+	Kinds map[int]error
+	// HttpClient 为空时使用 http.DefaultClient
+	HttpClient *http.Client
+}
+
+// Do 执行请求并解析JSON响应
+func (c *Client) Do(req *http.Request) (*json2.Json, error) {
+	js, _, err := c.do(req, false)
+	return js, err
+}
+
+// Fetch 执行请求并返回原始响应体，非JSON响应（如图片）不做解析直接返回
+func (c *Client) Fetch(req *http.Request) ([]byte, error) {
+	_, body, err := c.do(req, true)
+	return body, err
+}
+
+func (c *Client) do(req *http.Request, raw bool) (*json2.Json, []byte, error) {
+	client := c.HttpClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	logRequest(req)
+	response, err := client.Do(req)
+	if err != nil {
+		if logger.LogRequests() {
+			logger.Debugf("%s %s: %v", req.Method, logger.Redact(req.URL.String()), err)
+		}
+		return nil, nil, NewError(c.Platform, fmt.Errorf("failed to execute request: %w", err))
+	}
+	defer func() {
+		_ = response.Body.Close()
+	}()
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, nil, &Error{Platform: c.Platform, StatusCode: response.StatusCode, Err: fmt.Errorf("failed to read response body: %w", err)}
+	}
+	if raw && response.StatusCode < http.StatusBadRequest && !isJSON(response.Header) {
+		if logger.LogRequests() {
+			logger.Debugf("%s %s: %d, %d bytes", req.Method, logger.Redact(req.URL.String()), response.StatusCode, len(body))
+		}
+		return nil, body, nil
+	}
+	logResponse(req, response, body)
+	js, err := CheckResponse(c.Platform, c.Kinds, response, body)
+	return js, body, err
+}
+
+// logRequest 记录请求体，需开启 logger.SetLogBody
+func logRequest(req *http.Request) {
+	if !logger.LogBody() || req.GetBody == nil {
+		return
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return
+	}
+	defer func() {
+		_ = body.Close()
+	}()
+	if payload, err := io.ReadAll(body); err == nil && len(payload) > 0 {
+		logger.Debugf("%s %s request: %s", req.Method, logger.Redact(req.URL.String()), logger.Redact(string(payload)))
+	}
+}
+
+// logResponse 开启 logger.SetLogRequests 时记录请求地址与状态，开启 logger.SetLogBody 时记录响应内容，敏感字段均已脱敏
+func logResponse(req *http.Request, response *http.Response, body []byte) {
+	if !logger.LogRequests() {
+		return
+	}
+	if logger.LogBody() {
+		logger.Debugf("%s %s: %d %s", req.Method, logger.Redact(req.URL.String()), response.StatusCode, logger.Redact(string(body)))
+		return
+	}
+	logger.Debugf("%s %s: %d", req.Method, logger.Redact(req.URL.String()), response.StatusCode)
+}
+
+func isJSON(header http.Header) bool {
+	mediaType, _, _ := mime.ParseMediaType(header.Get("Content-Type"))
+	return mediaType == "application/json" || mediaType == "text/plain"
+}
+
+// ReadBody 读取请求体，便于令牌失效重试时重新构造请求
+func ReadBody(body io.Reader) ([]byte, error) {
+	if body == nil {
+		return nil, nil
+	}
+	return io.ReadAll(body)
+}
+
+// BodyReader 根据 ReadBody 的结果构造请求体，nil 表示无请求体
+func BodyReader(payload []byte) io.Reader {
+	if payload == nil {
+		return nil
+	}
+	return bytes.NewReader(payload)
+}
+
+// WithToken 复制查询参数并设置令牌，不修改原参数
+func WithToken(params url.Values, key, token string) url.Values {
+	query := url.Values{}
+	for k, v := range params {
+		query[k] = append([]string(nil), v...)
+	}
+	query.Set(key, token)
+	return query
+}
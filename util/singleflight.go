@@ -0,0 +1,72 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrRefreshTimeout 等待令牌刷新超时
+var ErrRefreshTimeout = errors.New("tpp: token refresh timed out")
+
+// DefaultRefreshTimeout 默认令牌刷新等待时间
+const DefaultRefreshTimeout = 30 * time.Second
+
+// flight 进行中的刷新
+type flight struct {
+	done  chan struct{}
+	value string
+	err   error
+}
+
+// flightGroup 按键合并并发刷新，相同键共享一次进行中的刷新，不同键互不阻塞
+type flightGroup struct {
+	mu      sync.Mutex
+	flights map[string]*flight
+}
+
+var refreshGroup = &flightGroup{flights: map[string]*flight{}}
+
+// Do 执行或等待 key 对应的刷新，最长等待 timeout
+// 刷新不随单个调用方的 ctx 取消而中止，调用方 ctx 取消或等待超时时直接返回
+func (g *flightGroup) Do(ctx context.Context, key string, timeout time.Duration, fn func(ctx context.Context) (string, error)) (string, error) {
+	if timeout <= 0 {
+		timeout = DefaultRefreshTimeout
+	}
+	g.mu.Lock()
+	f, ok := g.flights[key]
+	if !ok {
+		f = &flight{done: make(chan struct{})}
+		g.flights[key] = f
+		go g.run(ctx, key, timeout, f, fn)
+	}
+	g.mu.Unlock()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-f.done:
+		return f.value, f.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	case <-timer.C:
+		return "", ErrRefreshTimeout
+	}
+}
+
+func (g *flightGroup) run(ctx context.Context, key string, timeout time.Duration, f *flight, fn func(ctx context.Context) (string, error)) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+	defer cancel()
+	defer func() {
+		if r := recover(); r != nil {
+			f.err = fmt.Errorf("tpp: token refresh panic: %v", r)
+		}
+		g.mu.Lock()
+		delete(g.flights, key)
+		g.mu.Unlock()
+		close(f.done)
+	}()
+	f.value, f.err = fn(ctx)
+}
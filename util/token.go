@@ -1,45 +1,116 @@
 package util
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	json2 "github.com/bitly/go-simplejson"
 	"github.com/faabiosr/cachego"
 	"net/http"
 	"net/url"
-	"sync"
+	"strings"
 	"time"
 )
 
 const ContentType = "application/json;charset=utf-8"
 
-type getRefreshRequestFunc func() []byte
+type getRefreshRequestFunc func(ctx context.Context) ([]byte, error)
 
 type AccessToken struct {
 	Id                    string
 	Cache                 cachego.Cache
 	GetRefreshRequestFunc getRefreshRequestFunc
+	// Parse 解析刷新响应中的令牌与有效期，为空时自动识别
+	Parse func(resp []byte) (token string, expiresIn time.Duration)
+	// RefreshTimeout 刷新等待时间，默认 DefaultRefreshTimeout
+	RefreshTimeout time.Duration
+	// Locker 多实例间的刷新锁，为空时仅在进程内合并刷新
+	Locker Locker
 }
 
-var refreshAccessTokenLock sync.Mutex
+// ErrEmptyToken 刷新响应中未包含令牌
+var ErrEmptyToken = errors.New("tpp: empty access token in refresh response")
 
-func (a AccessToken) GetAccessToken() (token string) {
-	// 第一次检查缓存
-	token, _ = a.Cache.Fetch("access_token:" + a.Id)
-	if token != "" {
-		return
+func (a AccessToken) GetAccessToken() (string, error) {
+	return a.GetAccessTokenContext(context.Background())
+}
+
+// GetAccessTokenContext 获取令牌，同一令牌的并发刷新合并为一次，ctx 取消时停止等待
+func (a AccessToken) GetAccessTokenContext(ctx context.Context) (string, error) {
+	if token, _ := a.Cache.Fetch(a.key()); token != "" {
+		return token, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return "", err
 	}
+	return a.coordinate(ctx, func() (string, bool) {
+		token, _ := a.Cache.Fetch(a.key())
+		return token, token != ""
+	})
+}
+
+// coordinate 合并进程内的并发刷新，配置 Locker 时跨实例加锁
+// fresh 返回无需刷新时的令牌，等待合并或获取锁后再次检查，其他调用方或实例可能已完成刷新
+func (a AccessToken) coordinate(ctx context.Context, fresh func() (string, bool)) (string, error) {
+	key := a.key()
+	return refreshGroup.Do(ctx, key, a.refreshTimeout(), func(ctx context.Context) (string, error) {
+		if token, ok := fresh(); ok {
+			return token, nil
+		}
+		if a.Locker != nil {
+			unlock, err := a.Locker.Lock(ctx, "lock:"+key, a.refreshTimeout())
+			if err != nil {
+				return "", err
+			}
+			defer unlock()
+			if token, ok := fresh(); ok {
+				return token, nil
+			}
+		}
+		return a.refresh(ctx)
+	})
+}
+
+func (a AccessToken) key() string {
+	return "access_token:" + a.Id
+}
+
+// metaKey 记录令牌签发时间与有效期，供预刷新判断
+func (a AccessToken) metaKey() string {
+	return "access_token_meta:" + a.Id
+}
 
-	// 加锁
-	refreshAccessTokenLock.Lock()
-	defer refreshAccessTokenLock.Unlock()
+func (a AccessToken) refreshTimeout() time.Duration {
+	if a.RefreshTimeout > 0 {
+		return a.RefreshTimeout
+	}
+	return DefaultRefreshTimeout
+}
 
-	// 第二次检查缓存（双重检查锁定）
-	token, _ = a.Cache.Fetch("access_token:" + a.Id)
-	if token != "" {
-		return
+// refresh 请求新令牌并写入缓存
+func (a AccessToken) refresh(ctx context.Context) (string, error) {
+	resp, err := a.GetRefreshRequestFunc(ctx)
+	if err != nil {
+		return "", err
+	}
+	parse := a.Parse
+	if parse == nil {
+		parse = parseAccessToken
+	}
+	token, d := parse(resp)
+	if token == "" {
+		return "", ErrEmptyToken
+	}
+	_ = a.Cache.Save(a.key(), token, d)
+	if d > 0 {
+		_ = a.Cache.Save(a.metaKey(), fmt.Sprintf("%d:%d", time.Now().Unix(), int64(d/time.Second)), d)
 	}
+	return token, nil
+}
 
-	// 刷新令牌
-	resp := a.GetRefreshRequestFunc()
+// parseAccessToken 识别各平台刷新响应中的令牌与有效期
+func parseAccessToken(resp []byte) (token string, d time.Duration) {
 	var res struct {
 		AccessToken           string `json:"access_token"`
 		ComponentAccessToken  string `json:"component_access_token"`
@@ -48,6 +119,7 @@ func (a AccessToken) GetAccessToken() (token string) {
 		AccessLarkToken       string `json:"tenant_access_token"`
 		ExpiresIn             int    `json:"expires_in"`
 		Expire                int    `json:"expire"`
+		ExpireIn              int    `json:"expireIn"`
 	}
 	_ = json.Unmarshal(resp, &res)
 
@@ -63,25 +135,107 @@ func (a AccessToken) GetAccessToken() (token string) {
 		token = res.AccessDingToken
 	}
 
-	if token != "" {
-		var d time.Duration
-		if res.ExpiresIn > 0 {
-			d = time.Duration(res.ExpiresIn) * time.Second
-		} else if res.Expire > 0 {
-			d = time.Duration(res.Expire) * time.Second
+	if res.ExpiresIn > 0 {
+		d = time.Duration(res.ExpiresIn) * time.Second
+	} else if res.Expire > 0 {
+		d = time.Duration(res.Expire) * time.Second
+	} else if res.ExpireIn > 0 {
+		d = time.Duration(res.ExpireIn) * time.Second
+	}
+	return
+}
+
+// ParseJSON 按字段路径（以 . 分隔）解析刷新响应中的令牌与有效期（秒）
+func ParseJSON(tokenPath, expiresInPath string) func(resp []byte) (string, time.Duration) {
+	return func(resp []byte) (string, time.Duration) {
+		js, err := json2.NewJson(resp)
+		if err != nil {
+			return "", 0
 		}
-		_ = a.Cache.Save("access_token:"+a.Id, token, d)
+		token := js.GetPath(strings.Split(tokenPath, ".")...).MustString()
+		d := time.Duration(js.GetPath(strings.Split(expiresInPath, ".")...).MustInt()) * time.Second
+		return token, d
 	}
+}
 
-	return
+// refreshAt 返回令牌到达有效期 fraction 处的时间，缺少签发记录时返回 false
+func (a AccessToken) refreshAt(fraction float64) (time.Time, bool) {
+	meta, _ := a.Cache.Fetch(a.metaKey())
+	var issued, lifetime int64
+	if _, err := fmt.Sscanf(meta, "%d:%d", &issued, &lifetime); err != nil || lifetime <= 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(issued, 0).Add(time.Duration(float64(lifetime)*fraction) * time.Second), true
+}
+
+// preRefresh 令牌已过有效期 fraction 处时主动刷新，返回下次预刷新时间，令牌无有效期时返回零值
+func (a AccessToken) preRefresh(ctx context.Context, fraction float64) (time.Time, error) {
+	_, err := a.coordinate(ctx, func() (string, bool) {
+		token, _ := a.Cache.Fetch(a.key())
+		at, ok := a.refreshAt(fraction)
+		return token, token != "" && ok && time.Now().Before(at)
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+	at, _ := a.refreshAt(fraction)
+	return at, nil
+}
+
+// Invalidate 清除缓存中的令牌，仅当缓存值仍为 token 时生效，避免覆盖其他请求已刷新的令牌
+func (a AccessToken) Invalidate(token string) {
+	if cached, _ := a.Cache.Fetch(a.key()); cached == token {
+		_ = a.Cache.Delete(a.key())
+		_ = a.Cache.Delete(a.metaKey())
+	}
+}
+
+// Clear 清除缓存中的令牌，令牌所属账号不再可用时调用
+func (a AccessToken) Clear() {
+	_ = a.Cache.Delete(a.key())
+	_ = a.Cache.Delete(a.metaKey())
 }
 
-func (a AccessToken) ApplyAccessToken(url url.Values) url.Values {
-	url.Add("access_token", a.GetAccessToken())
-	return url
+// WithAccessToken 获取令牌后执行 fn，平台返回令牌失效（ErrInvalidToken）时清除缓存、刷新令牌并重试一次
+func (a AccessToken) WithAccessToken(ctx context.Context, fn func(token string) error) error {
+	token, err := a.GetAccessTokenContext(ctx)
+	if err != nil {
+		return err
+	}
+	if err = fn(token); !errors.Is(err, ErrInvalidToken) {
+		return err
+	}
+	a.Invalidate(token)
+	if token, err = a.GetAccessTokenContext(ctx); err != nil {
+		return err
+	}
+	return fn(token)
 }
 
-func (a AccessToken) SetLarkAccessToken(header http.Header) http.Header {
-	header.Set("Authorization", "Bearer "+a.GetAccessToken())
-	return header
+func (a AccessToken) ApplyAccessToken(url url.Values) (url.Values, error) {
+	return a.ApplyAccessTokenContext(context.Background(), url)
+}
+
+// ApplyAccessTokenContext 在查询参数中附加 access_token
+func (a AccessToken) ApplyAccessTokenContext(ctx context.Context, url url.Values) (url.Values, error) {
+	token, err := a.GetAccessTokenContext(ctx)
+	if err != nil {
+		return url, err
+	}
+	url.Add("access_token", token)
+	return url, nil
+}
+
+func (a AccessToken) SetLarkAccessToken(header http.Header) (http.Header, error) {
+	return a.SetLarkAccessTokenContext(context.Background(), header)
+}
+
+// SetLarkAccessTokenContext 在请求头中附加 Authorization: Bearer
+func (a AccessToken) SetLarkAccessTokenContext(ctx context.Context, header http.Header) (http.Header, error) {
+	token, err := a.GetAccessTokenContext(ctx)
+	if err != nil {
+		return header, err
+	}
+	header.Set("Authorization", "Bearer "+token)
+	return header, nil
 }
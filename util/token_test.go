@@ -0,0 +1,88 @@
+package util
+
+import (
+	"context"
+	"errors"
+	gosync "sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/faabiosr/cachego/sync"
+)
+
+// countingRefresh 记录刷新次数，每次刷新耗时 delay
+func countingRefresh(calls *int32, delay time.Duration, resp string) getRefreshRequestFunc {
+	return func(ctx context.Context) ([]byte, error) {
+		atomic.AddInt32(calls, 1)
+		time.Sleep(delay)
+		return []byte(resp), nil
+	}
+}
+
+func TestGetAccessTokenSingleflight(t *testing.T) {
+	tests := []struct {
+		name   string
+		locker func(t *testing.T) Locker
+	}{
+		{"in process", func(*testing.T) Locker { return nil }},
+		{"file locker", func(t *testing.T) Locker { return NewFileLocker(t.TempDir()) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32
+			token := AccessToken{
+				Id:                    "singleflight:" + tt.name,
+				Cache:                 sync.New(),
+				GetRefreshRequestFunc: countingRefresh(&calls, 50*time.Millisecond, `{"access_token":"T","expires_in":7200}`),
+				Locker:                tt.locker(t),
+			}
+			var wg gosync.WaitGroup
+			for i := 0; i < 20; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if got, err := token.GetAccessTokenContext(context.Background()); err != nil || got != "T" {
+						t.Errorf("GetAccessTokenContext() = %q, %v", got, err)
+					}
+				}()
+			}
+			wg.Wait()
+			if calls != 1 {
+				t.Errorf("refresh calls = %d, want 1", calls)
+			}
+		})
+	}
+}
+
+func TestGetAccessTokenContextCancel(t *testing.T) {
+	var calls int32
+	cache := sync.New()
+	token := AccessToken{
+		Id:                    "cancel",
+		Cache:                 cache,
+		GetRefreshRequestFunc: countingRefresh(&calls, 100*time.Millisecond, `{"access_token":"T","expires_in":7200}`),
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := token.GetAccessTokenContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("GetAccessTokenContext() error = %v, want context.DeadlineExceeded", err)
+	}
+	// 调用方取消后刷新继续完成并写入缓存
+	time.Sleep(200 * time.Millisecond)
+	if got, _ := cache.Fetch("access_token:" + token.Id); got != "T" {
+		t.Errorf("cached token = %q, want T", got)
+	}
+}
+
+func TestGetAccessTokenEmpty(t *testing.T) {
+	var calls int32
+	token := AccessToken{
+		Id:                    "empty",
+		Cache:                 sync.New(),
+		GetRefreshRequestFunc: countingRefresh(&calls, 0, `{"errcode":40013}`),
+	}
+	if _, err := token.GetAccessTokenContext(context.Background()); !errors.Is(err, ErrEmptyToken) {
+		t.Errorf("GetAccessTokenContext() error = %v, want ErrEmptyToken", err)
+	}
+}
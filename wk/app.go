@@ -2,12 +2,12 @@ package wk
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
-	"fmt"
 	json2 "github.com/bitly/go-simplejson"
+	"github.com/faabiosr/cachego"
 	"github.com/faabiosr/cachego/file"
 	"github.com/leapig/tpp/util"
-	"io"
 	"net/http"
 	"net/url"
 	"os"
@@ -16,39 +16,71 @@ import (
 )
 
 type App interface {
+	Key() string
 	Id() string
-	Test() string
-	OrgEduList(cursor int) (res []interface{})
-	GetOrgByIds(ids interface{}) (res []interface{})
-	GetOrgUsers(id interface{}, cursor int, fetchChild int) (res []interface{})
-	GetUserByCardNumber(cardNumbers interface{}) (res []interface{})
-	Search(keyword interface{}) (res []interface{})
-	AuthorizationCode(wxCode string, appKey string, appSecret string, redirectUri string) (res string)
-	GetUserInfoByOauth(accessToken string) (res string)
+	Close()
+	Test() (string, error)
+	TestContext(ctx context.Context) (string, error)
+	OrgEduList(cursor int) ([]interface{}, error)
+	OrgEduListContext(ctx context.Context, cursor int) ([]interface{}, error)
+	GetOrgByIds(ids interface{}) ([]interface{}, error)
+	GetOrgByIdsContext(ctx context.Context, ids interface{}) ([]interface{}, error)
+	GetOrgUsers(id interface{}, cursor int, fetchChild int) ([]interface{}, error)
+	GetOrgUsersContext(ctx context.Context, id interface{}, cursor int, fetchChild int) ([]interface{}, error)
+	GetUserByCardNumber(cardNumbers interface{}) ([]interface{}, error)
+	GetUserByCardNumberContext(ctx context.Context, cardNumbers interface{}) ([]interface{}, error)
+	Search(keyword interface{}) ([]interface{}, error)
+	SearchContext(ctx context.Context, keyword interface{}) ([]interface{}, error)
+	AuthorizationCode(wxCode string, appKey string, appSecret string, redirectUri string) (string, error)
+	AuthorizationCodeContext(ctx context.Context, wxCode string, appKey string, appSecret string, redirectUri string) (string, error)
+	GetUserInfoByOauth(accessToken string) (string, error)
+	GetUserInfoByOauthContext(ctx context.Context, accessToken string) (string, error)
 }
 
 type Config struct {
-	AppID     string `json:"appId"`
-	AppSecret string `json:"appSecret"`
-	AppCode   string `json:"appCode"`
+	Key       string `json:"key" yaml:"key"`
+	AppID     string `json:"appId" yaml:"appId"`
+	AppSecret string `json:"appSecret" yaml:"appSecret"`
+	AppCode   string `json:"appCode" yaml:"appCode"`
+	// Cache 令牌缓存，为空时使用系统临时目录下的文件缓存
+	Cache cachego.Cache `json:"cache" yaml:"-"`
+	// BaseURL 服务地址，为空时使用平台默认地址
+	BaseURL string `json:"baseURL" yaml:"baseURL"`
+	// Proxy 出口代理地址
+	Proxy string `json:"proxy" yaml:"proxy"`
+	// HTTPClient 自定义请求客户端，优先于 Transport 与 Proxy
+	HTTPClient *http.Client `json:"-" yaml:"-"`
+	// Transport 自定义 Transport
+	Transport http.RoundTripper `json:"-" yaml:"-"`
+	// Locker 多实例部署时的令牌刷新锁
+	Locker util.Locker `json:"-" yaml:"-"`
+	// Refresher 后台预刷新，为空时仅在令牌过期后刷新
+	Refresher *util.Refresher `json:"-" yaml:"-"`
 }
 
 type app struct {
 	config Config
 	token  util.AccessToken
+	client *util.Client
 	server string
 }
 
 func NewApp(config Config) App {
-	server := "https://open.wecard.qq.com"
+	if config.Cache == nil {
+		config.Cache = file.New(os.TempDir())
+	}
+	server := util.BaseURL(config.BaseURL, "https://open.wecard.qq.com")
+	client := &util.Client{Platform: util.PlatformWK, Kinds: errorKinds, HttpClient: util.NewHTTPClient(config.HTTPClient, config.Transport, config.Proxy)}
 	// 管理token
-	return &app{
+	a := &app{
 		server: server,
 		config: config,
+		client: client,
 		token: util.AccessToken{
-			Id:    config.AppID + config.AppSecret,
-			Cache: file.New(os.TempDir()),
-			GetRefreshRequestFunc: func() []byte {
+			Id:     util.CacheKey(util.PlatformWK, config.AppID, config.AppSecret),
+			Cache:  config.Cache,
+			Locker: config.Locker,
+			GetRefreshRequestFunc: func(ctx context.Context) ([]byte, error) {
 				payload, _ := json.Marshal(map[string]string{
 					"app_key":    config.AppID,
 					"app_secret": config.AppSecret,
@@ -56,16 +88,56 @@ func NewApp(config Config) App {
 					"scope":      "base",
 					"ocode":      config.AppCode,
 				})
-				req, _ := http.NewRequest(http.MethodPost,
+				req, err := http.NewRequestWithContext(ctx, http.MethodPost,
 					server+"/cgi-bin/oauth2/token", bytes.NewReader(payload))
+				if err != nil {
+					return nil, util.NewError(util.PlatformWK, err)
+				}
 				req.Header.Set("Content-Type", "application/json")
-				response, _ := http.DefaultClient.Do(req)
-				resp, _ := io.ReadAll(response.Body)
-				fmt.Printf("\n\n%s\n\n", string(resp))
-				return resp
+				return client.Fetch(req)
 			},
 		},
 	}
+	if config.Refresher != nil {
+		config.Refresher.Add(a.token)
+	}
+	return a
+}
+
+// doHttp 以JSON格式提交请求并解析响应
+func (a *app) doHttp(ctx context.Context, path string, params url.Values, data interface{}) (*json2.Json, error) {
+	payload, _ := json.Marshal(data)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.server+path+"?"+params.Encode(), bytes.NewReader(payload))
+	if err != nil {
+		return nil, util.NewError(util.PlatformWK, err)
+	}
+	req.Header.Set("Content-Type", util.ContentType)
+	resp, err := a.client.Fetch(req)
+	if err != nil {
+		return nil, err
+	}
+	respStr, _ := strconv.Unquote(strings.Replace(strconv.Quote(string(resp)), `\\u`, `\u`, -1))
+	js, err := json2.NewJson([]byte(respStr))
+	if err != nil {
+		return nil, &util.Error{Platform: util.PlatformWK, Body: resp, Err: err}
+	}
+	return js, nil
+}
+
+// doHttpWithToken 附加 access_token 后执行请求，令牌失效时刷新后重试一次
+func (a *app) doHttpWithToken(ctx context.Context, path string, data interface{}) (js *json2.Json, err error) {
+	err = a.token.WithAccessToken(ctx, func(token string) error {
+		params := url.Values{}
+		params.Add("access_token", token)
+		js, err = a.doHttp(ctx, path, params, data)
+		return err
+	})
+	return
+}
+
+// Key 获取当前实例Key
+func (a *app) Key() string {
+	return a.config.Key
 }
 
 // Id 获取当前实例ID
@@ -73,184 +145,158 @@ func (a *app) Id() string {
 	return a.config.AppID
 }
 
+// Close 停止实例令牌的后台预刷新，实例不再使用时调用
+func (a *app) Close() {
+	if a.config.Refresher != nil {
+		a.config.Refresher.Remove(a.token)
+	}
+}
+
 // Test 校验是否配置是否正常（返回access_token）
-func (a *app) Test() string {
-	return a.token.GetAccessToken()
+func (a *app) Test() (string, error) {
+	return a.TestContext(context.Background())
 }
 
-// Applications GET /open-apis/application/v6/applications/:app_id
-func (a *app) Applications() (res map[string]interface{}) {
-	params := url.Values{}
-	params.Add("lang", "zh_cn")
-	req, _ := http.NewRequest(http.MethodGet, a.server+"/open-apis/application/v6/applications/"+a.config.AppID+"?"+params.Encode(), nil)
-	a.token.SetLarkAccessToken(req.Header)
-	if response, err := http.DefaultClient.Do(req); err == nil {
-		if resp, err := io.ReadAll(response.Body); err == nil {
-			js, _ := json2.NewJson(resp)
-			if js.Get("code").MustInt() == 0 {
-				res = js.Get("data").Get("app").MustMap()
-			}
-		}
-	}
-	return
+// TestContext 同 Test，支持 context.Context
+func (a *app) TestContext(ctx context.Context) (string, error) {
+	return a.token.GetAccessTokenContext(ctx)
 }
 
 // OrgEduList POST https://open.wecard.qq.com/cgi-bin/user/org-edu-list?access_token=access_token
-func (a *app) OrgEduList(cursor int) (res []interface{}) {
-	params := url.Values{}
-	params = a.token.ApplyAccessToken(params)
-	payload, _ := json.Marshal(map[string]interface{}{
+func (a *app) OrgEduList(cursor int) ([]interface{}, error) {
+	return a.OrgEduListContext(context.Background(), cursor)
+}
+
+// OrgEduListContext 同 OrgEduList，支持 context.Context
+func (a *app) OrgEduListContext(ctx context.Context, cursor int) ([]interface{}, error) {
+	js, err := a.doHttpWithToken(ctx, "/cgi-bin/user/org-edu-list", map[string]interface{}{
 		"page":      cursor,
 		"page_size": 5000,
 	})
-	if response, err := http.Post(a.server+"/cgi-bin/user/org-edu-list?"+params.Encode(), "application/json;charset=utf-8", bytes.NewReader(payload)); err == nil {
-		if resp, err := io.ReadAll(response.Body); err == nil {
-			respStr, _ := strconv.Unquote(strings.Replace(strconv.Quote(string(resp)), `\\u`, `\u`, -1))
-			js, _ := json2.NewJson([]byte(respStr))
-			if js.Get("errcode").MustInt() == 0 {
-				res = js.Get("organization").MustArray()
-				if len(res) > 1 {
-					cursor = cursor + 1
-					res = append(res, a.OrgEduList(cursor)...)
-				}
-			} else {
-				// TODO
-			}
+	if err != nil {
+		return nil, err
+	}
+	res := js.Get("organization").MustArray()
+	if len(res) > 1 {
+		cursor = cursor + 1
+		more, err := a.OrgEduListContext(ctx, cursor)
+		res = append(res, more...)
+		if err != nil {
+			return res, err
 		}
 	}
-	return
+	return res, nil
 }
 
 // GetOrgByIds POST https://open.wecard.qq.com/cgi-bin/org/get-org-by-ids?access_token=access_token
-func (a *app) GetOrgByIds(ids interface{}) (res []interface{}) {
-	params := url.Values{}
-	params = a.token.ApplyAccessToken(params)
+func (a *app) GetOrgByIds(ids interface{}) ([]interface{}, error) {
+	return a.GetOrgByIdsContext(context.Background(), ids)
+}
+
+// GetOrgByIdsContext 同 GetOrgByIds，支持 context.Context
+func (a *app) GetOrgByIdsContext(ctx context.Context, ids interface{}) ([]interface{}, error) {
 	id, _ := strconv.Atoi(ids.(string))
-	payload, _ := json.Marshal(map[string]interface{}{
+	js, err := a.doHttpWithToken(ctx, "/cgi-bin/org/get-org-by-ids", map[string]interface{}{
 		"org_ids": []int{id},
 	})
-	if response, err := http.Post(a.server+"/cgi-bin/org/get-org-by-ids?"+params.Encode(), "application/json;charset=utf-8", bytes.NewReader(payload)); err == nil {
-		if resp, err := io.ReadAll(response.Body); err == nil {
-			respStr, _ := strconv.Unquote(strings.Replace(strconv.Quote(string(resp)), `\\u`, `\u`, -1))
-			js, _ := json2.NewJson([]byte(respStr))
-			if js.Get("errcode").MustInt() == 0 {
-				res = js.Get("organization").MustArray()
-			}
-		} else {
-			//
-		}
+	if err != nil {
+		return nil, err
 	}
-	return
+	return js.Get("organization").MustArray(), nil
 }
 
 // GetOrgUsers POST https://open.wecard.qq.com/cgi-bin/user/get-org-users?access_token=access_token
-func (a *app) GetOrgUsers(id interface{}, cursor int, fetchChild int) (res []interface{}) {
-	params := url.Values{}
-	params = a.token.ApplyAccessToken(params)
-	payload, _ := json.Marshal(map[string]interface{}{
+func (a *app) GetOrgUsers(id interface{}, cursor int, fetchChild int) ([]interface{}, error) {
+	return a.GetOrgUsersContext(context.Background(), id, cursor, fetchChild)
+}
+
+// GetOrgUsersContext 同 GetOrgUsers，支持 context.Context
+func (a *app) GetOrgUsersContext(ctx context.Context, id interface{}, cursor int, fetchChild int) ([]interface{}, error) {
+	js, err := a.doHttpWithToken(ctx, "/cgi-bin/user/get-org-users", map[string]interface{}{
 		"page":        cursor,
 		"page_size":   5000,
 		"org_id":      id,
 		"fetch_child": fetchChild, //是否递归获取子组织架构下面的成员：1-是；0-否，默认为否
 	})
-	if response, err := http.Post(a.server+"/cgi-bin/user/get-org-users?"+params.Encode(), "application/json;charset=utf-8", bytes.NewReader(payload)); err == nil {
-		if resp, err := io.ReadAll(response.Body); err == nil {
-			respStr, _ := strconv.Unquote(strings.Replace(strconv.Quote(string(resp)), `\\u`, `\u`, -1))
-			js, _ := json2.NewJson([]byte(respStr))
-			if js.Get("errcode").MustInt() == 0 {
-				res = js.Get("userlist").MustArray()
-				if len(res) > 0 {
-					cursor = cursor + 1
-					res = append(res, a.GetOrgUsers(id, cursor, fetchChild)...)
-				}
-			}
-		} else {
-			//
+	if err != nil {
+		return nil, err
+	}
+	res := js.Get("userlist").MustArray()
+	if len(res) > 0 {
+		cursor = cursor + 1
+		more, err := a.GetOrgUsersContext(ctx, id, cursor, fetchChild)
+		res = append(res, more...)
+		if err != nil {
+			return res, err
 		}
 	}
-	return
+	return res, nil
 }
 
 // GetUserByCardNumber POST https://open.wecard.qq.com/cgi-bin/user/get-user-by-card-numbers?access_token=access_token
-func (a *app) GetUserByCardNumber(cardNumbers interface{}) (res []interface{}) {
-	params := url.Values{}
-	params = a.token.ApplyAccessToken(params)
-	payload, _ := json.Marshal(map[string]interface{}{
+func (a *app) GetUserByCardNumber(cardNumbers interface{}) ([]interface{}, error) {
+	return a.GetUserByCardNumberContext(context.Background(), cardNumbers)
+}
+
+// GetUserByCardNumberContext 同 GetUserByCardNumber，支持 context.Context
+func (a *app) GetUserByCardNumberContext(ctx context.Context, cardNumbers interface{}) ([]interface{}, error) {
+	js, err := a.doHttpWithToken(ctx, "/cgi-bin/user/get-user-by-card-numbers", map[string]interface{}{
 		"card_numbers": cardNumbers,
 	})
-	if response, err := http.Post(a.server+"/cgi-bin/user/get-user-by-card-numbers?"+params.Encode(), "application/json;charset=utf-8", bytes.NewReader(payload)); err == nil {
-		if resp, err := io.ReadAll(response.Body); err == nil {
-			respStr, _ := strconv.Unquote(strings.Replace(strconv.Quote(string(resp)), `\\u`, `\u`, -1))
-			js, _ := json2.NewJson([]byte(respStr))
-			if js.Get("errcode").MustInt() == 0 {
-				res = js.Get("userlist").MustArray()
-			}
-		} else {
-			//
-		}
+	if err != nil {
+		return nil, err
 	}
-	return
+	return js.Get("userlist").MustArray(), nil
 }
 
 // Search POST https://open.wecard.qq.com/cgi-bin/user/search?access_token=access_token
-func (a *app) Search(keyword interface{}) (res []interface{}) {
-	params := url.Values{}
-	params = a.token.ApplyAccessToken(params)
-	payload, _ := json.Marshal(map[string]interface{}{
+func (a *app) Search(keyword interface{}) ([]interface{}, error) {
+	return a.SearchContext(context.Background(), keyword)
+}
+
+// SearchContext 同 Search，支持 context.Context
+func (a *app) SearchContext(ctx context.Context, keyword interface{}) ([]interface{}, error) {
+	js, err := a.doHttpWithToken(ctx, "/cgi-bin/user/search", map[string]interface{}{
 		"keywords": keyword,
 	})
-	if response, err := http.Post(a.server+"/cgi-bin/user/search?"+params.Encode(), "application/json;charset=utf-8", bytes.NewReader(payload)); err == nil {
-		if resp, err := io.ReadAll(response.Body); err == nil {
-			respStr, _ := strconv.Unquote(strings.Replace(strconv.Quote(string(resp)), `\\u`, `\u`, -1))
-			js, _ := json2.NewJson([]byte(respStr))
-			if js.Get("errcode").MustInt() == 0 {
-				res = js.Get("userlist").MustArray()
-			}
-		} else {
-			//
-		}
+	if err != nil {
+		return nil, err
 	}
-	return
+	return js.Get("userlist").MustArray(), nil
 }
 
 // AuthorizationCode POST https://open.wecard.qq.com/connect/oauth2/token
-func (a *app) AuthorizationCode(wxCode string, appKey string, appSecret string, redirectUri string) (res string) {
-	payload, _ := json.Marshal(map[string]interface{}{
+func (a *app) AuthorizationCode(wxCode string, appKey string, appSecret string, redirectUri string) (string, error) {
+	return a.AuthorizationCodeContext(context.Background(), wxCode, appKey, appSecret, redirectUri)
+}
+
+// AuthorizationCodeContext 同 AuthorizationCode，支持 context.Context
+func (a *app) AuthorizationCodeContext(ctx context.Context, wxCode string, appKey string, appSecret string, redirectUri string) (string, error) {
+	js, err := a.doHttp(ctx, "/connect/oauth2/token", url.Values{}, map[string]interface{}{
 		"wxcode":       wxCode,
 		"app_key":      appKey,
 		"app_secret":   appSecret,
 		"grant_type":   "authorization_code",
 		"redirect_uri": redirectUri,
 	})
-	if response, err := http.Post(a.server+"/connect/oauth2/token", "application/json;charset=utf-8", bytes.NewReader(payload)); err == nil {
-		if resp, err := io.ReadAll(response.Body); err == nil {
-			respStr, _ := strconv.Unquote(strings.Replace(strconv.Quote(string(resp)), `\\u`, `\u`, -1))
-			js, _ := json2.NewJson([]byte(respStr))
-			if js.Get("errcode").MustInt() == 0 {
-				res = js.Get("access_token").MustString()
-			}
-		} else {
-			//
-		}
+	if err != nil {
+		return "", err
 	}
-	return
+	return js.Get("access_token").MustString(), nil
 }
 
 // GetUserInfoByOauth POST https://open.wecard.qq.com/connect/oauth/get-user-info
-func (a *app) GetUserInfoByOauth(accessToken string) (res string) {
-	payload, _ := json.Marshal(map[string]interface{}{
+func (a *app) GetUserInfoByOauth(accessToken string) (string, error) {
+	return a.GetUserInfoByOauthContext(context.Background(), accessToken)
+}
+
+// GetUserInfoByOauthContext 同 GetUserInfoByOauth，支持 context.Context
+func (a *app) GetUserInfoByOauthContext(ctx context.Context, accessToken string) (string, error) {
+	js, err := a.doHttp(ctx, "/connect/oauth/get-user-info", url.Values{}, map[string]interface{}{
 		"access_token": accessToken,
 	})
-	if response, err := http.Post(a.server+"/connect/oauth/get-user-info", "application/json;charset=utf-8", bytes.NewReader(payload)); err == nil {
-		if resp, err := io.ReadAll(response.Body); err == nil {
-			respStr, _ := strconv.Unquote(strings.Replace(strconv.Quote(string(resp)), `\\u`, `\u`, -1))
-			js, _ := json2.NewJson([]byte(respStr))
-			if js.Get("errcode").MustInt() == 0 {
-				res = js.Get("card_number").MustString()
-			}
-		} else {
-			//
-		}
+	if err != nil {
+		return "", err
 	}
-	return
+	return js.Get("card_number").MustString(), nil
 }
@@ -0,0 +1,12 @@
+package wk
+
+import "github.com/leapig/tpp/util"
+
+// errorKinds 微卡错误码分类
+var errorKinds = map[int]error{
+	40001: util.ErrInvalidToken,
+	40014: util.ErrInvalidToken,
+	42001: util.ErrInvalidToken,
+	45009: util.ErrRateLimited,
+	48001: util.ErrPermissionDenied,
+}
@@ -0,0 +1,183 @@
+package wo
+
+import (
+	"context"
+
+	json2 "github.com/bitly/go-simplejson"
+	"github.com/faabiosr/cachego"
+	"github.com/leapig/tpp/mp"
+	"github.com/leapig/tpp/oa"
+	"github.com/leapig/tpp/util"
+)
+
+// AuthorizerStore 授权账号刷新令牌存储，可基于数据库等实现持久化
+// 刷新令牌仅在授权时下发，丢失后需要重新授权，生产环境建议使用持久化存储
+type AuthorizerStore interface {
+	// RefreshToken 获取授权账号的刷新令牌，不存在时返回空字符串
+	RefreshToken(ctx context.Context, authorizerAppId string) (string, error)
+	// SetRefreshToken 保存授权账号的刷新令牌
+	SetRefreshToken(ctx context.Context, authorizerAppId, refreshToken string) error
+	// Delete 删除授权账号的刷新令牌
+	Delete(ctx context.Context, authorizerAppId string) error
+}
+
+type cacheAuthorizerStore struct {
+	cache  cachego.Cache
+	prefix string
+}
+
+// NewCacheAuthorizerStore 基于 cachego.Cache 的刷新令牌存储，刷新令牌不设置过期时间
+// prefix 用于区分不同第三方平台
+func NewCacheAuthorizerStore(cache cachego.Cache, prefix string) AuthorizerStore {
+	return &cacheAuthorizerStore{cache: cache, prefix: prefix}
+}
+
+func (s *cacheAuthorizerStore) key(authorizerAppId string) string {
+	return "authorizer_refresh_token:" + s.prefix + ":" + authorizerAppId
+}
+
+func (s *cacheAuthorizerStore) RefreshToken(_ context.Context, authorizerAppId string) (string, error) {
+	if !s.cache.Contains(s.key(authorizerAppId)) {
+		return "", nil
+	}
+	return s.cache.Fetch(s.key(authorizerAppId))
+}
+
+func (s *cacheAuthorizerStore) SetRefreshToken(_ context.Context, authorizerAppId, refreshToken string) error {
+	return s.cache.Save(s.key(authorizerAppId), refreshToken, 0)
+}
+
+func (s *cacheAuthorizerStore) Delete(_ context.Context, authorizerAppId string) error {
+	return s.cache.Delete(s.key(authorizerAppId))
+}
+
+// authorizerToken 授权账号调用令牌，使用已保存的刷新令牌换取，平台下发新的刷新令牌时同步保存
+func (a *app) authorizerToken(authorizerAppId string) util.AccessToken {
+	return util.AccessToken{
+		Id:     "authorizer:" + a.token.Id + ":" + authorizerAppId,
+		Cache:  a.config.Cache,
+		Locker: a.config.Locker,
+		Parse:  util.ParseJSON("authorizer_access_token", "expires_in"),
+		GetRefreshRequestFunc: func(ctx context.Context) ([]byte, error) {
+			refreshToken, err := a.config.AuthorizerStore.RefreshToken(ctx, authorizerAppId)
+			if err != nil {
+				return nil, util.NewError(util.PlatformWO, err)
+			}
+			if refreshToken == "" {
+				return nil, &util.Error{Platform: util.PlatformWO, ErrMsg: "authorizer refresh token not found: " + authorizerAppId, Kind: util.ErrNotFound}
+			}
+			js, err := a.GetAuthorizerAccessTokenContext(ctx, authorizerAppId, refreshToken)
+			if err != nil {
+				return nil, err
+			}
+			if rotated := js.Get("authorizer_refresh_token").MustString(); rotated != "" && rotated != refreshToken {
+				if err = a.config.AuthorizerStore.SetRefreshToken(ctx, authorizerAppId, rotated); err != nil {
+					return nil, util.NewError(util.PlatformWO, err)
+				}
+			}
+			return js.MarshalJSON()
+		},
+	}
+}
+
+// AuthorizerToken 获取授权账号调用令牌（authorizer_access_token），过期后使用已保存的刷新令牌自动刷新
+// doc https://developers.weixin.qq.com/doc/oplatform/openApi/OpenApiDoc/ticket-token/getAuthorizerAccessToken.html
+func (a *app) AuthorizerToken(authorizerAppId string) (string, error) {
+	return a.AuthorizerTokenContext(context.Background(), authorizerAppId)
+}
+
+// AuthorizerTokenContext 同 AuthorizerToken，支持 context.Context
+func (a *app) AuthorizerTokenContext(ctx context.Context, authorizerAppId string) (string, error) {
+	return a.authorizerToken(authorizerAppId).GetAccessTokenContext(ctx)
+}
+
+// SetAuthorizerRefreshToken 保存授权账号的刷新令牌，用于导入已有授权
+func (a *app) SetAuthorizerRefreshToken(authorizerAppId, refreshToken string) error {
+	return a.SetAuthorizerRefreshTokenContext(context.Background(), authorizerAppId, refreshToken)
+}
+
+// SetAuthorizerRefreshTokenContext 同 SetAuthorizerRefreshToken，支持 context.Context
+func (a *app) SetAuthorizerRefreshTokenContext(ctx context.Context, authorizerAppId, refreshToken string) error {
+	if authorizerAppId == "" || refreshToken == "" {
+		return &util.Error{Platform: util.PlatformWO, ErrMsg: "empty authorizer appid or refresh token", Kind: util.ErrInvalidParam}
+	}
+	if err := a.config.AuthorizerStore.SetRefreshToken(ctx, authorizerAppId, refreshToken); err != nil {
+		return util.NewError(util.PlatformWO, err)
+	}
+	return nil
+}
+
+// Authorize 使用授权码换取授权信息，并保存授权账号的刷新令牌
+// doc https://developers.weixin.qq.com/doc/oplatform/openApi/OpenApiDoc/ticket-token/getAuthorizerRefreshToken.html
+func (a *app) Authorize(authorizationCode string) (*json2.Json, error) {
+	return a.AuthorizeContext(context.Background(), authorizationCode)
+}
+
+// AuthorizeContext 同 Authorize，支持 context.Context
+func (a *app) AuthorizeContext(ctx context.Context, authorizationCode string) (*json2.Json, error) {
+	js, err := a.GetAuthorizerRefreshTokenContext(ctx, authorizationCode)
+	if err != nil {
+		return nil, err
+	}
+	info := js.Get("authorization_info")
+	authorizerAppId := info.Get("authorizer_appid").MustString()
+	refreshToken := info.Get("authorizer_refresh_token").MustString()
+	if authorizerAppId == "" || refreshToken == "" {
+		return nil, util.Errorf(util.PlatformWO, "missing authorizer_appid or authorizer_refresh_token in authorization_info")
+	}
+	if err = a.config.AuthorizerStore.SetRefreshToken(ctx, authorizerAppId, refreshToken); err != nil {
+		return nil, util.NewError(util.PlatformWO, err)
+	}
+	// 重新授权后权限集可能变化，丢弃旧令牌
+	a.authorizerToken(authorizerAppId).Clear()
+	return info, nil
+}
+
+// Unauthorize 删除授权账号已保存的刷新令牌与调用令牌
+func (a *app) Unauthorize(authorizerAppId string) error {
+	return a.UnauthorizeContext(context.Background(), authorizerAppId)
+}
+
+// UnauthorizeContext 同 Unauthorize，支持 context.Context
+func (a *app) UnauthorizeContext(ctx context.Context, authorizerAppId string) error {
+	if err := a.config.AuthorizerStore.Delete(ctx, authorizerAppId); err != nil {
+		return util.NewError(util.PlatformWO, err)
+	}
+	a.authorizerToken(authorizerAppId).Clear()
+	return nil
+}
+
+// MP 获取授权小程序实例，每次调用均使用托管的授权账号调用令牌与 component_access_token
+func (a *app) MP(authorizerAppId string) mp.App {
+	token := a.authorizerToken(authorizerAppId)
+	return mp.NewApp(mp.Config{
+		AppId:                   authorizerAppId,
+		ComponentAppid:          a.config.AppId,
+		Cache:                   a.config.Cache,
+		BaseURL:                 a.server,
+		Proxy:                   a.config.Proxy,
+		HTTPClient:              a.client.HttpClient,
+		Locker:                  a.config.Locker,
+		AccessToken:             &token,
+		GetComponentAccessToken: a.TokenContext,
+	})
+}
+
+// OA 获取授权公众号实例，每次调用均使用托管的授权账号调用令牌与 component_access_token
+// 消息推送使用第三方平台的消息校验 Token 与消息加解密 Key
+func (a *app) OA(authorizerAppId string) oa.App {
+	token := a.authorizerToken(authorizerAppId)
+	return oa.NewApp(oa.Config{
+		AppId:                   authorizerAppId,
+		Token:                   a.config.Token,
+		AesKey:                  a.config.AesKey,
+		ComponentAppid:          a.config.AppId,
+		Cache:                   a.config.Cache,
+		BaseURL:                 a.server,
+		Proxy:                   a.config.Proxy,
+		HTTPClient:              a.client.HttpClient,
+		Locker:                  a.config.Locker,
+		AccessToken:             &token,
+		GetComponentAccessToken: a.TokenContext,
+	})
+}
@@ -0,0 +1,24 @@
+package wo
+
+import "github.com/leapig/tpp/util"
+
+// errorKinds 开放平台错误码分类
+// ErrInvalidToken 仅用于刷新令牌后可恢复的错误，ticket 与 refresh_token 失效刷新令牌无法恢复
+// doc https://developers.weixin.qq.com/doc/oplatform/Return_codes/Return_code_descriptions_new.html
+var errorKinds = map[int]error{
+	40001: util.ErrInvalidToken,
+	40014: util.ErrInvalidToken,
+	42001: util.ErrInvalidToken,
+	40013: util.ErrInvalidParam,
+	40029: util.ErrInvalidParam,
+	61005: util.ErrInvalidParam,
+	61006: util.ErrInvalidParam,
+	45009: util.ErrRateLimited,
+	45011: util.ErrRateLimited,
+	48001: util.ErrPermissionDenied,
+	61003: util.ErrPermissionDenied,
+	61004: util.ErrPermissionDenied,
+	61007: util.ErrPermissionDenied,
+	61023: util.ErrPermissionDenied,
+	89044: util.ErrNotFound,
+}
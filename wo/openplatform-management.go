@@ -2,6 +2,7 @@ package wo
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	json2 "github.com/bitly/go-simplejson"
 	"net/http"
@@ -12,6 +13,11 @@ import (
 // doc https://developers.weixin.qq.com/doc/oplatform/openApi/OpenApiDoc/openplatform-management/bindOpenAccount.html
 // req POST https://api.weixin.qq.com/cgi-bin/open/bind?access_token=ACCESS_TOKEN
 func (a *app) BindOpenAccount(authorizerAccessToken, openAppid string) (*json2.Json, error) {
+	return a.BindOpenAccountContext(context.Background(), authorizerAccessToken, openAppid)
+}
+
+// BindOpenAccountContext 同 BindOpenAccount，支持 context.Context
+func (a *app) BindOpenAccountContext(ctx context.Context, authorizerAccessToken, openAppid string) (*json2.Json, error) {
 	params := url.Values{}
 	params.Add("access_token", authorizerAccessToken)
 	if openAppid == "" {
@@ -20,13 +26,18 @@ func (a *app) BindOpenAccount(authorizerAccessToken, openAppid string) (*json2.J
 	payload, _ := json.Marshal(map[string]string{
 		"open_appid": openAppid,
 	})
-	return a.doHttp(http.MethodPost, "/cgi-bin/open/bind?"+params.Encode(), bytes.NewReader(payload))
+	return a.doHttp(ctx, http.MethodPost, "/cgi-bin/open/bind?"+params.Encode(), bytes.NewReader(payload))
 }
 
 // UnbindOpenAccount 解除绑定开放平台账号
 // doc https://developers.weixin.qq.com/doc/oplatform/openApi/OpenApiDoc/openplatform-management/unbindOpenAccount.html
 // req POST https://api.weixin.qq.com/cgi-bin/open/unbind?access_token=ACCESS_TOKEN
 func (a *app) UnbindOpenAccount(authorizerAccessToken, openAppid string) (*json2.Json, error) {
+	return a.UnbindOpenAccountContext(context.Background(), authorizerAccessToken, openAppid)
+}
+
+// UnbindOpenAccountContext 同 UnbindOpenAccount，支持 context.Context
+func (a *app) UnbindOpenAccountContext(ctx context.Context, authorizerAccessToken, openAppid string) (*json2.Json, error) {
 	params := url.Values{}
 	params.Add("access_token", authorizerAccessToken)
 	if openAppid == "" {
@@ -35,23 +46,33 @@ func (a *app) UnbindOpenAccount(authorizerAccessToken, openAppid string) (*json2
 	payload, _ := json.Marshal(map[string]string{
 		"open_appid": openAppid,
 	})
-	return a.doHttp(http.MethodPost, "/cgi-bin/open/unbind?"+params.Encode(), bytes.NewReader(payload))
+	return a.doHttp(ctx, http.MethodPost, "/cgi-bin/open/unbind?"+params.Encode(), bytes.NewReader(payload))
 }
 
 // GetOpenAccount 获取开放平台账号
 // doc https://developers.weixin.qq.com/doc/oplatform/openApi/OpenApiDoc/openplatform-management/getOpenAccount.html
 // req POST https://api.weixin.qq.com/cgi-bin/open/get?access_token=ACCESS_TOKEN
 func (a *app) GetOpenAccount(authorizerAccessToken string) (*json2.Json, error) {
+	return a.GetOpenAccountContext(context.Background(), authorizerAccessToken)
+}
+
+// GetOpenAccountContext 同 GetOpenAccount，支持 context.Context
+func (a *app) GetOpenAccountContext(ctx context.Context, authorizerAccessToken string) (*json2.Json, error) {
 	params := url.Values{}
 	params.Add("access_token", authorizerAccessToken)
-	return a.doHttp(http.MethodPost, "/cgi-bin/open/get?"+params.Encode(), nil)
+	return a.doHttp(ctx, http.MethodPost, "/cgi-bin/open/get?"+params.Encode(), nil)
 }
 
 // CreateOpenAccount 绑定开放平台账号
 // doc https://developers.weixin.qq.com/doc/oplatform/openApi/OpenApiDoc/openplatform-management/createOpenAccount.html
 // req POST https://api.weixin.qq.com/cgi-bin/open/create?access_token=ACCESS_TOKEN
 func (a *app) CreateOpenAccount(authorizerAccessToken string) (*json2.Json, error) {
+	return a.CreateOpenAccountContext(context.Background(), authorizerAccessToken)
+}
+
+// CreateOpenAccountContext 同 CreateOpenAccount，支持 context.Context
+func (a *app) CreateOpenAccountContext(ctx context.Context, authorizerAccessToken string) (*json2.Json, error) {
 	params := url.Values{}
 	params.Add("access_token", authorizerAccessToken)
-	return a.doHttp(http.MethodPost, "/cgi-bin/open/create?"+params.Encode(), nil)
+	return a.doHttp(ctx, http.MethodPost, "/cgi-bin/open/create?"+params.Encode(), nil)
 }
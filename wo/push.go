@@ -0,0 +1,44 @@
+package wo
+
+import json2 "github.com/bitly/go-simplejson"
+
+// 第三方平台推送类型
+// doc https://developers.weixin.qq.com/doc/oplatform/Third-party_Platforms/2.0/api/Before_Develop/authorize_event.html
+const (
+	InfoTypeComponentVerifyTicket = "component_verify_ticket"
+	InfoTypeAuthorized            = "authorized"
+	InfoTypeUpdateAuthorized      = "updateauthorized"
+	InfoTypeUnauthorized          = "unauthorized"
+)
+
+// Push 第三方平台推送公共字段，Raw 为解密后的明文XML
+type Push struct {
+	AppId      string `xml:"AppId"`
+	CreateTime int64  `xml:"CreateTime"`
+	InfoType   string `xml:"InfoType"`
+	Raw        []byte `xml:"-"`
+}
+
+// ComponentVerifyTicketPush 验证票据推送，每 10 分钟推送一次，有效期 12 小时
+// doc https://developers.weixin.qq.com/doc/oplatform/openApi/OpenApiDoc/ticket-token/getComponentVerifyTicket.html
+type ComponentVerifyTicketPush struct {
+	Push
+	ComponentVerifyTicket string `xml:"ComponentVerifyTicket"`
+}
+
+// AuthorizedEvent 授权成功与授权更新事件，Authorization 为使用授权码换取的 authorization_info
+// doc https://developers.weixin.qq.com/doc/oplatform/Third-party_Platforms/2.0/api/Before_Develop/authorize_event.html
+type AuthorizedEvent struct {
+	Push
+	AuthorizerAppid              string      `xml:"AuthorizerAppid"`
+	AuthorizationCode            string      `xml:"AuthorizationCode"`
+	AuthorizationCodeExpiredTime int64       `xml:"AuthorizationCodeExpiredTime"`
+	PreAuthCode                  string      `xml:"PreAuthCode"`
+	Authorization                *json2.Json `xml:"-"`
+}
+
+// UnauthorizedEvent 取消授权事件
+type UnauthorizedEvent struct {
+	Push
+	AuthorizerAppid string `xml:"AuthorizerAppid"`
+}
@@ -0,0 +1,187 @@
+package wo
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/leapig/tpp/logger"
+	"github.com/leapig/tpp/util"
+)
+
+// maxPushSize 推送消息体上限
+const maxPushSize = 1 << 20
+
+// handler 解析明文XML并调用处理函数
+type handler func(ctx context.Context, raw []byte) error
+
+// Server 第三方平台授权事件回调服务，推送均为安全模式
+// 收到 component_verify_ticket 后写入 Config.Cache，令牌刷新时优先使用
+// 授权成功与授权更新时使用授权码换取并保存刷新令牌，取消授权时删除已保存的凭证
+// doc https://developers.weixin.qq.com/doc/oplatform/Third-party_Platforms/2.0/api/Before_Develop/authorize_event.html
+type Server struct {
+	app      *app
+	crypt    *util.WXBizMsgCrypt
+	handlers map[string]handler
+	fallback func(ctx context.Context, push *Push) error
+	guard    *util.CallbackGuard
+}
+
+// Server 创建回调服务，使用 Config.Token 与 Config.AesKey 校验、解密推送
+func (a *app) Server() *Server {
+	s := &Server{
+		app:      a,
+		handlers: map[string]handler{},
+	}
+	if a.config.AesKey != "" {
+		s.crypt = util.NewWXBizMsgCrypt(a.config.AppId, a.config.Token, a.config.AesKey)
+	}
+	return s.OnComponentVerifyTicket(nil).OnAuthorized(nil).OnUpdateAuthorized(nil).OnUnauthorized(nil)
+}
+
+func on[T any](s *Server, infoType string, h func(ctx context.Context, push *T) error) *Server {
+	s.handlers[infoType] = func(ctx context.Context, raw []byte) error {
+		push := new(T)
+		if err := xml.Unmarshal(raw, push); err != nil {
+			return err
+		}
+		return h(ctx, push)
+	}
+	return s
+}
+
+// OnComponentVerifyTicket 验证票据推送，票据保存后调用
+func (s *Server) OnComponentVerifyTicket(h func(ctx context.Context, push *ComponentVerifyTicketPush) error) *Server {
+	return on(s, InfoTypeComponentVerifyTicket, func(ctx context.Context, push *ComponentVerifyTicketPush) error {
+		if err := s.app.SetComponentVerifyTicket(push.ComponentVerifyTicket); err != nil {
+			return err
+		}
+		if h == nil {
+			return nil
+		}
+		return h(ctx, push)
+	})
+}
+
+// OnAuthorized 授权成功事件，刷新令牌保存后调用
+func (s *Server) OnAuthorized(h func(ctx context.Context, event *AuthorizedEvent) error) *Server {
+	return on(s, InfoTypeAuthorized, s.authorized(h))
+}
+
+// OnUpdateAuthorized 授权更新事件，刷新令牌保存后调用
+func (s *Server) OnUpdateAuthorized(h func(ctx context.Context, event *AuthorizedEvent) error) *Server {
+	return on(s, InfoTypeUpdateAuthorized, s.authorized(h))
+}
+
+// authorized 使用授权码换取授权信息后调用处理函数
+func (s *Server) authorized(h func(ctx context.Context, event *AuthorizedEvent) error) func(ctx context.Context, event *AuthorizedEvent) error {
+	return func(ctx context.Context, event *AuthorizedEvent) (err error) {
+		if event.Authorization, err = s.app.AuthorizeContext(ctx, event.AuthorizationCode); err != nil {
+			return err
+		}
+		if h == nil {
+			return nil
+		}
+		return h(ctx, event)
+	}
+}
+
+// OnUnauthorized 取消授权事件，已保存的凭证删除后调用
+func (s *Server) OnUnauthorized(h func(ctx context.Context, event *UnauthorizedEvent) error) *Server {
+	return on(s, InfoTypeUnauthorized, func(ctx context.Context, event *UnauthorizedEvent) error {
+		if err := s.app.UnauthorizeContext(ctx, event.AuthorizerAppid); err != nil {
+			return err
+		}
+		if h == nil {
+			return nil
+		}
+		return h(ctx, event)
+	})
+}
+
+// Guard 启用重放保护与去重，拒绝时间戳超出 window 的推送，window 内重复的推送直接回复 success
+// 使用 Config.Cache 记录已处理的推送，配置 Config.Locker 时跨实例加锁去重，window 为 0 时使用 util.DefaultCallbackWindow
+func (s *Server) Guard(window time.Duration) *Server {
+	s.guard = util.NewCallbackGuard(s.app.config.Cache, s.app.config.Locker, window)
+	return s
+}
+
+// OnDefault 未注册类型的推送
+func (s *Server) OnDefault(h func(ctx context.Context, push *Push) error) *Server {
+	s.fallback = h
+	return s
+}
+
+// ServeHTTP 解密推送并分发，处理成功后回复 success
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.crypt == nil {
+		http.Error(w, "aes key not configured", http.StatusInternalServerError)
+		return
+	}
+	query := r.URL.Query()
+	if s.guard != nil {
+		if err := s.guard.Fresh(query.Get("timestamp")); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxPushSize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	plaintext, _, cryptErr := s.crypt.DecryptMsg(query.Get("msg_signature"), query.Get("timestamp"), query.Get("nonce"), body)
+	if cryptErr != nil {
+		http.Error(w, cryptErr.ErrMsg, http.StatusForbidden)
+		return
+	}
+	if err = s.dispatch(r.Context(), plaintext); err != nil {
+		logger.Errorf("wo push: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	_, _ = io.WriteString(w, "success")
+}
+
+// dispatch 解析推送类型并调用处理函数，重复推送直接返回
+func (s *Server) dispatch(ctx context.Context, plaintext []byte) error {
+	push := &Push{Raw: plaintext}
+	if err := xml.Unmarshal(plaintext, push); err != nil {
+		return fmt.Errorf("failed to parse push: %w", err)
+	}
+	if s.guard == nil {
+		return s.handle(ctx, push)
+	}
+	// 授权事件同一秒内可能有多条，使用推送内容去重
+	id := util.CallbackId(plaintext)
+	if err := s.guard.Accept(ctx, util.PlatformWO, id); err != nil {
+		if errors.Is(err, util.ErrDuplicateCallback) {
+			return nil
+		}
+		return err
+	}
+	err := s.handle(ctx, push)
+	if err != nil {
+		s.guard.Release(util.PlatformWO, id)
+	}
+	return err
+}
+
+// handle 调用推送类型对应的处理函数
+func (s *Server) handle(ctx context.Context, push *Push) error {
+	if h, ok := s.handlers[push.InfoType]; ok {
+		return h(ctx, push.Raw)
+	}
+	if s.fallback != nil {
+		return s.fallback(ctx, push)
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package wo
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	json2 "github.com/bitly/go-simplejson"
 	"net/http"
@@ -15,46 +16,58 @@ import (
 // doc https://developers.weixin.qq.com/doc/oplatform/openApi/OpenApiDoc/thirdparty-management/template-management/getTemplatedRaftList.html
 // req GET https://api.weixin.qq.com/wxa/gettemplatedraftlist?access_token=ACCESS_TOKEN
 func (a *app) GetTemplatedRaftList() (*json2.Json, error) {
-	params := url.Values{}
-	params = a.token.ApplyAccessToken(params)
-	return a.doHttp(http.MethodGet, "/wxa/gettemplatedraftlist?"+params.Encode(), nil)
+	return a.GetTemplatedRaftListContext(context.Background())
+}
+
+// GetTemplatedRaftListContext 同 GetTemplatedRaftList，支持 context.Context
+func (a *app) GetTemplatedRaftListContext(ctx context.Context) (*json2.Json, error) {
+	return a.doHttpWithToken(ctx, http.MethodGet, "/wxa/gettemplatedraftlist", url.Values{}, nil)
 }
 
 // AddToTemplate 将草稿添加到模板库
 // doc https://developers.weixin.qq.com/doc/oplatform/openApi/OpenApiDoc/thirdparty-management/template-management/addToTemplate.html
 // req POST https://api.weixin.qq.com/wxa/addtotemplate?access_token=ACCESS_TOKEN
 func (a *app) AddToTemplate(draftId, templateType int64) (*json2.Json, error) {
-	params := url.Values{}
-	params = a.token.ApplyAccessToken(params)
+	return a.AddToTemplateContext(context.Background(), draftId, templateType)
+}
+
+// AddToTemplateContext 同 AddToTemplate，支持 context.Context
+func (a *app) AddToTemplateContext(ctx context.Context, draftId, templateType int64) (*json2.Json, error) {
 	payload, _ := json.Marshal(map[string]int64{
 		"draft_id":      draftId,
 		"template_type": templateType,
 	})
-	return a.doHttp(http.MethodPost, "/wxa/addtotemplate?"+params.Encode(), bytes.NewReader(payload))
+	return a.doHttpWithToken(ctx, http.MethodPost, "/wxa/addtotemplate", url.Values{}, bytes.NewReader(payload))
 }
 
 // GetTemplateList 获取模板列表
 // doc https://developers.weixin.qq.com/doc/oplatform/openApi/OpenApiDoc/thirdparty-management/template-management/getTemplateList.html
 // req GET https://api.weixin.qq.com/wxa/gettemplatelist?access_token=ACCESS_TOKEN
 func (a *app) GetTemplateList(templateType int64) (*json2.Json, error) {
-	params := url.Values{}
-	params = a.token.ApplyAccessToken(params)
+	return a.GetTemplateListContext(context.Background(), templateType)
+}
+
+// GetTemplateListContext 同 GetTemplateList，支持 context.Context
+func (a *app) GetTemplateListContext(ctx context.Context, templateType int64) (*json2.Json, error) {
 	payload, _ := json.Marshal(map[string]int64{
 		"template_type": templateType,
 	})
-	return a.doHttp(http.MethodGet, "/wxa/gettemplatelist?"+params.Encode(), bytes.NewReader(payload))
+	return a.doHttpWithToken(ctx, http.MethodGet, "/wxa/gettemplatelist", url.Values{}, bytes.NewReader(payload))
 }
 
 // DeleteTemplate 删除代码模板
 // doc https://developers.weixin.qq.com/doc/oplatform/openApi/OpenApiDoc/thirdparty-management/template-management/deleteTemplate.html
 // req POST https://api.weixin.qq.com/wxa/deletetemplate?access_token=ACCESS_TOKEN
 func (a *app) DeleteTemplate(templateId int64) (*json2.Json, error) {
-	params := url.Values{}
-	params = a.token.ApplyAccessToken(params)
+	return a.DeleteTemplateContext(context.Background(), templateId)
+}
+
+// DeleteTemplateContext 同 DeleteTemplate，支持 context.Context
+func (a *app) DeleteTemplateContext(ctx context.Context, templateId int64) (*json2.Json, error) {
 	payload, _ := json.Marshal(map[string]int64{
 		"template_id": templateId,
 	})
-	return a.doHttp(http.MethodPost, "/wxa/deletetemplate?"+params.Encode(), bytes.NewReader(payload))
+	return a.doHttpWithToken(ctx, http.MethodPost, "/wxa/deletetemplate", url.Values{}, bytes.NewReader(payload))
 }
 
 /* domain-mgnt */
@@ -63,8 +76,11 @@ func (a *app) DeleteTemplate(templateId int64) (*json2.Json, error) {
 // doc https://developers.weixin.qq.com/doc/oplatform/openApi/OpenApiDoc/thirdparty-management/domain-mgnt/modifyThirdpartyServerDomain.html
 // req POST https://api.weixin.qq.com/cgi-bin/component/modify_wxa_server_domain?access_token=ACCESS_TOKEN
 func (a *app) ModifyThirdpartyServerDomain(action, WxaServerDomain string, IsModifyPublishedTogether bool) (*json2.Json, error) {
-	params := url.Values{}
-	params = a.token.ApplyAccessToken(params)
+	return a.ModifyThirdpartyServerDomainContext(context.Background(), action, WxaServerDomain, IsModifyPublishedTogether)
+}
+
+// ModifyThirdpartyServerDomainContext 同 ModifyThirdpartyServerDomain，支持 context.Context
+func (a *app) ModifyThirdpartyServerDomainContext(ctx context.Context, action, WxaServerDomain string, IsModifyPublishedTogether bool) (*json2.Json, error) {
 	var body map[string]interface{}
 	if strings.ToLower(action) != "get" {
 		body = map[string]interface{}{
@@ -78,24 +94,30 @@ func (a *app) ModifyThirdpartyServerDomain(action, WxaServerDomain string, IsMod
 		}
 	}
 	payload, _ := json.Marshal(body)
-	return a.doHttp(http.MethodPost, "/cgi-bin/component/modify_wxa_server_domain?"+params.Encode(), bytes.NewReader(payload))
+	return a.doHttpWithToken(ctx, http.MethodPost, "/cgi-bin/component/modify_wxa_server_domain", url.Values{}, bytes.NewReader(payload))
 }
 
 // GetThirdpartyJumpDomainConfirmFile 获取第三方平台业务域名校验文件
 // doc https://developers.weixin.qq.com/doc/oplatform/openApi/OpenApiDoc/thirdparty-management/domain-mgnt/getThirdpartyJumpDomainConfirmFile.html
 // req POST https://api.weixin.qq.com/cgi-bin/component/get_domain_confirmfile?access_token=ACCESS_TOKEN
 func (a *app) GetThirdpartyJumpDomainConfirmFile() (*json2.Json, error) {
-	params := url.Values{}
-	params = a.token.ApplyAccessToken(params)
-	return a.doHttp(http.MethodPost, "/cgi-bin/component/get_domain_confirmfile?"+params.Encode(), nil)
+	return a.GetThirdpartyJumpDomainConfirmFileContext(context.Background())
+}
+
+// GetThirdpartyJumpDomainConfirmFileContext 同 GetThirdpartyJumpDomainConfirmFile，支持 context.Context
+func (a *app) GetThirdpartyJumpDomainConfirmFileContext(ctx context.Context) (*json2.Json, error) {
+	return a.doHttpWithToken(ctx, http.MethodPost, "/cgi-bin/component/get_domain_confirmfile", url.Values{}, nil)
 }
 
 // ModifyThirdpartyJumpDomain 设置第三方平台业务域名
 // https://developers.weixin.qq.com/doc/oplatform/openApi/OpenApiDoc/thirdparty-management/domain-mgnt/modifyThirdpartyJumpDomain.html
 // req POST https://api.weixin.qq.com/cgi-bin/component/modify_wxa_jump_domain?access_token=ACCESS_TOKEN
 func (a *app) ModifyThirdpartyJumpDomain(action, WxaJumpH5Domain string, IsModifyPublishedTogether bool) (*json2.Json, error) {
-	params := url.Values{}
-	params = a.token.ApplyAccessToken(params)
+	return a.ModifyThirdpartyJumpDomainContext(context.Background(), action, WxaJumpH5Domain, IsModifyPublishedTogether)
+}
+
+// ModifyThirdpartyJumpDomainContext 同 ModifyThirdpartyJumpDomain，支持 context.Context
+func (a *app) ModifyThirdpartyJumpDomainContext(ctx context.Context, action, WxaJumpH5Domain string, IsModifyPublishedTogether bool) (*json2.Json, error) {
 	var body map[string]interface{}
 	if strings.ToLower(action) != "get" {
 		body = map[string]interface{}{
@@ -109,5 +131,5 @@ func (a *app) ModifyThirdpartyJumpDomain(action, WxaJumpH5Domain string, IsModif
 		}
 	}
 	payload, _ := json.Marshal(body)
-	return a.doHttp(http.MethodPost, "/cgi-bin/component/modify_wxa_jump_domain?"+params.Encode(), bytes.NewReader(payload))
+	return a.doHttpWithToken(ctx, http.MethodPost, "/cgi-bin/component/modify_wxa_jump_domain", url.Values{}, bytes.NewReader(payload))
 }
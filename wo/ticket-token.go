@@ -2,70 +2,140 @@ package wo
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	json2 "github.com/bitly/go-simplejson"
+	"github.com/leapig/tpp/util"
 	"net/http"
 	"net/url"
+	"time"
 )
 
 // StartPushTicket 开启推送ticket
 // doc https://developers.weixin.qq.com/doc/oplatform/openApi/OpenApiDoc/ticket-token/startPushTicket.html
 // req POST https://api.weixin.qq.com/cgi-bin/component/api_start_push_ticket
 func (a *app) StartPushTicket() (*json2.Json, error) {
+	return a.StartPushTicketContext(context.Background())
+}
+
+// StartPushTicketContext 同 StartPushTicket，支持 context.Context
+func (a *app) StartPushTicketContext(ctx context.Context) (*json2.Json, error) {
 	payload, _ := json.Marshal(map[string]interface{}{
 		"component_appid":  a.config.AppId,
-		"component_secret": a.config.AppId,
+		"component_secret": a.config.Secret,
 	})
-	return a.doHttp(http.MethodPost, "/cgi-bin/component/api_start_push_ticket", bytes.NewReader(payload))
+	return a.doHttp(ctx, http.MethodPost, "/cgi-bin/component/api_start_push_ticket", bytes.NewReader(payload))
 }
 
 // GetPreAuthCode 获取预授权码
 // doc https://developers.weixin.qq.com/doc/oplatform/openApi/OpenApiDoc/ticket-token/getPreAuthCode.html
 // req POST https://api.weixin.qq.com/cgi-bin/component/api_create_preauthcode?access_token=ACCESS_TOKEN
 func (a *app) GetPreAuthCode() (*json2.Json, error) {
-	params := url.Values{}
-	params = a.token.ApplyAccessToken(params)
+	return a.GetPreAuthCodeContext(context.Background())
+}
+
+// GetPreAuthCodeContext 同 GetPreAuthCode，支持 context.Context
+func (a *app) GetPreAuthCodeContext(ctx context.Context) (*json2.Json, error) {
 	payload, _ := json.Marshal(map[string]interface{}{
 		"component_appid": a.config.AppId,
 	})
-	return a.doHttp(http.MethodPost, "/cgi-bin/component/api_create_preauthcode?"+params.Encode(), bytes.NewReader(payload))
+	return a.doHttpWithToken(ctx, http.MethodPost, "/cgi-bin/component/api_create_preauthcode", url.Values{}, bytes.NewReader(payload))
 }
 
 // GetAuthorizerAccessToken 获取授权账号调用令牌
 // doc https://developers.weixin.qq.com/doc/oplatform/openApi/OpenApiDoc/ticket-token/getAuthorizerAccessToken.html
 // req POST https://api.weixin.qq.com/cgi-bin/component/api_authorizer_token?component_access_token=ACCESS_TOKEN
 func (a *app) GetAuthorizerAccessToken(authorizerAppId, authorizerRefreshToken string) (*json2.Json, error) {
-	params := url.Values{}
-	params = a.token.ApplyAccessToken(params)
+	return a.GetAuthorizerAccessTokenContext(context.Background(), authorizerAppId, authorizerRefreshToken)
+}
+
+// GetAuthorizerAccessTokenContext 同 GetAuthorizerAccessToken，支持 context.Context
+func (a *app) GetAuthorizerAccessTokenContext(ctx context.Context, authorizerAppId, authorizerRefreshToken string) (*json2.Json, error) {
 	payload, _ := json.Marshal(map[string]interface{}{
 		"component_appid":          a.config.AppId,
 		"authorizer_appid":         authorizerAppId,
 		"authorizer_refresh_token": authorizerRefreshToken,
 	})
-	return a.doHttp(http.MethodPost, "/cgi-bin/component/api_authorizer_token?"+params.Encode(), bytes.NewReader(payload))
+	return a.doHttpWithToken(ctx, http.MethodPost, "/cgi-bin/component/api_authorizer_token", url.Values{}, bytes.NewReader(payload))
 }
 
 // GetAuthorizerRefreshToken 获取刷新令牌
 // doc https://developers.weixin.qq.com/doc/oplatform/openApi/OpenApiDoc/ticket-token/getAuthorizerRefreshToken.html
 // req POST https://api.weixin.qq.com/cgi-bin/component/api_query_auth?access_token=ACCESS_TOKEN
 func (a *app) GetAuthorizerRefreshToken(authorizationCode string) (*json2.Json, error) {
-	params := url.Values{}
-	params.Add("component_access_token", a.Token())
+	return a.GetAuthorizerRefreshTokenContext(context.Background(), authorizationCode)
+}
+
+// GetAuthorizerRefreshTokenContext 同 GetAuthorizerRefreshToken，支持 context.Context
+func (a *app) GetAuthorizerRefreshTokenContext(ctx context.Context, authorizationCode string) (*json2.Json, error) {
 	payload, _ := json.Marshal(map[string]interface{}{
 		"component_appid":    a.config.AppId,
 		"authorization_code": authorizationCode,
 	})
-	return a.doHttp(http.MethodPost, "/cgi-bin/component/api_query_auth?"+params.Encode(), bytes.NewReader(payload))
+	var js *json2.Json
+	err := a.token.WithAccessToken(ctx, func(token string) (err error) {
+		params := url.Values{}
+		params.Add("component_access_token", token)
+		js, err = a.doHttp(ctx, http.MethodPost, "/cgi-bin/component/api_query_auth?"+params.Encode(), bytes.NewReader(payload))
+		return
+	})
+	return js, err
 }
 
 // GetComponentAccessToken 获取令牌
 // doc https://developers.weixin.qq.com/doc/oplatform/openApi/OpenApiDoc/ticket-token/getComponentAccessToken.html
 // req POST https://api.weixin.qq.com/cgi-bin/component/api_component_token
 func (a *app) GetComponentAccessToken() (*json2.Json, error) {
+	return a.GetComponentAccessTokenContext(context.Background())
+}
+
+// GetComponentAccessTokenContext 同 GetComponentAccessToken，支持 context.Context
+func (a *app) GetComponentAccessTokenContext(ctx context.Context) (*json2.Json, error) {
+	ticket, err := a.componentVerifyTicket()
+	if err != nil {
+		return nil, err
+	}
 	payload, _ := json.Marshal(map[string]string{
 		"component_appid":         a.config.AppId,
 		"component_appsecret":     a.config.Secret,
-		"component_verify_ticket": a.config.Ticket,
+		"component_verify_ticket": ticket,
 	})
-	return a.doHttp(http.MethodPost, "/cgi-bin/component/api_component_token", bytes.NewReader(payload))
+	return a.doHttp(ctx, http.MethodPost, "/cgi-bin/component/api_component_token", bytes.NewReader(payload))
+}
+
+// componentVerifyTicketTTL component_verify_ticket 有效期
+const componentVerifyTicketTTL = 12 * time.Hour
+
+// ticketKey component_verify_ticket 缓存键
+func (a *app) ticketKey() string {
+	return "component_verify_ticket:" + a.token.Id
+}
+
+// ComponentVerifyTicket 获取最近一次推送的 component_verify_ticket，未收到推送时返回 Config.Ticket
+// doc https://developers.weixin.qq.com/doc/oplatform/openApi/OpenApiDoc/ticket-token/getComponentAccessToken.html
+func (a *app) ComponentVerifyTicket() string {
+	ticket, _ := a.componentVerifyTicket()
+	return ticket
+}
+
+// componentVerifyTicket 同 ComponentVerifyTicket，未收到推送且未配置 Config.Ticket 时返回 ErrNotFound
+func (a *app) componentVerifyTicket() (string, error) {
+	if ticket, err := a.config.Cache.Fetch(a.ticketKey()); err == nil && ticket != "" {
+		return ticket, nil
+	}
+	if a.config.Ticket == "" {
+		return "", &util.Error{Platform: util.PlatformWO, ErrMsg: "component_verify_ticket not received", Kind: util.ErrNotFound}
+	}
+	return a.config.Ticket, nil
+}
+
+// SetComponentVerifyTicket 保存 component_verify_ticket
+func (a *app) SetComponentVerifyTicket(ticket string) error {
+	if ticket == "" {
+		return &util.Error{Platform: util.PlatformWO, ErrMsg: "empty component_verify_ticket", Kind: util.ErrInvalidParam}
+	}
+	if err := a.config.Cache.Save(a.ticketKey(), ticket, componentVerifyTicketTTL); err != nil {
+		return util.NewError(util.PlatformWO, err)
+	}
+	return nil
 }
@@ -0,0 +1,69 @@
+package wo
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/faabiosr/cachego/sync"
+	"github.com/leapig/tpp/util"
+)
+
+func TestGetComponentAccessTokenTicket(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		pushed string
+		want   string
+		err    error
+	}{
+		{"pushed ticket", "configured", "pushed", "pushed", nil},
+		{"configured ticket", "configured", "", "configured", nil},
+		{"no ticket", "", "", "", util.ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var body map[string]string
+				_ = json.NewDecoder(r.Body).Decode(&body)
+				got = body["component_verify_ticket"]
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(`{"component_access_token":"CAT","expires_in":7200}`))
+			}))
+			defer srv.Close()
+			a := NewApp(Config{AppId: "wx1", Secret: "s", Ticket: tt.config, BaseURL: srv.URL, Cache: sync.New()})
+			if tt.pushed != "" {
+				if err := a.SetComponentVerifyTicket(tt.pushed); err != nil {
+					t.Fatal(err)
+				}
+			}
+			_, err := a.GetComponentAccessToken()
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("GetComponentAccessToken() error = %v, want %v", err, tt.err)
+			}
+			if got != tt.want {
+				t.Errorf("component_verify_ticket = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStartPushTicket(t *testing.T) {
+	var got map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"errcode":0,"errmsg":"ok"}`))
+	}))
+	defer srv.Close()
+	a := NewApp(Config{AppId: "wx1", Secret: "s", BaseURL: srv.URL, Cache: sync.New()})
+	if _, err := a.StartPushTicket(); err != nil {
+		t.Fatal(err)
+	}
+	if got["component_appid"] != "wx1" || got["component_secret"] != "s" {
+		t.Errorf("payload = %v, want component_appid wx1 and component_secret s", got)
+	}
+}
@@ -2,67 +2,170 @@ package ww
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
-	"errors"
-	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"os"
-	"time"
 
 	json2 "github.com/bitly/go-simplejson"
+	"github.com/faabiosr/cachego"
 	"github.com/faabiosr/cachego/file"
 	"github.com/leapig/tpp/util"
 )
 
 type App interface {
+	Key() string
 	Id() string
-	Test() string
-	AgentGet() (res map[string]interface{})
-	DepartmentSimpleList(id string) []interface{}
-	DepartmentGet(id string) map[string]interface{}
-	UserList(id string) []interface{}
-	UserGet(userId string) map[string]interface{}
-	GetUserDetail(userTicket string) map[string]interface{}
-	GetJsApiTicket() (ticket string)
-	GetUserInfo(code string) (res map[string]interface{})
-	MessageSend(msg Message) (err error)
+	Close()
+	Test() (string, error)
+	TestContext(ctx context.Context) (string, error)
+	AgentGet() (map[string]interface{}, error)
+	AgentGetContext(ctx context.Context) (map[string]interface{}, error)
+	DepartmentSimpleList(id string) ([]interface{}, error)
+	DepartmentSimpleListContext(ctx context.Context, id string) ([]interface{}, error)
+	DepartmentGet(id string) (map[string]interface{}, error)
+	DepartmentGetContext(ctx context.Context, id string) (map[string]interface{}, error)
+	UserList(id string) ([]interface{}, error)
+	UserListContext(ctx context.Context, id string) ([]interface{}, error)
+	UserGet(userId string) (map[string]interface{}, error)
+	UserGetContext(ctx context.Context, userId string) (map[string]interface{}, error)
+	GetUserDetail(userTicket string) (map[string]interface{}, error)
+	GetUserDetailContext(ctx context.Context, userTicket string) (map[string]interface{}, error)
+	GetJsApiTicket() (string, error)
+	GetJsApiTicketContext(ctx context.Context) (string, error)
+	GetAgentConfigTicket() (string, error)
+	GetAgentConfigTicketContext(ctx context.Context) (string, error)
+	JsConfig(url string) (*JsConfig, error)
+	JsConfigContext(ctx context.Context, url string) (*JsConfig, error)
+	AgentConfig(url string) (*AgentConfig, error)
+	AgentConfigContext(ctx context.Context, url string) (*AgentConfig, error)
+	GetUserInfo(code string) (map[string]interface{}, error)
+	GetUserInfoContext(ctx context.Context, code string) (map[string]interface{}, error)
+	MessageSend(msg Message) error
+	MessageSendContext(ctx context.Context, msg Message) error
+	Server() *Server
 }
 
 type Config struct {
-	CorpId     string `json:"corpid"`
-	CorpSecret string `json:"corpsecret"`
-	AgentId    string `json:"agentid"`
+	Key        string `json:"key" yaml:"key"`
+	CorpId     string `json:"corpid" yaml:"corpid"`
+	CorpSecret string `json:"corpsecret" yaml:"corpsecret"`
+	AgentId    string `json:"agentid" yaml:"agentid"`
+	// Token 应用接收消息的 Token
+	Token string `json:"token" yaml:"token"`
+	// EncodingAESKey 应用接收消息的 EncodingAESKey
+	EncodingAESKey string `json:"encoding_aes_key" yaml:"encoding_aes_key"`
+	// Cache 令牌缓存，为空时使用系统临时目录下的文件缓存
+	Cache cachego.Cache `json:"cache" yaml:"-"`
+	// BaseURL 服务地址，为空时使用平台默认地址
+	BaseURL string `json:"base_url" yaml:"base_url"`
+	// Proxy 出口代理地址
+	Proxy string `json:"proxy" yaml:"proxy"`
+	// HTTPClient 自定义请求客户端，优先于 Transport 与 Proxy
+	HTTPClient *http.Client `json:"-" yaml:"-"`
+	// Transport 自定义 Transport
+	Transport http.RoundTripper `json:"-" yaml:"-"`
+	// Locker 多实例部署时的令牌刷新锁
+	Locker util.Locker `json:"-" yaml:"-"`
+	// Refresher 后台预刷新，为空时仅在令牌过期后刷新
+	Refresher *util.Refresher `json:"-" yaml:"-"`
 }
 
 type app struct {
 	config Config
 	token  util.AccessToken
-	server string
+	// ticket jsapi_ticket
+	ticket util.AccessToken
+	// agentTicket 应用的 jsapi_ticket，用于 wx.agentConfig
+	agentTicket util.AccessToken
+	client      *util.Client
+	server      string
 }
 
 func NewApp(config Config) App {
-	server := "https://qyapi.weixin.qq.com"
+	if config.Cache == nil {
+		config.Cache = file.New(os.TempDir())
+	}
+	server := util.BaseURL(config.BaseURL, "https://qyapi.weixin.qq.com")
+	client := &util.Client{Platform: util.PlatformWW, Kinds: errorKinds, HttpClient: util.NewHTTPClient(config.HTTPClient, config.Transport, config.Proxy)}
 	// 管理token
-	return &app{
+	a := &app{
 		server: server,
 		config: config,
+		client: client,
 		token: util.AccessToken{
-			Id:    config.CorpId + config.CorpSecret,
-			Cache: file.New(os.TempDir()),
-			GetRefreshRequestFunc: func() []byte {
+			Id:     util.CacheKey(util.PlatformWW, config.CorpId, config.CorpSecret),
+			Cache:  config.Cache,
+			Locker: config.Locker,
+			GetRefreshRequestFunc: func(ctx context.Context) ([]byte, error) {
 				params := url.Values{}
 				params.Add("corpid", config.CorpId)
 				params.Add("corpsecret", config.CorpSecret)
-				req, _ := http.NewRequest(http.MethodGet, server+"/cgi-bin/gettoken?"+params.Encode(), nil)
-				response, _ := http.DefaultClient.Do(req)
-				resp, _ := io.ReadAll(response.Body)
-				fmt.Printf("\n\n%s\n\n", string(resp))
-				return resp
+				req, err := http.NewRequestWithContext(ctx, http.MethodGet, server+"/cgi-bin/gettoken?"+params.Encode(), nil)
+				if err != nil {
+					return nil, util.NewError(util.PlatformWW, err)
+				}
+				return client.Fetch(req)
 			},
 		},
 	}
+	a.ticket = util.AccessToken{
+		Id:     "ticket:" + a.token.Id,
+		Cache:  a.token.Cache,
+		Locker: config.Locker,
+		GetRefreshRequestFunc: func(ctx context.Context) ([]byte, error) {
+			js, err := a.doHttp(ctx, http.MethodGet, "/cgi-bin/get_jsapi_ticket", url.Values{}, nil)
+			if err != nil {
+				return nil, err
+			}
+			return js.Encode()
+		},
+		Parse: util.ParseJSON("ticket", "expires_in"),
+	}
+	a.agentTicket = util.AccessToken{
+		Id:     "ticket:agent_config:" + a.token.Id,
+		Cache:  a.token.Cache,
+		Locker: config.Locker,
+		GetRefreshRequestFunc: func(ctx context.Context) ([]byte, error) {
+			js, err := a.doHttp(ctx, http.MethodGet, "/cgi-bin/ticket/get", url.Values{"type": {"agent_config"}}, nil)
+			if err != nil {
+				return nil, err
+			}
+			return js.Encode()
+		},
+		Parse: util.ParseJSON("ticket", "expires_in"),
+	}
+	if config.Refresher != nil {
+		config.Refresher.Add(a.token)
+		config.Refresher.Add(a.ticket)
+		config.Refresher.Add(a.agentTicket)
+	}
+	return a
+}
+
+// doHttp 附加 access_token 后执行请求并解析JSON响应，令牌失效时刷新后重试一次
+func (a *app) doHttp(ctx context.Context, method string, path string, params url.Values, body io.Reader) (js *json2.Json, err error) {
+	payload, err := util.ReadBody(body)
+	if err != nil {
+		return nil, util.NewError(util.PlatformWW, err)
+	}
+	err = a.token.WithAccessToken(ctx, func(token string) error {
+		query := util.WithToken(params, "access_token", token)
+		req, err := http.NewRequestWithContext(ctx, method, a.server+path+"?"+query.Encode(), util.BodyReader(payload))
+		if err != nil {
+			return util.NewError(util.PlatformWW, err)
+		}
+		js, err = a.client.Do(req)
+		return err
+	})
+	return
+}
+
+// Key 获取当前实例Key
+func (a *app) Key() string {
+	return a.config.Key
 }
 
 // Id 获取当前实例ID
@@ -70,155 +173,157 @@ func (a *app) Id() string {
 	return a.config.CorpId
 }
 
+// Close 停止实例令牌的后台预刷新，实例不再使用时调用
+func (a *app) Close() {
+	if a.config.Refresher != nil {
+		a.config.Refresher.Remove(a.token, a.ticket, a.agentTicket)
+	}
+}
+
 // Test 校验是否配置是否正常（返回access_token）
-func (a *app) Test() string {
-	return a.token.GetAccessToken()
+func (a *app) Test() (string, error) {
+	return a.TestContext(context.Background())
+}
+
+// TestContext 同 Test，支持 context.Context
+func (a *app) TestContext(ctx context.Context) (string, error) {
+	return a.token.GetAccessTokenContext(ctx)
 }
 
 // AgentGet https://qyapi.weixin.qq.com/cgi-bin/agent/get?access_token=ACCESS_TOKEN&agentid=AGENTID
-func (a *app) AgentGet() (res map[string]interface{}) {
+func (a *app) AgentGet() (map[string]interface{}, error) {
+	return a.AgentGetContext(context.Background())
+}
+
+// AgentGetContext 同 AgentGet，支持 context.Context
+func (a *app) AgentGetContext(ctx context.Context) (map[string]interface{}, error) {
 	params := url.Values{}
 	params.Add("agentid", a.config.AgentId)
-	params = a.token.ApplyAccessToken(params)
-	if response, err := http.Get(a.server + "/cgi-bin/agent/get?" + params.Encode()); err == nil {
-		if resp, err := io.ReadAll(response.Body); err == nil {
-			js, _ := json2.NewJson(resp)
-			res = js.MustMap()
-		}
+	js, err := a.doHttp(ctx, http.MethodGet, "/cgi-bin/agent/get", params, nil)
+	if err != nil {
+		return nil, err
 	}
-	return
+	return js.MustMap(), nil
 }
 
 // DepartmentSimpleList GET https://qyapi.weixin.qq.com/cgi-bin/department/simplelist?access_token=ACCESS_TOKEN&id=ID
-func (a *app) DepartmentSimpleList(id string) (res []interface{}) {
+func (a *app) DepartmentSimpleList(id string) ([]interface{}, error) {
+	return a.DepartmentSimpleListContext(context.Background(), id)
+}
+
+// DepartmentSimpleListContext 同 DepartmentSimpleList，支持 context.Context
+func (a *app) DepartmentSimpleListContext(ctx context.Context, id string) ([]interface{}, error) {
 	params := url.Values{}
 	params.Add("id", id)
-	params = a.token.ApplyAccessToken(params)
-	if response, err := http.Get(a.server + "/cgi-bin/department/simplelist?" + params.Encode()); err == nil {
-		if resp, err := io.ReadAll(response.Body); err == nil {
-			js, _ := json2.NewJson(resp)
-			if js.Get("errcode").MustInt() == 0 {
-				res = js.Get("department_id").MustArray()
-			}
-		}
+	js, err := a.doHttp(ctx, http.MethodGet, "/cgi-bin/department/simplelist", params, nil)
+	if err != nil {
+		return nil, err
 	}
-	return
+	return js.Get("department_id").MustArray(), nil
 }
 
 // DepartmentGet GET https://qyapi.weixin.qq.com/cgi-bin/department/get?access_token=ACCESS_TOKEN&id=ID
-func (a *app) DepartmentGet(id string) (res map[string]interface{}) {
+func (a *app) DepartmentGet(id string) (map[string]interface{}, error) {
+	return a.DepartmentGetContext(context.Background(), id)
+}
+
+// DepartmentGetContext 同 DepartmentGet，支持 context.Context
+func (a *app) DepartmentGetContext(ctx context.Context, id string) (map[string]interface{}, error) {
 	params := url.Values{}
 	params.Add("id", id)
-	params = a.token.ApplyAccessToken(params)
-	if response, err := http.Get(a.server + "/cgi-bin/department/get?" + params.Encode()); err == nil {
-		if resp, err := io.ReadAll(response.Body); err == nil {
-			js, _ := json2.NewJson(resp)
-			if js.Get("errcode").MustInt() == 0 {
-				res = js.Get("department").MustMap()
-			} else if js.Get("errcode").MustInt() == 60011 {
-				// "no privilege to access/modify contact/party/agent"
-				res = nil
-			}
-		}
+	js, err := a.doHttp(ctx, http.MethodGet, "/cgi-bin/department/get", params, nil)
+	if err != nil {
+		// 60011 "no privilege to access/modify contact/party/agent" 可通过 errors.Is(err, util.ErrPermissionDenied) 判断
+		return nil, err
 	}
-	return
+	return js.Get("department").MustMap(), nil
 }
 
 // UserList GET https://qyapi.weixin.qq.com/cgi-bin/user/list?access_token=ACCESS_TOKEN&department_id=DEPARTMENT_ID
-func (a *app) UserList(departmentId string) (res []interface{}) {
+func (a *app) UserList(departmentId string) ([]interface{}, error) {
+	return a.UserListContext(context.Background(), departmentId)
+}
+
+// UserListContext 同 UserList，支持 context.Context
+func (a *app) UserListContext(ctx context.Context, departmentId string) ([]interface{}, error) {
 	params := url.Values{}
 	params.Add("department_id", departmentId)
-	params = a.token.ApplyAccessToken(params)
-	if response, err := http.Get(a.server + "/cgi-bin/user/list?" + params.Encode()); err == nil {
-		if resp, err := io.ReadAll(response.Body); err == nil {
-			js, _ := json2.NewJson(resp)
-			if js.Get("errcode").MustInt() == 0 {
-				res = js.Get("userlist").MustArray()
-			} else if js.Get("errcode").MustInt() == 60011 {
-				// "no privilege to access/modify contact/party/agent"
-				res = js.Get("userlist").MustArray()
-			} else {
-				res = []interface{}{}
-			}
-		}
+	js, err := a.doHttp(ctx, http.MethodGet, "/cgi-bin/user/list", params, nil)
+	if err != nil {
+		return []interface{}{}, err
 	}
-	return
+	return js.Get("userlist").MustArray(), nil
 }
 
 // UserGet GET https://qyapi.weixin.qq.com/cgi-bin/user/get?access_token=ACCESS_TOKEN&userid=USERID
-func (a *app) UserGet(userId string) (res map[string]interface{}) {
+func (a *app) UserGet(userId string) (map[string]interface{}, error) {
+	return a.UserGetContext(context.Background(), userId)
+}
+
+// UserGetContext 同 UserGet，支持 context.Context
+func (a *app) UserGetContext(ctx context.Context, userId string) (map[string]interface{}, error) {
 	params := url.Values{}
 	params.Add("userid", userId)
-	params = a.token.ApplyAccessToken(params)
-	if response, err := http.Get(a.server + "/cgi-bin/user/get?" + params.Encode()); err == nil {
-		if resp, err := io.ReadAll(response.Body); err == nil {
-			js, _ := json2.NewJson(resp)
-			if js.Get("errcode").MustInt() == 0 {
-				js.Del("errcode")
-				js.Del("errmsg")
-				res = js.MustMap()
-			} else if js.Get("errcode").MustInt() == 60011 {
-				// "no privilege to access/modify contact/party/agent"
-				res = nil
-			}
-		}
+	js, err := a.doHttp(ctx, http.MethodGet, "/cgi-bin/user/get", params, nil)
+	if err != nil {
+		return nil, err
 	}
-	return
+	js.Del("errcode")
+	js.Del("errmsg")
+	return js.MustMap(), nil
 }
 
 // GetUserDetail POST https://qyapi.weixin.qq.com/cgi-bin/auth/getuserdetail?access_token=ACCESS_TOKEN
-func (a *app) GetUserDetail(userTicket string) (res map[string]interface{}) {
-	params := url.Values{}
-	params = a.token.ApplyAccessToken(params)
+func (a *app) GetUserDetail(userTicket string) (map[string]interface{}, error) {
+	return a.GetUserDetailContext(context.Background(), userTicket)
+}
+
+// GetUserDetailContext 同 GetUserDetail，支持 context.Context
+func (a *app) GetUserDetailContext(ctx context.Context, userTicket string) (map[string]interface{}, error) {
 	payload, _ := json.Marshal(map[string]interface{}{
 		"user_ticket": userTicket,
 	})
-	req, _ := http.NewRequest(http.MethodPost, a.server+"/cgi-bin/auth/getuserdetail?"+params.Encode(), bytes.NewReader(payload))
-	if response, err := http.DefaultClient.Do(req); err == nil {
-		if resp, err := io.ReadAll(response.Body); err == nil {
-			js, _ := json2.NewJson(resp)
-			if js.Get("errcode").MustInt() == 0 {
-				res = js.MustMap()
-			}
-		}
+	js, err := a.doHttp(ctx, http.MethodPost, "/cgi-bin/auth/getuserdetail", url.Values{}, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
 	}
-	return
+	return js.MustMap(), nil
 }
 
 // GetJsApiTicket GET https://qyapi.weixin.qq.com/cgi-bin/get_jsapi_ticket?access_token=ACCESS_TOKEN
-func (a *app) GetJsApiTicket() (ticket string) {
-	ticket, _ = a.token.Cache.Fetch("ticket" + a.token.Id)
-	if ticket == "" {
-		params := url.Values{}
-		params = a.token.ApplyAccessToken(params)
-		if response, err := http.Get(a.server + "/cgi-bin/get_jsapi_ticket?" + params.Encode()); err == nil {
-			if resp, err := io.ReadAll(response.Body); err == nil {
-				js, _ := json2.NewJson(resp)
-				if js.Get("errcode").MustInt() == 0 {
-					ticket = js.Get("ticket").MustString()
-					d := time.Duration(js.Get("expires_in").MustInt()) * time.Second
-					_ = a.token.Cache.Save("ticket:"+a.token.Id, ticket, d)
-				}
-			}
-		}
-	}
-	return
+func (a *app) GetJsApiTicket() (ticket string, err error) {
+	return a.GetJsApiTicketContext(context.Background())
+}
+
+// GetJsApiTicketContext 同 GetJsApiTicket，支持 context.Context
+func (a *app) GetJsApiTicketContext(ctx context.Context) (ticket string, err error) {
+	return a.ticket.GetAccessTokenContext(ctx)
+}
+
+// GetAgentConfigTicket GET https://qyapi.weixin.qq.com/cgi-bin/ticket/get?access_token=ACCESS_TOKEN&type=agent_config
+func (a *app) GetAgentConfigTicket() (ticket string, err error) {
+	return a.GetAgentConfigTicketContext(context.Background())
+}
+
+// GetAgentConfigTicketContext 同 GetAgentConfigTicket，支持 context.Context
+func (a *app) GetAgentConfigTicketContext(ctx context.Context) (ticket string, err error) {
+	return a.agentTicket.GetAccessTokenContext(ctx)
 }
 
 // GetUserInfo GET https://qyapi.weixin.qq.com/cgi-bin/user/getuserinfo?access_token=ACCESS_TOKEN&code=CODE
-func (a *app) GetUserInfo(code string) (res map[string]interface{}) {
+func (a *app) GetUserInfo(code string) (map[string]interface{}, error) {
+	return a.GetUserInfoContext(context.Background(), code)
+}
+
+// GetUserInfoContext 同 GetUserInfo，支持 context.Context
+func (a *app) GetUserInfoContext(ctx context.Context, code string) (map[string]interface{}, error) {
 	params := url.Values{}
 	params.Add("code", code)
-	params = a.token.ApplyAccessToken(params)
-	if response, err := http.Get(a.server + "/cgi-bin/user/getuserinfo?" + params.Encode()); err == nil {
-		if resp, err := io.ReadAll(response.Body); err == nil {
-			js, _ := json2.NewJson(resp)
-			if js.Get("errcode").MustInt() == 0 {
-				res = js.MustMap()
-			}
-		}
+	js, err := a.doHttp(ctx, http.MethodGet, "/cgi-bin/user/getuserinfo", params, nil)
+	if err != nil {
+		return nil, err
 	}
-	return
+	return js.MustMap(), nil
 }
 
 // Message 微信模板消息结构体
@@ -236,9 +341,12 @@ type TextCard struct {
 }
 
 // MessageSend POST https://qyapi.weixin.qq.com/cgi-bin/message/send?access_token=ACCESS_TOKEN
-func (a *app) MessageSend(msg Message) (err error) {
-	params := url.Values{}
-	params = a.token.ApplyAccessToken(params)
+func (a *app) MessageSend(msg Message) error {
+	return a.MessageSendContext(context.Background(), msg)
+}
+
+// MessageSendContext 同 MessageSend，支持 context.Context
+func (a *app) MessageSendContext(ctx context.Context, msg Message) error {
 	if msg.AgentId == "" {
 		msg.AgentId = a.config.AgentId
 	}
@@ -246,14 +354,6 @@ func (a *app) MessageSend(msg Message) (err error) {
 		msg.MsgType = "textcard"
 	}
 	payload, _ := json.Marshal(msg)
-	req, _ := http.NewRequest(http.MethodPost, a.server+"/cgi-bin/message/send?"+params.Encode(), bytes.NewReader(payload))
-	if response, err := http.DefaultClient.Do(req); err == nil {
-		if resp, err := io.ReadAll(response.Body); err == nil {
-			js, _ := json2.NewJson(resp)
-			if js.Get("errcode").MustInt() != 0 {
-				return errors.New(js.Get("errmsg").MustString())
-			}
-		}
-	}
-	return
+	_, err := a.doHttp(ctx, http.MethodPost, "/cgi-bin/message/send", url.Values{}, bytes.NewReader(payload))
+	return err
 }
@@ -0,0 +1,22 @@
+package ww
+
+import "github.com/leapig/tpp/util"
+
+// errorKinds 企业微信错误码分类
+// ErrInvalidToken 仅用于刷新令牌后可恢复的错误，缺少 access_token 属于参数错误
+// doc https://developer.work.weixin.qq.com/document/path/96213
+var errorKinds = map[int]error{
+	40014: util.ErrInvalidToken,
+	42001: util.ErrInvalidToken,
+	40029: util.ErrInvalidParam,
+	40056: util.ErrInvalidParam,
+	41001: util.ErrInvalidParam,
+	45009: util.ErrRateLimited,
+	45033: util.ErrRateLimited,
+	48002: util.ErrPermissionDenied,
+	60011: util.ErrPermissionDenied,
+	60020: util.ErrPermissionDenied,
+	60003: util.ErrNotFound,
+	60111: util.ErrNotFound,
+	60123: util.ErrNotFound,
+}
@@ -0,0 +1,69 @@
+package ww
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/leapig/tpp/util"
+)
+
+// JsConfig wx.config 签名参数，使用企业的 jsapi_ticket，可直接序列化后传给前端
+// doc https://developer.work.weixin.qq.com/document/path/90514
+type JsConfig struct {
+	AppId     string `json:"appId"`
+	Timestamp int64  `json:"timestamp"`
+	NonceStr  string `json:"nonceStr"`
+	Signature string `json:"signature"`
+}
+
+// AgentConfig wx.agentConfig 签名参数，使用应用的 jsapi_ticket
+// doc https://developer.work.weixin.qq.com/document/path/94313
+type AgentConfig struct {
+	CorpId    string `json:"corpid"`
+	AgentId   string `json:"agentid"`
+	Timestamp int64  `json:"timestamp"`
+	NonceStr  string `json:"nonceStr"`
+	Signature string `json:"signature"`
+}
+
+// JsConfig 生成 url 页面的 wx.config 签名参数
+func (a *app) JsConfig(url string) (*JsConfig, error) {
+	return a.JsConfigContext(context.Background(), url)
+}
+
+// JsConfigContext 同 JsConfig，支持 context.Context
+func (a *app) JsConfigContext(ctx context.Context, url string) (*JsConfig, error) {
+	ticket, err := a.ticket.GetAccessTokenContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	config := &JsConfig{
+		AppId:     a.config.CorpId,
+		Timestamp: time.Now().Unix(),
+		NonceStr:  util.GetRandString(16),
+	}
+	config.Signature = util.JsSdkSignature(ticket, config.NonceStr, strconv.FormatInt(config.Timestamp, 10), url)
+	return config, nil
+}
+
+// AgentConfig 生成 url 页面的 wx.agentConfig 签名参数
+func (a *app) AgentConfig(url string) (*AgentConfig, error) {
+	return a.AgentConfigContext(context.Background(), url)
+}
+
+// AgentConfigContext 同 AgentConfig，支持 context.Context
+func (a *app) AgentConfigContext(ctx context.Context, url string) (*AgentConfig, error) {
+	ticket, err := a.agentTicket.GetAccessTokenContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	config := &AgentConfig{
+		CorpId:    a.config.CorpId,
+		AgentId:   a.config.AgentId,
+		Timestamp: time.Now().Unix(),
+		NonceStr:  util.GetRandString(16),
+	}
+	config.Signature = util.JsSdkSignature(ticket, config.NonceStr, strconv.FormatInt(config.Timestamp, 10), url)
+	return config, nil
+}
@@ -0,0 +1,166 @@
+package ww
+
+// 消息类型
+const (
+	MsgTypeText  = "text"
+	MsgTypeImage = "image"
+	MsgTypeEvent = "event"
+)
+
+// 事件类型
+// doc https://developer.work.weixin.qq.com/document/path/90240
+const (
+	EventChangeContact     = "change_contact"
+	EventEnterAgent        = "enter_agent"
+	EventClick             = "click"
+	EventView              = "view"
+	EventTemplateCard      = "template_card_event"
+	EventSysApprovalChange = "sys_approval_change"
+)
+
+// 通讯录变更类型
+// doc https://developer.work.weixin.qq.com/document/path/90970
+const (
+	ChangeTypeCreateUser  = "create_user"
+	ChangeTypeUpdateUser  = "update_user"
+	ChangeTypeDeleteUser  = "delete_user"
+	ChangeTypeCreateParty = "create_party"
+	ChangeTypeUpdateParty = "update_party"
+	ChangeTypeDeleteParty = "delete_party"
+	ChangeTypeUpdateTag   = "update_tag"
+)
+
+// PushHeader 推送消息公共字段，ToUserName 为企业ID，FromUserName 为成员 UserID（通讯录变更事件为 sys）
+type PushHeader struct {
+	ToUserName   string `xml:"ToUserName"`
+	FromUserName string `xml:"FromUserName"`
+	CreateTime   int64  `xml:"CreateTime"`
+	MsgType      string `xml:"MsgType"`
+	AgentID      int64  `xml:"AgentID"`
+}
+
+// Push 未注册类型处理函数的推送，Raw 为解密后的明文XML
+type Push struct {
+	PushHeader
+	MsgId      int64  `xml:"MsgId"`
+	Event      string `xml:"Event"`
+	ChangeType string `xml:"ChangeType"`
+	Raw        []byte `xml:"-"`
+}
+
+// TextMessage 文本消息
+// doc https://developer.work.weixin.qq.com/document/path/90239
+type TextMessage struct {
+	PushHeader
+	MsgId   int64  `xml:"MsgId"`
+	Content string `xml:"Content"`
+}
+
+// ImageMessage 图片消息
+type ImageMessage struct {
+	PushHeader
+	MsgId   int64  `xml:"MsgId"`
+	PicUrl  string `xml:"PicUrl"`
+	MediaId string `xml:"MediaId"`
+}
+
+// EnterAgentEvent 进入应用事件
+type EnterAgentEvent struct {
+	PushHeader
+	Event    string `xml:"Event"`
+	EventKey string `xml:"EventKey"`
+}
+
+// ClickEvent 点击菜单拉取消息事件，EventKey 为菜单 key
+type ClickEvent struct {
+	PushHeader
+	Event    string `xml:"Event"`
+	EventKey string `xml:"EventKey"`
+}
+
+// ViewEvent 点击菜单跳转链接事件，EventKey 为跳转URL
+type ViewEvent struct {
+	PushHeader
+	Event    string `xml:"Event"`
+	EventKey string `xml:"EventKey"`
+}
+
+// UserChangeEvent 成员变更事件，ChangeType 为 create_user、update_user 或 delete_user
+// Department 为逗号分隔的部门ID，变更事件仅包含发生变更的字段
+type UserChangeEvent struct {
+	PushHeader
+	Event          string `xml:"Event"`
+	ChangeType     string `xml:"ChangeType"`
+	UserID         string `xml:"UserID"`
+	NewUserID      string `xml:"NewUserID"`
+	Name           string `xml:"Name"`
+	Department     string `xml:"Department"`
+	MainDepartment string `xml:"MainDepartment"`
+	IsLeaderInDept string `xml:"IsLeaderInDept"`
+	Position       string `xml:"Position"`
+	Mobile         string `xml:"Mobile"`
+	Gender         string `xml:"Gender"`
+	Email          string `xml:"Email"`
+	Status         string `xml:"Status"`
+	Avatar         string `xml:"Avatar"`
+	Alias          string `xml:"Alias"`
+	Telephone      string `xml:"Telephone"`
+}
+
+// PartyChangeEvent 部门变更事件，ChangeType 为 create_party、update_party 或 delete_party
+type PartyChangeEvent struct {
+	PushHeader
+	Event      string `xml:"Event"`
+	ChangeType string `xml:"ChangeType"`
+	Id         string `xml:"Id"`
+	Name       string `xml:"Name"`
+	ParentId   string `xml:"ParentId"`
+	Order      string `xml:"Order"`
+}
+
+// TagChangeEvent 标签成员变更事件，成员与部门ID均以逗号分隔
+type TagChangeEvent struct {
+	PushHeader
+	Event         string `xml:"Event"`
+	ChangeType    string `xml:"ChangeType"`
+	TagId         string `xml:"TagId"`
+	AddUserItems  string `xml:"AddUserItems"`
+	DelUserItems  string `xml:"DelUserItems"`
+	AddPartyItems string `xml:"AddPartyItems"`
+	DelPartyItems string `xml:"DelPartyItems"`
+}
+
+// TemplateCardEvent 模板卡片按钮点击事件，ResponseCode 用于更新卡片
+// doc https://developer.work.weixin.qq.com/document/path/90240#模板卡片事件推送
+type TemplateCardEvent struct {
+	PushHeader
+	Event         string `xml:"Event"`
+	EventKey      string `xml:"EventKey"`
+	TaskId        string `xml:"TaskId"`
+	CardType      string `xml:"CardType"`
+	ResponseCode  string `xml:"ResponseCode"`
+	SelectedItems []struct {
+		QuestionKey string   `xml:"QuestionKey"`
+		OptionIds   []string `xml:"OptionIds>OptionId"`
+	} `xml:"SelectedItems>SelectedItem"`
+}
+
+// ApprovalChangeEvent 审批申请状态变化事件
+// SpStatus 1-审批中 2-已通过 3-已驳回 4-已撤销 6-通过后撤销 7-已删除 10-已支付
+// doc https://developer.work.weixin.qq.com/document/path/91815
+type ApprovalChangeEvent struct {
+	PushHeader
+	Event        string `xml:"Event"`
+	ApprovalInfo struct {
+		SpNo       string `xml:"SpNo"`
+		SpName     string `xml:"SpName"`
+		SpStatus   int    `xml:"SpStatus"`
+		TemplateId string `xml:"TemplateId"`
+		ApplyTime  int64  `xml:"ApplyTime"`
+		Applyer    struct {
+			UserId string `xml:"UserId"`
+			Party  string `xml:"Party"`
+		} `xml:"Applyer"`
+		StatuChangeEvent int `xml:"StatuChangeEvent"`
+	} `xml:"ApprovalInfo"`
+}
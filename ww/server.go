@@ -0,0 +1,259 @@
+package ww
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/faabiosr/cachego"
+	"github.com/leapig/tpp/logger"
+	"github.com/leapig/tpp/util"
+)
+
+// maxPushSize 推送消息体上限
+const maxPushSize = 1 << 20
+
+// Reply 被动回复消息，Marshal 的 toUserName 为成员 UserID，fromUserName 为企业ID
+type Reply = util.Reply
+
+// RawReply 已序列化的明文XML回复
+type RawReply = util.RawReply
+
+// handler 解析明文XML并调用处理函数
+type handler func(ctx context.Context, raw []byte) (Reply, error)
+
+// Server 企业微信应用回调服务，推送均为加密模式
+// doc https://developer.work.weixin.qq.com/document/path/90930
+type Server struct {
+	crypt    *util.WXBizMsgCrypt
+	handlers map[string]handler
+	fallback func(ctx context.Context, push *Push) (Reply, error)
+	cache    cachego.Cache
+	locker   util.Locker
+	guard    *util.CallbackGuard
+}
+
+// Server 创建回调服务，使用 Config.Token 与 Config.EncodingAESKey 校验、解密推送
+// 未配置 Token 或 EncodingAESKey 不合法时回调服务拒绝所有请求
+func (a *app) Server() *Server {
+	s := &Server{handlers: map[string]handler{}, cache: a.config.Cache, locker: a.config.Locker}
+	// EncodingAESKey 为 43 位 base64，解码后为 32 字节 AES 密钥
+	if _, err := base64.StdEncoding.DecodeString(a.config.EncodingAESKey + "="); err == nil && len(a.config.EncodingAESKey) == 43 && a.config.Token != "" {
+		s.crypt = util.NewWXBizMsgCrypt(a.config.CorpId, a.config.Token, a.config.EncodingAESKey)
+	}
+	return s
+}
+
+func on[T any](s *Server, key string, h func(ctx context.Context, msg *T) (Reply, error)) *Server {
+	s.handlers[key] = func(ctx context.Context, raw []byte) (Reply, error) {
+		msg := new(T)
+		if err := xml.Unmarshal(raw, msg); err != nil {
+			return nil, err
+		}
+		return h(ctx, msg)
+	}
+	return s
+}
+
+// changeContact 通讯录变更事件分发键
+func changeContact(changeType string) string {
+	return MsgTypeEvent + ":" + EventChangeContact + ":" + changeType
+}
+
+// OnText 文本消息
+func (s *Server) OnText(h func(ctx context.Context, msg *TextMessage) (Reply, error)) *Server {
+	return on(s, MsgTypeText, h)
+}
+
+// OnImage 图片消息
+func (s *Server) OnImage(h func(ctx context.Context, msg *ImageMessage) (Reply, error)) *Server {
+	return on(s, MsgTypeImage, h)
+}
+
+// OnEnterAgent 进入应用事件
+func (s *Server) OnEnterAgent(h func(ctx context.Context, event *EnterAgentEvent) (Reply, error)) *Server {
+	return on(s, MsgTypeEvent+":"+EventEnterAgent, h)
+}
+
+// OnClick 点击菜单拉取消息事件
+func (s *Server) OnClick(h func(ctx context.Context, event *ClickEvent) (Reply, error)) *Server {
+	return on(s, MsgTypeEvent+":"+EventClick, h)
+}
+
+// OnView 点击菜单跳转链接事件
+func (s *Server) OnView(h func(ctx context.Context, event *ViewEvent) (Reply, error)) *Server {
+	return on(s, MsgTypeEvent+":"+EventView, h)
+}
+
+// OnCreateUser 新增成员事件
+func (s *Server) OnCreateUser(h func(ctx context.Context, event *UserChangeEvent) (Reply, error)) *Server {
+	return on(s, changeContact(ChangeTypeCreateUser), h)
+}
+
+// OnUpdateUser 更新成员事件
+func (s *Server) OnUpdateUser(h func(ctx context.Context, event *UserChangeEvent) (Reply, error)) *Server {
+	return on(s, changeContact(ChangeTypeUpdateUser), h)
+}
+
+// OnDeleteUser 删除成员事件
+func (s *Server) OnDeleteUser(h func(ctx context.Context, event *UserChangeEvent) (Reply, error)) *Server {
+	return on(s, changeContact(ChangeTypeDeleteUser), h)
+}
+
+// OnCreateParty 新增部门事件
+func (s *Server) OnCreateParty(h func(ctx context.Context, event *PartyChangeEvent) (Reply, error)) *Server {
+	return on(s, changeContact(ChangeTypeCreateParty), h)
+}
+
+// OnUpdateParty 更新部门事件
+func (s *Server) OnUpdateParty(h func(ctx context.Context, event *PartyChangeEvent) (Reply, error)) *Server {
+	return on(s, changeContact(ChangeTypeUpdateParty), h)
+}
+
+// OnDeleteParty 删除部门事件
+func (s *Server) OnDeleteParty(h func(ctx context.Context, event *PartyChangeEvent) (Reply, error)) *Server {
+	return on(s, changeContact(ChangeTypeDeleteParty), h)
+}
+
+// OnUpdateTag 标签成员变更事件
+func (s *Server) OnUpdateTag(h func(ctx context.Context, event *TagChangeEvent) (Reply, error)) *Server {
+	return on(s, changeContact(ChangeTypeUpdateTag), h)
+}
+
+// OnTemplateCard 模板卡片按钮点击事件，可回复 UpdateButtonReply 更新按钮
+func (s *Server) OnTemplateCard(h func(ctx context.Context, event *TemplateCardEvent) (Reply, error)) *Server {
+	return on(s, MsgTypeEvent+":"+EventTemplateCard, h)
+}
+
+// OnApprovalChange 审批申请状态变化事件
+func (s *Server) OnApprovalChange(h func(ctx context.Context, event *ApprovalChangeEvent) (Reply, error)) *Server {
+	return on(s, MsgTypeEvent+":"+EventSysApprovalChange, h)
+}
+
+// Guard 启用重放保护与去重，拒绝时间戳超出 window 的推送，window 内重复的推送直接返回成功
+// 使用 Config.Cache 记录已处理的推送，配置 Config.Locker 时跨实例加锁去重，window 为 0 时使用 util.DefaultCallbackWindow
+func (s *Server) Guard(window time.Duration) *Server {
+	s.guard = util.NewCallbackGuard(s.cache, s.locker, window)
+	return s
+}
+
+// OnDefault 未注册类型的消息与事件
+func (s *Server) OnDefault(h func(ctx context.Context, push *Push) (Reply, error)) *Server {
+	s.fallback = h
+	return s
+}
+
+// ServeHTTP GET 请求校验回调地址，POST 请求解密推送并分发，处理函数的返回值加密后作为被动回复
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.crypt == nil {
+		http.Error(w, "token or encoding aes key not configured", http.StatusInternalServerError)
+		return
+	}
+	query := r.URL.Query()
+	signature, timestamp, nonce := query.Get("msg_signature"), query.Get("timestamp"), query.Get("nonce")
+	switch r.Method {
+	case http.MethodGet:
+		echo, _, cryptErr := s.crypt.VerifyURL(signature, timestamp, nonce, query.Get("echostr"))
+		if cryptErr != nil {
+			http.Error(w, cryptErr.ErrMsg, http.StatusForbidden)
+			return
+		}
+		_, _ = w.Write(echo)
+	case http.MethodPost:
+		if s.guard != nil {
+			if err := s.guard.Fresh(timestamp); err != nil {
+				http.Error(w, err.Error(), http.StatusForbidden)
+				return
+			}
+		}
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxPushSize))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		plaintext, _, cryptErr := s.crypt.DecryptMsg(signature, timestamp, nonce, body)
+		if cryptErr != nil {
+			http.Error(w, cryptErr.ErrMsg, http.StatusForbidden)
+			return
+		}
+		push, reply, err := s.dispatch(r.Context(), plaintext)
+		if err != nil {
+			logger.Errorf("ww push: %v", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if reply == nil {
+			return
+		}
+		out, err := util.EncryptReply(s.crypt, reply, push.FromUserName, push.ToUserName, timestamp, nonce)
+		if err != nil {
+			logger.Errorf("ww reply: %v", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+		_, _ = w.Write(out)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// dispatch 解析推送类型并调用处理函数，无回复或重复推送时 Reply 为 nil
+func (s *Server) dispatch(ctx context.Context, plaintext []byte) (*Push, Reply, error) {
+	push := &Push{Raw: plaintext}
+	if err := xml.Unmarshal(plaintext, push); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse push: %w", err)
+	}
+	if s.guard == nil {
+		reply, err := s.handle(ctx, push)
+		return push, reply, err
+	}
+	id := push.id()
+	if err := s.guard.Accept(ctx, util.PlatformWW, id); err != nil {
+		if errors.Is(err, util.ErrDuplicateCallback) {
+			return push, nil, nil
+		}
+		return nil, nil, err
+	}
+	reply, err := s.handle(ctx, push)
+	if err != nil {
+		s.guard.Release(util.PlatformWW, id)
+	}
+	return push, reply, err
+}
+
+// id 推送去重ID，消息使用 MsgId，事件使用 FromUserName 与 CreateTime
+// 通讯录变更事件的 FromUserName 均为 sys，同一秒内可能有多条，使用推送内容
+func (p *Push) id() string {
+	if p.MsgId != 0 {
+		return strconv.FormatInt(p.MsgId, 10)
+	}
+	if p.Event == EventChangeContact {
+		return util.CallbackId(p.Raw)
+	}
+	return p.ToUserName + ":" + p.FromUserName + ":" + strconv.FormatInt(p.CreateTime, 10)
+}
+
+// handle 调用推送类型对应的处理函数
+func (s *Server) handle(ctx context.Context, push *Push) (Reply, error) {
+	key := push.MsgType
+	if push.MsgType == MsgTypeEvent {
+		key += ":" + push.Event
+		if push.Event == EventChangeContact {
+			key += ":" + push.ChangeType
+		}
+	}
+	if h, ok := s.handlers[key]; ok {
+		return h(ctx, push.Raw)
+	}
+	if s.fallback != nil {
+		return s.fallback(ctx, push)
+	}
+	return nil, nil
+}
@@ -0,0 +1,114 @@
+package ww
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/leapig/tpp/util"
+)
+
+// 企业微信官方示例
+const (
+	testCorpId = "wx5823bf96d3bd56c7"
+	testToken  = "QDG6eK"
+	testAesKey = "jWmYm7qr5nMoAUwZRjGtBxmz3KA1tkAj3ykkR6q2B2C"
+)
+
+func TestServerVerifyURL(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    Config
+		signature string
+		status    int
+		want      string
+	}{
+		{"official sample", Config{CorpId: testCorpId, Token: testToken, EncodingAESKey: testAesKey}, "5c45ff5e21c57e6ad56bac8758b79b1d9ac89fd3", http.StatusOK, "1616140317555161061"},
+		{"wrong signature", Config{CorpId: testCorpId, Token: testToken, EncodingAESKey: testAesKey}, "0c45ff5e21c57e6ad56bac8758b79b1d9ac89fd3", http.StatusForbidden, ""},
+		{"missing token", Config{CorpId: testCorpId, EncodingAESKey: testAesKey}, "5c45ff5e21c57e6ad56bac8758b79b1d9ac89fd3", http.StatusInternalServerError, ""},
+		{"invalid aes key", Config{CorpId: testCorpId, Token: testToken, EncodingAESKey: "short"}, "5c45ff5e21c57e6ad56bac8758b79b1d9ac89fd3", http.StatusInternalServerError, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := url.Values{
+				"msg_signature": {tt.signature},
+				"timestamp":     {"1409659589"},
+				"nonce":         {"263014780"},
+				"echostr":       {"P9nAzCzyDtyTWESHep1vC5X9xho/qYX3Zpb4yKa9SKld1DsH3Iyt3tP3zNdtp+4RPcs8TgAE7OaBO+FZXvnaqQ=="},
+			}
+			w := httptest.NewRecorder()
+			NewApp(tt.config).Server().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/?"+query.Encode(), nil))
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.status, w.Body)
+			}
+			if tt.status == http.StatusOK && w.Body.String() != tt.want {
+				t.Errorf("body = %q, want %q", w.Body, tt.want)
+			}
+		})
+	}
+}
+
+func TestServerRoundTrip(t *testing.T) {
+	crypt := util.NewWXBizMsgCrypt(testCorpId, testToken, testAesKey)
+	push := "<xml><ToUserName><![CDATA[" + testCorpId + "]]></ToUserName><FromUserName><![CDATA[zhangsan]]></FromUserName>" +
+		"<CreateTime>1348831860</CreateTime><MsgType><![CDATA[text]]></MsgType><Content><![CDATA[ping]]></Content><MsgId>1</MsgId></xml>"
+	body, cryptErr := crypt.EncryptMsg(push, "1409659813", "1372623149")
+	if cryptErr != nil {
+		t.Fatalf("EncryptMsg() error = %+v", cryptErr)
+	}
+	var sent util.WXBizMsg4Send
+	if err := xml.Unmarshal(body, &sent); err != nil {
+		t.Fatal(err)
+	}
+
+	var got string
+	s := NewApp(Config{CorpId: testCorpId, Token: testToken, EncodingAESKey: testAesKey}).Server().
+		OnText(func(ctx context.Context, msg *TextMessage) (Reply, error) {
+			got = msg.Content
+			return TextReply{Content: "pong"}, nil
+		})
+	query := url.Values{"msg_signature": {sent.Signature.Value}, "timestamp": {"1409659813"}, "nonce": {"1372623149"}}
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/?"+query.Encode(), bytes.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body)
+	}
+	if got != "ping" {
+		t.Errorf("Content = %q, want ping", got)
+	}
+
+	// 被动回复需以相同的 Token 与 EncodingAESKey 解密
+	var reply util.WXBizMsg4Send
+	if err := xml.Unmarshal(w.Body.Bytes(), &reply); err != nil {
+		t.Fatal(err)
+	}
+	plaintext, _, cryptErr := crypt.DecryptMsg(reply.Signature.Value, reply.Timestamp, reply.Nonce.Value, w.Body.Bytes())
+	if cryptErr != nil {
+		t.Fatalf("DecryptMsg() error = %+v", cryptErr)
+	}
+	var msg struct {
+		ToUserName string `xml:"ToUserName"`
+		Content    string `xml:"Content"`
+	}
+	if err := xml.Unmarshal(plaintext, &msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.ToUserName != "zhangsan" || msg.Content != "pong" {
+		t.Errorf("reply = %+v, want pong to zhangsan", msg)
+	}
+
+	// 签名不匹配时拒绝推送
+	query.Set("msg_signature", "0"+sent.Signature.Value[1:])
+	if query.Get("msg_signature") == sent.Signature.Value {
+		query.Set("msg_signature", "1"+sent.Signature.Value[1:])
+	}
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/?"+query.Encode(), bytes.NewReader(body)))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("wrong signature status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+}